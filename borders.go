@@ -0,0 +1,168 @@
+// This file draws grids with a border line around every cell, heavier where
+// neighbouring cells are in different regions, for puzzles whose regions
+// aren't plain rows and columns.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// gridEdge is the kind of border between two neighbouring cells.
+type gridEdge int
+
+const (
+	edgeNone gridEdge = iota - 1
+	edgeCell
+	edgeCage
+	edgeRegion
+)
+
+// gridBorders are the lines a bordered grid is drawn with, indexed by the
+// kind of edge, and how the lines meet.
+type gridBorders struct {
+	horizontal [3]string
+	vertical   [3]string
+	junction   func(edges [4]gridEdge) string
+}
+
+var gridBorderGlyphs = map[string]gridBorders{
+	unicodeGlyphs: {
+		horizontal: [3]string{"─", "┄", "━"},
+		vertical:   [3]string{"│", "┆", "┃"},
+		junction:   boxJunction,
+	},
+	emojiGlyphs: {
+		horizontal: [3]string{"─", "┄", "━"},
+		vertical:   [3]string{"│", "┆", "┃"},
+		junction:   boxJunction,
+	},
+	asciiGlyphs: {
+		horizontal: [3]string{" ", ".", "-"},
+		vertical:   [3]string{" ", ":", "|"},
+		junction: func(edges [4]gridEdge) string {
+			heaviest := edgeNone
+			for _, e := range edges {
+				heaviest = max(heaviest, e)
+			}
+			return [...]string{" ", " ", ".", "+"}[heaviest+1]
+		},
+	},
+}
+
+// boxJunctions holds a box drawing character for every way lines can meet
+// at a point, indexed by the weight of the line up, right, down and left as
+// a base 3 number, where 0 is no line, 1 a light line and 2 a heavy one.
+var boxJunctions = []rune(" ╴╸╷┐┑╻┒┓╶─╾┌┬┭┎┰┱╺╼━┍┮┯┏┲┳" +
+	"╵┘┙│┤┥╽┧┪└┴┵├┼┽┟╁╅┕┶┷┝┾┿┢╆╈" +
+	"╹┚┛╿┦┩┃┨┫┖┸┹┞╀╃┠╂╉┗┺┻┡╄╇┣╊╋")
+
+// boxJunction picks the box drawing character where the edges up, right,
+// down and left of a point meet. Region edges are drawn heavy and the rest
+// light.
+func boxJunction(edges [4]gridEdge) string {
+	i := 0
+	for _, e := range edges {
+		w := 0
+		switch e {
+		case edgeRegion:
+			w = 2
+		case edgeCell, edgeCage:
+			w = 1
+		}
+		i = i*3 + w
+	}
+	return string(boxJunctions[i])
+}
+
+// borderedGrid draws a grid with a line of border above and beside every
+// cell.
+type borderedGrid struct {
+	width     int
+	height    int
+	cellWidth int
+	// edge returns the border between two neighbouring cells. Either may
+	// be off the grid.
+	edge func(x1, y1, x2, y2 int) gridEdge
+	// cell draws a cell, cellWidth wide.
+	cell func(x, y int) string
+	// label draws text over the start of the border above a cell, such as
+	// a cage's sum. It returns "" for none and may be nil.
+	label func(x, y int) string
+}
+
+// view draws the grid: a border line, then a line of cells, for each row.
+func (g borderedGrid) view() string {
+	borders, ok := gridBorderGlyphs[activeGlyphSet]
+	if !ok {
+		borders = gridBorderGlyphs[asciiGlyphs]
+	}
+	edgeStyles := [3]lipgloss.Style{subtleStyle, focusedStyle, blurredStyle}
+
+	var lines []string
+	for y := 0; y <= g.height; y++ {
+		var b strings.Builder
+		for x := 0; x <= g.width; x++ {
+			edges := [4]gridEdge{edgeNone, edgeNone, edgeNone, edgeNone}
+			if y > 0 {
+				edges[0] = g.edge(x-1, y-1, x, y-1)
+			}
+			if x < g.width {
+				edges[1] = g.edge(x, y-1, x, y)
+			}
+			if y < g.height {
+				edges[2] = g.edge(x-1, y, x, y)
+			}
+			if x > 0 {
+				edges[3] = g.edge(x-1, y-1, x-1, y)
+			}
+			heaviest := edgeCell
+			for _, k := range edges {
+				heaviest = max(heaviest, k)
+			}
+			b.WriteString(edgeStyles[heaviest].Render(borders.junction(edges)))
+			if x == g.width {
+				break
+			}
+
+			k := g.edge(x, y-1, x, y)
+			label := ""
+			if g.label != nil && y < g.height {
+				label = g.label(x, y)
+			}
+			b.WriteString(label)
+			b.WriteString(edgeStyles[k].Render(strings.Repeat(borders.horizontal[k], max(0, g.cellWidth-lipgloss.Width(label)))))
+		}
+		lines = append(lines, b.String())
+		if y == g.height {
+			break
+		}
+
+		b.Reset()
+		for x := 0; x <= g.width; x++ {
+			k := g.edge(x-1, y, x, y)
+			b.WriteString(edgeStyles[k].Render(borders.vertical[k]))
+			if x < g.width {
+				b.WriteString(g.cell(x, y))
+			}
+		}
+		lines = append(lines, b.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// cellAt maps a position within the drawn grid to the cell there. Borders
+// aren't cells.
+func (g borderedGrid) cellAt(col, row int) (int, int, bool) {
+	step := g.cellWidth + 1
+	if col < 0 || row < 0 || col%step == 0 || row%2 == 0 {
+		return 0, 0, false
+	}
+	x, y := col/step, row/2
+	if x >= g.width || y >= g.height {
+		return 0, 0, false
+	}
+	return x, y, true
+}
@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestBoxJunction(t *testing.T) {
+	testCases := []struct {
+		edges [4]gridEdge
+		want  string
+	}{
+		{edges: [4]gridEdge{edgeNone, edgeRegion, edgeRegion, edgeNone}, want: "┏"},
+		{edges: [4]gridEdge{edgeNone, edgeRegion, edgeCell, edgeRegion}, want: "┯"},
+		{edges: [4]gridEdge{edgeCell, edgeCell, edgeCell, edgeCell}, want: "┼"},
+		{edges: [4]gridEdge{edgeCage, edgeRegion, edgeCage, edgeRegion}, want: "┿"},
+		{edges: [4]gridEdge{edgeRegion, edgeCell, edgeCell, edgeRegion}, want: "╃"},
+		{edges: [4]gridEdge{edgeRegion, edgeRegion, edgeRegion, edgeRegion}, want: "╋"},
+	}
+
+	for _, tc := range testCases {
+		if got := boxJunction(tc.edges); got != tc.want {
+			t.Errorf("edges %v: expected %q, got %q", tc.edges, tc.want, got)
+		}
+	}
+}
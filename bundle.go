@@ -0,0 +1,387 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Bundles are capped so a hostile archive can't exhaust memory.
+const (
+	maxBundleFileSize  = 64 << 20
+	maxBundleTotalSize = 256 << 20
+)
+
+// bundleManifestNames are the files a bundle may describe itself in.
+var bundleManifestNames = []string{"manifest.yaml", "manifest.yml", "manifest.json"}
+
+// BundleManifest lists the level packs in a bundle and the assets that ship
+// with each. Paths are relative to the root of the bundle.
+type BundleManifest struct {
+	Packs []BundlePack `yaml:"packs" json:"packs"`
+}
+
+// BundlePack is one level pack file in a bundle. Assets maps the name the
+// asset is stored under to its path in the bundle.
+type BundlePack struct {
+	Path   string            `yaml:"path" json:"path"`
+	Assets map[string]string `yaml:"assets,omitempty" json:"assets,omitempty"`
+}
+
+// isBundle reports whether p names a directory or an archive of packs
+// rather than a single pack file.
+func isBundle(p string) bool {
+	if info, err := os.Stat(p); err == nil && info.IsDir() {
+		return true
+	}
+	return bundleArchiveFormat(p) != ""
+}
+
+// bundleArchiveFormat names the archive format of path, or "" if it isn't
+// one.
+func bundleArchiveFormat(p string) string {
+	lower := strings.ToLower(p)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip"
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tar.gz"
+	}
+	return ""
+}
+
+// bundlePath cleans a path inside a bundle and rejects any that would
+// escape it.
+func bundlePath(name string) (string, error) {
+	p := path.Clean(strings.ReplaceAll(name, `\`, "/"))
+	if p == "." || path.IsAbs(p) || p == ".." || strings.HasPrefix(p, "../") {
+		return "", fmt.Errorf("invalid path %q in bundle", name)
+	}
+	return p, nil
+}
+
+// bundleFiles collects the files of a bundle by their cleaned path, keeping
+// within the size limits.
+type bundleFiles struct {
+	files map[string][]byte
+	total int64
+}
+
+func (b *bundleFiles) add(name string, r io.Reader) error {
+	p, err := bundlePath(name)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(io.LimitReader(r, maxBundleFileSize+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > maxBundleFileSize {
+		return fmt.Errorf("%s is larger than %d bytes", p, maxBundleFileSize)
+	}
+	b.total += int64(len(data))
+	if b.total > maxBundleTotalSize {
+		return fmt.Errorf("bundle is larger than %d bytes", maxBundleTotalSize)
+	}
+	b.files[p] = data
+	return nil
+}
+
+// loadBundle reads every regular file in a directory, zip or tar.gz bundle.
+func loadBundle(src string) (map[string][]byte, error) {
+	b := &bundleFiles{files: make(map[string][]byte)}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return err
+			}
+			rel, err := filepath.Rel(src, p)
+			if err != nil {
+				return err
+			}
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			return b.add(filepath.ToSlash(rel), f)
+		})
+		return b.files, err
+	}
+
+	switch bundleArchiveFormat(src) {
+	case "zip":
+		zr, err := zip.OpenReader(src)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			if !f.Mode().IsRegular() {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			err = b.add(f.Name, rc)
+			rc.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	case "tar.gz":
+		f, err := os.Open(src)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		tr := tar.NewReader(gz)
+		for {
+			hdr, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if hdr.Typeflag != tar.TypeReg {
+				continue
+			}
+			if err := b.add(hdr.Name, tr); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%s is not a directory, .zip or .tar.gz bundle", src)
+	}
+	return b.files, nil
+}
+
+// bundleManifest reads the manifest of a bundle. Without one, every YAML and
+// JSON file in the bundle is taken to be a level pack with no assets.
+func bundleManifest(files map[string][]byte) (*BundleManifest, error) {
+	for _, name := range bundleManifestNames {
+		data, ok := files[name]
+		if !ok {
+			continue
+		}
+		var manifest BundleManifest
+		if err := yaml.Unmarshal(data, &manifest); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		return &manifest, nil
+	}
+
+	var manifest BundleManifest
+	for name := range files {
+		switch strings.ToLower(path.Ext(name)) {
+		case ".yaml", ".yml", ".json":
+			manifest.Packs = append(manifest.Packs, BundlePack{Path: name})
+		}
+	}
+	sort.Slice(manifest.Packs, func(i, j int) bool {
+		return manifest.Packs[i].Path < manifest.Packs[j].Path
+	})
+	return &manifest, nil
+}
+
+// ImportBundle imports every level pack in a directory, zip or tar.gz
+// bundle, with their assets, in a single transaction. Either all of the
+// packs are imported or none are. It returns the imported packs.
+func (s *Store) ImportBundle(src string) ([]*LevelPack, error) {
+	files, err := loadBundle(src)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := bundleManifest(files)
+	if err != nil {
+		return nil, err
+	}
+	if len(manifest.Packs) == 0 {
+		return nil, fmt.Errorf("no level packs found in %s", src)
+	}
+
+	var packs []*LevelPack
+	err = s.InTx(func(tx *Store) error {
+		for _, bp := range manifest.Packs {
+			p, err := bundlePath(bp.Path)
+			if err != nil {
+				return err
+			}
+			data, ok := files[p]
+			if !ok {
+				return fmt.Errorf("%s is listed in the manifest but missing from the bundle", p)
+			}
+			levelPackYAML, err := DecodeLevelPackFile(p, data)
+			if err != nil {
+				return fmt.Errorf("%s: %w", p, err)
+			}
+			pack, err := tx.importPack(levelPackYAML)
+			if err != nil {
+				return fmt.Errorf("%s: %w", p, err)
+			}
+			packs = append(packs, pack)
+			for name, assetPath := range bp.Assets {
+				ap, err := bundlePath(assetPath)
+				if err != nil {
+					return err
+				}
+				asset, ok := files[ap]
+				if !ok {
+					return fmt.Errorf("asset %s of %s is missing from the bundle", ap, p)
+				}
+				if err := tx.UpsertPackAsset(pack.ID, name, asset); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("event=\"imported_bundle\" path=\"%s\" packs=%d", src, len(packs))
+	return packs, nil
+}
+
+// ExportBundle writes every installed level pack, with its assets and a
+// manifest, to a zip or tar.gz archive at dst.
+func (s *Store) ExportBundle(dst string, force bool) error {
+	format := bundleArchiveFormat(dst)
+	if format == "" {
+		return fmt.Errorf("%s must end in .zip, .tar.gz or .tgz", dst)
+	}
+
+	packs, err := s.GetAllLevelPacks()
+	if err != nil {
+		return err
+	}
+	sort.Slice(packs, func(i, j int) bool { return packs[i].Name < packs[j].Name })
+
+	var manifest BundleManifest
+	var names []string
+	files := make(map[string][]byte)
+	add := func(name string, data []byte) {
+		names = append(names, name)
+		files[name] = data
+	}
+	// A pack file can't take a name the manifest may be read from.
+	taken := func(name string) bool {
+		_, ok := files[name]
+		return ok || slices.Contains(bundleManifestNames, name)
+	}
+
+	for _, pack := range packs {
+		base := safeFileName(pack.Name)
+		if taken(base + ".yaml") {
+			base = fmt.Sprintf("%s-%d", base, pack.ID)
+		}
+		data, err := s.EncodeLevelPack(pack.ID, formatYAML)
+		if err != nil {
+			return err
+		}
+		bp := BundlePack{Path: base + ".yaml"}
+		add(bp.Path, data)
+
+		assets, err := s.GetPackAssets(pack.ID)
+		if err != nil {
+			return err
+		}
+		assetNames := make([]string, 0, len(assets))
+		for name := range assets {
+			assetNames = append(assetNames, name)
+		}
+		sort.Strings(assetNames)
+		for _, name := range assetNames {
+			if bp.Assets == nil {
+				bp.Assets = make(map[string]string)
+			}
+			bp.Assets[name] = path.Join("assets", base, safeFileName(name))
+			add(bp.Assets[name], assets[name])
+		}
+		manifest.Packs = append(manifest.Packs, bp)
+	}
+
+	data, err := yaml.Marshal(&manifest)
+	if err != nil {
+		return err
+	}
+	add("manifest.yaml", data)
+
+	var buf bytes.Buffer
+	if format == "zip" {
+		err = writeZip(&buf, names, files)
+	} else {
+		err = writeTarGz(&buf, names, files)
+	}
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(dst, buf.Bytes(), force); err != nil {
+		return err
+	}
+	log.Printf("event=\"exported_bundle\" path=\"%s\" packs=%d", dst, len(packs))
+	return nil
+}
+
+func writeZip(w io.Writer, names []string, files map[string][]byte) error {
+	zw := zip.NewWriter(w)
+	for _, name := range names {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(files[name]); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func writeTarGz(w io.Writer, names []string, files map[string][]byte) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, name := range names {
+		hdr := &tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(files[name])),
+			Typeflag: tar.TypeReg,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
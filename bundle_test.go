@@ -0,0 +1,167 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeBundleDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+const bundlePackA = `name: Pack A
+levels:
+  - name: A1
+    engine: nonogram
+    initial: "..\n.."
+    solution: "1.\n.1"
+`
+
+const bundlePackB = `{"name": "Pack B", "levels": [{"name": "B1", "engine": "nonogram", "initial": "..", "solution": "11"}]}`
+
+func TestBundlePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"packs/a.yaml", "packs/a.yaml", false},
+		{"./a.yaml", "a.yaml", false},
+		{`assets\dict.txt`, "assets/dict.txt", false},
+		{"../a.yaml", "", true},
+		{"packs/../../a.yaml", "", true},
+		{"/etc/passwd", "", true},
+		{".", "", true},
+	}
+	for _, tt := range tests {
+		got, err := bundlePath(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("bundlePath(%q) = %q, %v; want %q, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestImportBundleDirectoryWithoutManifest(t *testing.T) {
+	store := newTestStore(t)
+	dir := writeBundleDir(t, map[string]string{
+		"a.yaml":      bundlePackA,
+		"more/b.json": bundlePackB,
+		"README.txt":  "not a pack",
+	})
+
+	packs, err := store.ImportBundle(dir)
+	if err != nil {
+		t.Fatalf("failed to import bundle: %v", err)
+	}
+	if len(packs) != 2 {
+		t.Errorf("expected 2 packs imported, got %d", len(packs))
+	}
+	for _, name := range []string{"Pack A", "Pack B"} {
+		if _, err := store.FindLevelPack(name); err != nil {
+			t.Errorf("expected %s to be imported: %v", name, err)
+		}
+	}
+}
+
+func TestImportBundleIsAllOrNothing(t *testing.T) {
+	store := newTestStore(t)
+	dir := writeBundleDir(t, map[string]string{
+		"manifest.yaml": "packs:\n  - path: a.yaml\n  - path: broken.yaml\n",
+		"a.yaml":        bundlePackA,
+		"broken.yaml":   "name: [unterminated",
+	})
+
+	if _, err := store.ImportBundle(dir); err == nil {
+		t.Fatal("expected a broken pack to fail the import")
+	}
+	packs, err := store.GetAllLevelPacks()
+	if err != nil {
+		t.Fatalf("failed to list packs: %v", err)
+	}
+	if len(packs) != 0 {
+		t.Errorf("expected no packs after a failed import, got %+v", packs)
+	}
+}
+
+func TestExportBundleRoundTrip(t *testing.T) {
+	for _, name := range []string{"packs.zip", "packs.tar.gz"} {
+		t.Run(name, func(t *testing.T) {
+			src := newTestStore(t)
+			dir := writeBundleDir(t, map[string]string{
+				"manifest.yaml":    "packs:\n  - path: a.yaml\n    assets:\n      dict.txt: assets/words.txt\n  - path: b.json\n",
+				"a.yaml":           bundlePackA,
+				"b.json":           bundlePackB,
+				"assets/words.txt": "apple\npear\n",
+			})
+			if _, err := src.ImportBundle(dir); err != nil {
+				t.Fatalf("failed to import bundle: %v", err)
+			}
+
+			out := filepath.Join(t.TempDir(), name)
+			if err := src.ExportBundle(out, false); err != nil {
+				t.Fatalf("failed to export bundle: %v", err)
+			}
+			if err := src.ExportBundle(out, false); err == nil {
+				t.Error("expected export without force to refuse an existing file")
+			}
+
+			dst := newTestStore(t)
+			packs, err := dst.ImportBundle(out)
+			if err != nil {
+				t.Fatalf("failed to import exported bundle: %v", err)
+			}
+			if len(packs) != 2 {
+				t.Errorf("expected 2 packs, got %d", len(packs))
+			}
+
+			pack, err := dst.FindLevelPack("Pack A")
+			if err != nil {
+				t.Fatalf("failed to find Pack A: %v", err)
+			}
+			levels, err := dst.GetLevelsByPack(pack.ID)
+			if err != nil || len(levels) != 1 || levels[0].Solution != "1 \n 1" {
+				t.Errorf("unexpected levels %+v, err %v", levels, err)
+			}
+			assets, err := dst.GetPackAssets(pack.ID)
+			if err != nil {
+				t.Fatalf("failed to get assets: %v", err)
+			}
+			if string(assets["dict.txt"]) != "apple\npear\n" {
+				t.Errorf("expected dict.txt to survive the round trip, got %q", assets)
+			}
+		})
+	}
+}
+
+func TestExportBundleManifestPack(t *testing.T) {
+	src := newTestStore(t)
+	pack, _ := DecodeLevelPack([]byte(strings.Replace(bundlePackA, "Pack A", "manifest", 1)), formatYAML)
+	if _, err := src.importPack(pack); err != nil {
+		t.Fatalf("failed to import pack: %v", err)
+	}
+	out := filepath.Join(t.TempDir(), "packs.zip")
+	if err := src.ExportBundle(out, false); err != nil {
+		t.Fatalf("failed to export bundle: %v", err)
+	}
+
+	dst := newTestStore(t)
+	if _, err := dst.ImportBundle(out); err != nil {
+		t.Fatalf("failed to import exported bundle: %v", err)
+	}
+	if _, err := dst.FindLevelPack("manifest"); err != nil {
+		t.Errorf("expected a pack named manifest to survive the round trip, got %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// digitCells is an engine whose cells hold digits from 1 up to some highest
+// digit, entered by typing them.
+type digitCells interface {
+	HasCell(x, y int) bool
+	cellValue(x, y int) rune
+	ClearCell(x, y int) error
+	EnterValue(x, y int, v rune) error
+}
+
+// stepDigit moves the digit in a cell up or down, wrapping through blank
+// after maxDigit.
+func stepDigit(e digitCells, x, y, step, maxDigit int) error {
+	if !e.HasCell(x, y) {
+		return errors.New("coordinates out of bounds")
+	}
+	n := 0
+	if v := e.cellValue(x, y); v >= '1' && v < '1'+rune(maxDigit) {
+		n = int(v - '0')
+	}
+	n = ((n+step)%(maxDigit+1) + maxDigit + 1) % (maxDigit + 1)
+	if n == 0 {
+		return e.ClearCell(x, y)
+	}
+	return e.EnterValue(x, y, rune('0'+n))
+}
+
+// cellSeparator splits the values within a cell of a cell grid.
+const cellSeparator = `\`
+
+// parseCellGrid reads a level's cells: a grid with one line per row, cells
+// separated by whitespace and the values within a cell by a backslash. A
+// kakuro clue with a down sum of 17 and an across sum of 16 is "17\16", and
+// one with only an across sum is "\16". Every row must have width cells.
+func parseCellGrid(s string, width, height int) ([][][]string, error) {
+	lines := strings.Split(strings.Trim(s, "\n"), "\n")
+	if len(lines) != height {
+		return nil, fmt.Errorf("cells have %d rows, want %d", len(lines), height)
+	}
+	grid := make([][][]string, height)
+	for y, line := range lines {
+		tokens := strings.Fields(line)
+		if len(tokens) != width {
+			return nil, fmt.Errorf("cells row %d has %d cells, want %d", y+1, len(tokens), width)
+		}
+		grid[y] = make([][]string, width)
+		for x, token := range tokens {
+			grid[y][x] = strings.Split(token, cellSeparator)
+		}
+	}
+	return grid, nil
+}
+
+// parseRegionMap reads a level's regions: a grid with one line per row and
+// one character per cell, where cells with the same character are in the
+// same region. Regions are numbered from 0 in the order they first appear,
+// reading across each row from the top.
+func parseRegionMap(s string, width, height int) ([][]int, int, error) {
+	lines := strings.Split(strings.Trim(s, "\n"), "\n")
+	if len(lines) != height {
+		return nil, 0, fmt.Errorf("regions have %d rows, want %d", len(lines), height)
+	}
+	ids := make(map[rune]int)
+	regions := make([][]int, height)
+	for y, line := range lines {
+		row := []rune(line)
+		if len(row) != width {
+			return nil, 0, fmt.Errorf("regions row %d has %d cells, want %d", y+1, len(row), width)
+		}
+		regions[y] = make([]int, width)
+		for x, r := range row {
+			id, ok := ids[r]
+			if !ok {
+				id = len(ids)
+				ids[r] = id
+			}
+			regions[y][x] = id
+		}
+	}
+	return regions, len(ids), nil
+}
+
+// connectedCells reports whether a set of cells is joined up through
+// orthogonal neighbours.
+func connectedCells(cells [][2]int) bool {
+	if len(cells) == 0 {
+		return true
+	}
+	in := make(map[[2]int]bool)
+	for _, c := range cells {
+		in[c] = true
+	}
+	seen := map[[2]int]bool{cells[0]: true}
+	queue := [][2]int{cells[0]}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		for _, d := range [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+			n := [2]int{c[0] + d[0], c[1] + d[1]}
+			if in[n] && !seen[n] {
+				seen[n] = true
+				queue = append(queue, n)
+			}
+		}
+	}
+	return len(seen) == len(in)
+}
+
+// Sides of the grid, in the order a level's edges list them.
+const (
+	sideTop = iota
+	sideRight
+	sideBottom
+	sideLeft
+)
+
+// sideNames name the sides of the grid in error messages.
+var sideNames = [4]string{"top", "right", "bottom", "left"}
+
+// parseEdgeClues reads a level's edges: four lines of clues separated by
+// whitespace, for the top, right, bottom and left sides in turn. The top and
+// bottom run from left to right with one clue per column, and the sides from
+// top to bottom with one per row. A side is indexed by sideTop and so on, and
+// "." is a place without a clue.
+func parseEdgeClues(s string, width, height int) ([4][]string, error) {
+	var sides [4][]string
+	lines := strings.Split(strings.Trim(s, "\n"), "\n")
+	if len(lines) != len(sides) {
+		return sides, fmt.Errorf("edges have %d lines, want 4 for the top, right, bottom and left", len(lines))
+	}
+	for i, line := range lines {
+		want := width
+		if i == sideRight || i == sideLeft {
+			want = height
+		}
+		sides[i] = strings.Fields(line)
+		if len(sides[i]) != want {
+			return sides, fmt.Errorf("%s edge has %d clues, want %d", sideNames[i], len(sides[i]), want)
+		}
+	}
+	return sides, nil
+}
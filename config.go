@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds user settings read from the config file.
+type Config struct {
+	Theme  string  `yaml:"theme"`
+	Themes []Theme `yaml:"themes"`
+	Glyphs string  `yaml:"glyphs"`
+	// PackIndex is the URL of a JSON pack index to browse and install
+	// packs from.
+	PackIndex string `yaml:"pack_index"`
+	// Trust decides which signed packs may be imported.
+	Trust TrustConfig `yaml:"trust"`
+}
+
+// configPath returns the default location of the config file.
+func configPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "chronical", "config.yaml"), nil
+}
+
+// LoadConfig reads the config file at path. A missing file is not an error
+// and yields the default config.
+func LoadConfig(path string) (*Config, error) {
+	config := &Config{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("missing file", func(t *testing.T) {
+		config, err := LoadConfig(filepath.Join(dir, "missing.yaml"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if config.Theme != "" || len(config.Themes) != 0 {
+			t.Errorf("expected an empty config, got %+v", config)
+		}
+	})
+
+	t.Run("themes", func(t *testing.T) {
+		path := filepath.Join(dir, "config.yaml")
+		data := `
+theme: mine
+themes:
+  - name: mine
+    base: high-contrast
+    accent: "208"
+`
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+		config, err := LoadConfig(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		theme, err := findTheme(config.Theme, config.Themes)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if theme.Accent.ANSI256 != "208" {
+			t.Errorf("expected accent 208, got %+v", theme.Accent)
+		}
+		if theme.Title != builtinThemes["high-contrast"].Title {
+			t.Errorf("expected title from high-contrast, got %+v", theme.Title)
+		}
+	})
+}
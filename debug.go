@@ -8,6 +8,14 @@ const (
 	DebugEmptyTile     = ' '
 )
 
+var debugGlyphs = map[string]GlyphSet{
+	asciiGlyphs: {
+		DebugPrimaryTile:   "P",
+		DebugSecondaryTile: "S",
+		DebugEmptyTile:     " ",
+	},
+}
+
 // DebugEngine implements the GameEngine interface for debugging.
 type DebugEngine struct {
 	Engine
@@ -29,6 +37,12 @@ func (e *DebugEngine) SecondaryAction(x, y int) error {
 	return e.setCellValue(x, y, DebugSecondaryTile)
 }
 
+// GlyphSets returns the glyphs debug tiles are drawn with. The debug engine
+// only draws plain letters, so it has a single ascii set.
+func (e *DebugEngine) GlyphSets() map[string]GlyphSet {
+	return debugGlyphs
+}
+
 func (e *DebugEngine) View(m model) string {
 	g := e.gridView(m)
 	h := e.helpView(m)
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	solutionLayer uint = iota
+	givensLayer
+)
+
+// levelDraft is a level being authored in the editor. The solution is
+// painted cell by cell and givens reveal solution cells in the initial state.
+type levelDraft struct {
+	engine   string
+	solution [][]rune
+	givens   [][]bool
+}
+
+func newLevelDraft(engine string, width, height int) *levelDraft {
+	d := &levelDraft{
+		engine:   engine,
+		solution: make([][]rune, height),
+		givens:   make([][]bool, height),
+	}
+	for y := range d.solution {
+		d.solution[y] = []rune(strings.Repeat(string(EmptyTile), width))
+		d.givens[y] = make([]bool, width)
+	}
+	return d
+}
+
+func (d *levelDraft) GetWidth() int {
+	if len(d.solution) == 0 {
+		return 0
+	}
+	return len(d.solution[0])
+}
+
+func (d *levelDraft) GetHeight() int {
+	return len(d.solution)
+}
+
+func (d *levelDraft) HasCell(x, y int) bool {
+	return y >= 0 && y < len(d.solution) && x >= 0 && x < len(d.solution[y])
+}
+
+// paint sets a solution cell. A given that is painted blank stops being a
+// given, since there is nothing left to reveal.
+func (d *levelDraft) paint(x, y int, v rune) {
+	if !d.HasCell(x, y) {
+		return
+	}
+	d.solution[y][x] = v
+	if v == EmptyTile {
+		d.givens[y][x] = false
+	}
+}
+
+// setGiven marks whether a solution cell is revealed in the initial state.
+// Blank cells can't be givens.
+func (d *levelDraft) setGiven(x, y int, given bool) {
+	if !d.HasCell(x, y) {
+		return
+	}
+	d.givens[y][x] = given && d.solution[y][x] != EmptyTile
+}
+
+// Level builds a level from the draft, ready to validate and store.
+func (d *levelDraft) Level(name, author string) Level {
+	var initial, solution []string
+	for y, row := range d.solution {
+		var b strings.Builder
+		for x, r := range row {
+			if d.givens[y][x] {
+				b.WriteRune(r)
+			} else {
+				b.WriteRune(EmptyTile)
+			}
+		}
+		initial = append(initial, b.String())
+		solution = append(solution, string(row))
+	}
+	l := Level{
+		Name:     name,
+		Author:   author,
+		Engine:   d.engine,
+		Initial:  strings.Join(initial, "\n"),
+		Solution: strings.Join(solution, "\n"),
+	}
+	l.SetDimensions()
+	return l
+}
+
+// check reports whether the draft can be solved and whether its solution is
+// unique, using the engine's solver.
+func (d *levelDraft) check() string {
+	return checkLevel(d.Level("", ""))
+}
+
+// checkLevel reports whether a level can be solved and whether its solution
+// is unique, using its engine's solver.
+func checkLevel(l Level) string {
+	info, ok := engines[l.Engine]
+	if !ok || info.Solve == nil {
+		return fmt.Sprintf("no solver for %s levels", l.Engine)
+	}
+	count, ok := info.Solve(l, 2)
+	switch {
+	case !ok:
+		return "too hard to check"
+	case count == 0:
+		return "no solution"
+	case count == 1:
+		return "unique solution"
+	default:
+		return "multiple solutions"
+	}
+}
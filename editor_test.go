@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestLevelDraft(t *testing.T) {
+	d := newLevelDraft("nonogram", 3, 2)
+	d.paint(0, 0, FilledTile)
+	d.paint(2, 0, FilledTile)
+	d.paint(1, 1, FilledTile)
+	d.setGiven(0, 0, true)
+	d.setGiven(1, 0, true) // blank cells can't be givens
+	d.paint(5, 5, FilledTile)
+
+	l := d.Level("Draft", "Tester")
+	if l.Initial != "1  \n   " {
+		t.Errorf("unexpected initial state %q", l.Initial)
+	}
+	if l.Solution != "1 1\n 1 " {
+		t.Errorf("unexpected solution %q", l.Solution)
+	}
+	if l.Width != 3 || l.Height != 2 {
+		t.Errorf("expected 3x2 level, got %dx%d", l.Width, l.Height)
+	}
+	if err := l.Validate(); err != nil {
+		t.Errorf("expected a valid level, got %v", err)
+	}
+
+	d.paint(0, 0, EmptyTile)
+	if d.givens[0][0] {
+		t.Errorf("expected painting a given blank to remove it")
+	}
+}
+
+func TestLevelDraftCheck(t *testing.T) {
+	d := newLevelDraft("nonogram", 2, 2)
+	d.paint(0, 0, FilledTile)
+	d.paint(1, 1, FilledTile)
+	if got := d.check(); got != "multiple solutions" {
+		t.Errorf("expected multiple solutions, got %q", got)
+	}
+
+	d.setGiven(0, 0, true)
+	if got := d.check(); got != "unique solution" {
+		t.Errorf("expected a unique solution, got %q", got)
+	}
+
+	d.engine = "unknown"
+	if got := d.check(); got != "no solver for unknown levels" {
+		t.Errorf("expected no solver, got %q", got)
+	}
+}
+
+func TestEditorCheckRunsOnPaint(t *testing.T) {
+	m := model{editor: editorState{step: editorPaint, layer: solutionLayer, draft: newLevelDraft("nonogram", 2, 2)}}
+	if _, cmd := m.updateEditorPaint(tea.KeyMsg{Type: tea.KeyRight}); cmd != nil {
+		t.Error("expected moving the cursor not to check the draft")
+	}
+
+	_, first := m.updateEditorPaint(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'z'}})
+	if first == nil || m.editor.check != "checking..." {
+		t.Fatalf("expected painting to start a check, got %q", m.editor.check)
+	}
+	m.cursorX = 0
+	m.cursorY = 1
+	_, second := m.updateEditorPaint(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'z'}})
+	if second == nil {
+		t.Fatal("expected painting again to start another check")
+	}
+
+	// The first check finishes last, but the draft has changed since.
+	m.Update(second())
+	m.Update(first())
+	if m.editor.check != "multiple solutions" {
+		t.Errorf("expected the latest check to be shown, got %q", m.editor.check)
+	}
+}
@@ -3,18 +3,158 @@ package main
 import (
 	"errors"
 	"log"
+	"sort"
 	"strings"
 )
 
+// EngineInfo describes a game engine that levels can be played and authored
+// with.
+type EngineInfo struct {
+	// New returns an empty engine, ready for New(level, save).
+	New func() GameEngine
+	// Paint lists the values the level editor can paint into a solution,
+	// with the primary action's value first.
+	Paint []rune
+	// Solve counts the solutions of a level up to limit. ok is false when
+	// the search gave up before it could tell. It may be nil.
+	Solve func(l Level, limit int) (count int, ok bool)
+	// Validate checks a level as it is imported. It returns an error for a
+	// level that can't be played, or else a note for the import report,
+	// such as the level's par. It may be nil.
+	Validate func(l Level) (note string, err error)
+	// KeepDots marks engines whose grids use . as a value rather than a
+	// blank, such as the goals of sokoban, so imports leave it alone.
+	KeepDots bool
+}
+
+// engines maps the engine names used in level packs to their engines.
+var engines = map[string]EngineInfo{
+	"nonogram": {
+		New:   func() GameEngine { return new(NonogramEngine) },
+		Paint: []rune{FilledTile, EmptyTile},
+		Solve: countNonogramSolutions,
+	},
+	"minesweeper": {
+		New:   func() GameEngine { return new(MinesweeperEngine) },
+		Paint: []rune{MineTile, EmptyTile},
+		Solve: countMinesweeperSolutions,
+	},
+	"lightsout": {
+		New:      func() GameEngine { return new(LightsOutEngine) },
+		Paint:    []rune{LightOnTile, LightOffTile},
+		Solve:    countLightsOutSolutions,
+		Validate: validateLightsOut,
+	},
+	"kakuro": {
+		New:      func() GameEngine { return new(KakuroEngine) },
+		Paint:    []rune{KakuroBlockTile, EmptyTile, '1', '2', '3', '4', '5', '6', '7', '8', '9'},
+		Solve:    countKakuroSolutions,
+		Validate: validateKakuro,
+	},
+	"sudoku": {
+		New:      func() GameEngine { return new(SudokuEngine) },
+		Paint:    []rune{'1', EmptyTile, '2', '3', '4', '5', '6', '7', '8', '9'},
+		Solve:    countSudokuSolutions,
+		Validate: validateSudoku,
+	},
+	"starbattle": {
+		New:      func() GameEngine { return new(StarBattleEngine) },
+		Paint:    []rune{StarTile, EmptyTile},
+		Solve:    countStarBattleSolutions,
+		Validate: validateStarBattle,
+	},
+	"takuzu": {
+		New:      func() GameEngine { return new(TakuzuEngine) },
+		Paint:    []rune{TakuzuOneTile, TakuzuZeroTile},
+		Solve:    countTakuzuSolutions,
+		Validate: validateTakuzu,
+	},
+	"futoshiki": {
+		New:      func() GameEngine { return new(FutoshikiEngine) },
+		Paint:    []rune{'1', EmptyTile, '2', '3', '4', '5', '6', '7', '8', '9'},
+		Solve:    countFutoshikiSolutions,
+		Validate: validateFutoshiki,
+	},
+	"skyscrapers": {
+		New:      func() GameEngine { return new(SkyscrapersEngine) },
+		Paint:    []rune{'1', EmptyTile, '2', '3', '4', '5', '6', '7', '8', '9'},
+		Solve:    countSkyscrapersSolutions,
+		Validate: validateSkyscrapers,
+	},
+	"slitherlink": {
+		New:      func() GameEngine { return new(SlitherlinkEngine) },
+		Paint:    []rune{SlitherAcrossTile, KnownEmptyTile, SlitherDownTile, SlitherPointTile, '0', '1', '2', '3', '4'},
+		Solve:    countSlitherlinkSolutions,
+		Validate: validateSlitherlink,
+	},
+	"hashi": {
+		New:      func() GameEngine { return new(HashiEngine) },
+		Paint:    []rune{HashiAcrossTile, EmptyTile, HashiDoubleAcrossTile, HashiDownTile, HashiDoubleDownTile, '1', '2', '3', '4', '5', '6', '7', '8'},
+		Solve:    countHashiSolutions,
+		Validate: validateHashi,
+	},
+	"sokoban": {
+		New:      func() GameEngine { return new(SokobanEngine) },
+		Paint:    []rune{SokobanWallTile, EmptyTile, SokobanBoxTile, SokobanGoalTile, SokobanBoxOnGoalTile, SokobanPlayerTile, SokobanPlayerOnGoalTile},
+		Validate: validateSokoban,
+		KeepDots: true,
+	},
+}
+
+// engineNames returns the registered engine names in a stable order.
+func engineNames() []string {
+	var names []string
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateLevel runs the checks a level's engine makes on import.
+func validateLevel(l Level) (string, error) {
+	info, ok := engines[l.Engine]
+	if !ok || info.Validate == nil {
+		return "", nil
+	}
+	return info.Validate(l)
+}
+
+// newEngine loads a level into the engine it names, falling back to the
+// debug engine for engines that aren't registered.
+func newEngine(l Level, s *Save) (GameEngine, error) {
+	info, ok := engines[l.Engine]
+	if !ok {
+		log.Printf("event=\"engine_not_found\" level_engine=\"%v\"", l.Engine)
+		l.Engine = "fallback"
+		return new(DebugEngine).New(l, s)
+	}
+	return info.New().New(l, s)
+}
+
+// Outcome is where a game stands after a move.
+type Outcome uint
+
+const (
+	outcomePlaying Outcome = iota
+	outcomeWon
+	// outcomeLost ends the game until the level is restarted.
+	outcomeLost
+)
+
 // GameEngine defines the interface for a game engine.
 type GameEngine interface {
 	New(l Level, s *Save) (GameEngine, error)
 	Evaluate() (bool, error)
+	Outcome() Outcome
 	PrimaryAction(x, y int) error
 	SecondaryAction(x, y int) error
 	setCellValue(x, y int, value rune) error
 	ClearCell(x, y int) error
 	View(m model) string
+	CellAt(col, row int) (int, int, bool)
+	Zoom(step int)
+	GlyphSets() map[string]GlyphSet
 	GetWidth() int
 	GetHeight() int
 	HasCell(x, y int) bool
@@ -23,6 +163,29 @@ type GameEngine interface {
 	GetGameName() string
 }
 
+// valueEntry is implemented by engines whose cells take typed values, such
+// as digits. The game view passes it any key it doesn't use itself, and the
+// engine returns an error for values it doesn't accept.
+type valueEntry interface {
+	EnterValue(x, y int, v rune) error
+}
+
+// cursorMover is implemented by engines whose cursor doesn't step from cell
+// to cell, such as engines with state on the edges between cells. It
+// returns where the cursor lands when moved by dx, dy from x, y, and false
+// when it can't move that way.
+type cursorMover interface {
+	MoveCursor(x, y, dx, dy int) (int, int, bool)
+}
+
+// dragPainter is implemented by engines whose actions paint a value into a
+// cell, such as a nonogram fill, so dragging paints every cell passed over.
+// Other engines take a single click, as repeating an action such as
+// toggling a light along a drag would undo it.
+type dragPainter interface {
+	dragPaints()
+}
+
 // Engine implements the GameEngine interface.
 type Engine struct {
 	GameName string
@@ -35,6 +198,9 @@ func (e *Engine) New(l Level, s *Save) (GameEngine, error) {
 	if s == nil {
 		log.Printf("event=\"EmptyLevelLoad\" level_id=%d", l.ID)
 		s = l.CreateSave(l.Initial, false)
+	} else if !stateFits(l, s.State) {
+		log.Printf("event=\"MismatchedLevelLoad\" level_id=%d state=\"%v\"", l.ID, s.State)
+		s = l.CreateSave(l.Initial, false)
 	} else {
 		log.Printf("event=\"StatefulLevelLoad\" level_id=%d state=\"%v\"", l.ID, s.State)
 	}
@@ -61,10 +227,36 @@ func (e *Engine) New(l Level, s *Save) (GameEngine, error) {
 	return e, nil
 }
 
+// stateFits reports whether a saved state has a value for every cell of a
+// level's grid. Lines after the grid are left to the engine, which may keep
+// more there, such as the moves made so far.
+func stateFits(l Level, state string) bool {
+	iRows := strings.Split(l.Initial, "\n")
+	sRows := strings.Split(state, "\n")
+	if len(sRows) < len(iRows) {
+		return false
+	}
+	for y, row := range iRows {
+		if len(sRows[y]) != len(row) {
+			return false
+		}
+	}
+	return true
+}
+
 func (e *Engine) Evaluate() (bool, error) {
 	return e.Level.Solution == e.Save.State, nil
 }
 
+// Outcome reports whether the level is won. The base engine has no way to
+// lose.
+func (e *Engine) Outcome() Outcome {
+	if e.Save.Solved {
+		return outcomeWon
+	}
+	return outcomePlaying
+}
+
 func (e *Engine) PrimaryAction(x, y int) error {
 	return errors.New("not implemented")
 }
@@ -86,6 +278,21 @@ func (e *Engine) View(m model) string {
 	return ""
 }
 
+// CellAt maps a position within the engine's rendered view to grid
+// coordinates. The base engine does not render a grid, so nothing is hit.
+func (e *Engine) CellAt(col, row int) (int, int, bool) {
+	return 0, 0, false
+}
+
+// Zoom changes how large cells are drawn. The base engine has a fixed size.
+func (e *Engine) Zoom(step int) {}
+
+// GlyphSets returns the glyphs cell values are drawn with. The base engine
+// draws cell values as they are.
+func (e *Engine) GlyphSets() map[string]GlyphSet {
+	return nil
+}
+
 func (e *Engine) GetWidth() int {
 	return len(e.Grid[0])
 }
@@ -110,6 +317,23 @@ func (e *Engine) GetGameName() string {
 	return e.GameName
 }
 
+// cellValue returns the value in a cell, which must be on the grid.
+func (e *Engine) cellValue(x, y int) rune {
+	return e.Grid[y][x].value
+}
+
+// values returns the grid's current values.
+func (e *Engine) values() [][]rune {
+	values := make([][]rune, len(e.Grid))
+	for y, row := range e.Grid {
+		values[y] = make([]rune, len(row))
+		for x, cell := range row {
+			values[y][x] = cell.value
+		}
+	}
+	return values
+}
+
 func (e *Engine) setCellValue(x, y int, value rune) error {
 	if !e.HasCell(x, y) {
 		return errors.New("coordinates out of bounds")
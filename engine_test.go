@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestEngine loads a level into its engine with no save. The level's ID
+// and name default to ones that are fine for tests that don't care.
+func newTestEngine(t *testing.T, l Level) GameEngine {
+	t.Helper()
+	return loadTestEngine(t, l, nil)
+}
+
+// loadTestEngine loads a level into its engine, resuming from s.
+func loadTestEngine(t *testing.T, l Level, s *Save) GameEngine {
+	t.Helper()
+	if l.ID == 0 {
+		l.ID = 1
+	}
+	if l.Name == "" {
+		l.Name = "Test"
+	}
+	game, err := newEngine(l, s)
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+	return game
+}
+
+func TestEngineNewMismatchedSave(t *testing.T) {
+	level := Level{Engine: "nonogram", Initial: "  \n  ", Solution: "1 \n 1"}
+	for _, state := range []string{"1", "1 \n1", "1  \n 1 "} {
+		game := loadTestEngine(t, level, &Save{State: state})
+		if got := game.GetSave().State; got != level.Initial {
+			t.Errorf("state %q: expected a save that doesn't fit to start over, got %q", state, got)
+		}
+	}
+}
+
+// TestImportEngineLevels imports a level for each engine that validates its
+// levels, checks it is stored as written and loads, and checks a broken
+// variant of it is refused.
+func TestImportEngineLevels(t *testing.T) {
+	testCases := []struct {
+		name    string
+		level   Level
+		bad     Level
+		wantErr string
+	}{
+		// Every 3x3 board can be turned off, but a 4x4 board has patterns
+		// that can't be.
+		{
+			name:    "lightsout",
+			level:   Level{Engine: "lightsout", Options: LevelOptions{"wrap": "false"}, Initial: "1..\n...\n...", Solution: "...\n...\n..."},
+			bad:     Level{Engine: "lightsout", Initial: "1...\n....\n....\n....", Solution: "....\n....\n....\n...."},
+			wantErr: "can't all be turned off",
+		},
+		{
+			name:    "kakuro",
+			level:   testKakuroLevel,
+			bad:     Level{Engine: "kakuro", Initial: testKakuroLevel.Initial, Solution: testKakuroSolution, Cells: strings.Replace(testKakuroCells, "\\7", "\\8", 1)},
+			wantErr: "breaks the clue",
+		},
+		// The classic boxes don't fit a solution made for jigsaw regions.
+		{
+			name:    "sudoku",
+			level:   Level{Engine: "sudoku", Initial: "1...\n.4..\n..4.\n...1", Solution: testJigsawSolution, Regions: testJigsawRegions},
+			bad:     Level{Engine: "sudoku", Initial: "1...\n.4..\n..4.\n...1", Solution: testJigsawSolution},
+			wantErr: "breaks the rules",
+		},
+		// Stars may not touch, even diagonally.
+		{
+			name:    "starbattle",
+			level:   testStarBattleLevel,
+			bad:     Level{Engine: "starbattle", Initial: testStarBattleBlank, Solution: "*....\n.*...\n....*\n.*...\n...*.", Regions: testStarBattleRegions},
+			wantErr: "breaks the rules",
+		},
+		// No two rows may be the same.
+		{
+			name:    "takuzu",
+			level:   Level{Engine: "takuzu", Initial: testTakuzuOpenGivens, Solution: testTakuzuSolution},
+			bad:     Level{Engine: "takuzu", Initial: testTakuzuBlank, Solution: "0101\n1010\n0101\n1010"},
+			wantErr: "breaks the rules",
+		},
+		// The latin square puzzles refuse a sign or clue the solution breaks.
+		{
+			name:    "futoshiki",
+			level:   Level{Engine: "futoshiki", Initial: testLatinBlank, Solution: testLatinSolution, Cells: testFutoshikiCells},
+			bad:     Level{Engine: "futoshiki", Initial: testLatinBlank, Solution: testLatinSolution, Cells: strings.Replace(testFutoshikiCells, "<", ">", 1)},
+			wantErr: "breaks the rules",
+		},
+		{
+			name:    "skyscrapers",
+			level:   Level{Engine: "skyscrapers", Initial: testLatinBlank, Solution: testLatinSolution, Edges: testSkyscrapersUniqueEdges},
+			bad:     Level{Engine: "skyscrapers", Initial: testLatinBlank, Solution: testLatinSolution, Edges: strings.Replace(testSkyscrapersUniqueEdges, ". 4 . .", ". 3 . .", 1)},
+			wantErr: "breaks the rules",
+		},
+		// The loop may only go round once, not as a second loop below.
+		{
+			name:    "slitherlink",
+			level:   testSlitherlinkLevel,
+			bad:     Level{Engine: "slitherlink", Initial: testSlitherlinkInitial, Solution: strings.Replace(testSlitherlinkSolution, ".......\n+.+.+.+", "|.....|\n+-+-+-+", 1)},
+			wantErr: "single loop",
+		},
+		// A single bridge between the top 3 and 4 leaves both short.
+		{
+			name:    "hashi",
+			level:   testHashiLevel,
+			bad:     Level{Engine: "hashi", Initial: testHashiInitial, Solution: strings.Replace(testHashiSolution, "3=4", "3-4", 1)},
+			wantErr: "connected group",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newTestStore(t)
+			tc.level.Name = "Good"
+			pack, err := importTestPack(t, store, tc.name, tc.level)
+			if err != nil {
+				t.Fatalf("failed to import pack: %v", err)
+			}
+			levels, err := store.GetLevelsByPack(pack.ID)
+			if err != nil || len(levels) != 1 {
+				t.Fatalf("expected 1 level, got %d, %v", len(levels), err)
+			}
+			got, want := levels[0], tc.level
+			want.Initial = strings.ReplaceAll(want.Initial, ".", " ")
+			want.Solution = strings.ReplaceAll(want.Solution, ".", " ")
+			got.ID, got.Identity = 0, ""
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("expected the level to be stored as written, got %#v, want %#v", got, want)
+			}
+			newTestEngine(t, levels[0])
+
+			tc.bad.Name = "Bad"
+			if _, err := importTestPack(t, store, tc.name+" broken", tc.bad); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected the broken level to be refused with %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+// importTestPack imports a pack of the given levels from a JSON file.
+func importTestPack(t *testing.T, store *Store, name string, levels ...Level) (*LevelPack, error) {
+	t.Helper()
+	data, err := json.Marshal(LevelPackYAML{Name: name, Levels: levels})
+	if err != nil {
+		t.Fatalf("failed to encode pack: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "pack.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write pack: %v", err)
+	}
+	return store.ImportLevelPackFile(path)
+}
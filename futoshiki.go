@@ -0,0 +1,232 @@
+// This file implements the Futoshiki game logic on the Latin-square engine.
+//
+// Signs between neighbouring cells say which of the two digits is bigger.
+// They are kept in the level's cells, one cell per grid cell, with "<" or ">"
+// for a sign between the cell and the one to its right, and "^" or "v" for
+// a sign between the cell and the one below it. The sign points at the
+// smaller digit, so "^" puts a smaller digit above a bigger one. A cell with
+// signs on both sides holds both, such as "<\v", and "." is a cell with none.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// futoshikiSignGlyphs draw the signs between cells, keyed as they are
+// written in a level.
+var futoshikiSignGlyphs = map[string]GlyphSet{
+	unicodeGlyphs: {'<': "<", '>': ">", '^': "∧", 'v': "∨"},
+	asciiGlyphs:   {'<': "<", '>': ">", '^': "^", 'v': "v"},
+	emojiGlyphs:   {'<': "<", '>': ">", '^': "∧", 'v': "∨"},
+}
+
+// futoshikiSign is an inequality between a cell and its neighbour to the
+// right or below.
+type futoshikiSign struct {
+	x, y int
+	down bool
+	// less is whether the first cell holds the smaller digit.
+	less bool
+}
+
+// other returns the neighbour the sign compares its cell with.
+func (s futoshikiSign) other() (int, int) {
+	if s.down {
+		return s.x, s.y + 1
+	}
+	return s.x + 1, s.y
+}
+
+// glyph returns the rune the sign is written as in a level.
+func (s futoshikiSign) glyph() rune {
+	switch {
+	case s.down && s.less:
+		return '^'
+	case s.down:
+		return 'v'
+	case s.less:
+		return '<'
+	}
+	return '>'
+}
+
+// futoshikiRules are the signs of a level.
+type futoshikiRules struct {
+	size  int
+	signs []futoshikiSign
+	// right and down index the sign after each cell, or hold -1.
+	right, down [][]int
+}
+
+// futoshikiRulesFor reads the signs of a level from its cells.
+func futoshikiRulesFor(l Level) (*futoshikiRules, error) {
+	n, err := latinSize(l)
+	if err != nil {
+		return nil, err
+	}
+	r := &futoshikiRules{size: n, right: make([][]int, n), down: make([][]int, n)}
+	for y := 0; y < n; y++ {
+		r.right[y] = make([]int, n)
+		r.down[y] = make([]int, n)
+		for x := 0; x < n; x++ {
+			r.right[y][x], r.down[y][x] = -1, -1
+		}
+	}
+	if strings.TrimSpace(l.Cells) == "" {
+		return r, nil
+	}
+	grid, err := parseCellGrid(l.Cells, n, n)
+	if err != nil {
+		return nil, err
+	}
+	for y, row := range grid {
+		for x, values := range row {
+			for _, v := range values {
+				sign := futoshikiSign{x: x, y: y}
+				at := r.right
+				switch v {
+				case ".", "":
+					continue
+				case "<", ">":
+					sign.less = v == "<"
+				case "^", "v":
+					sign.down, sign.less = true, v == "^"
+					at = r.down
+				default:
+					return nil, fmt.Errorf("cell at row %d column %d has an unknown sign %q", y+1, x+1, v)
+				}
+				if ox, oy := sign.other(); ox >= n || oy >= n {
+					return nil, fmt.Errorf("sign %s at row %d column %d points off the grid", v, y+1, x+1)
+				}
+				if at[y][x] >= 0 {
+					return nil, fmt.Errorf("cell at row %d column %d has two signs on the same side", y+1, x+1)
+				}
+				at[y][x] = len(r.signs)
+				r.signs = append(r.signs, sign)
+			}
+		}
+	}
+	return r, nil
+}
+
+// check flags digits that break a sign. A lone digit breaks one when no
+// digit could go on the other side, such as a 1 that should be bigger.
+func (r *futoshikiRules) check(values [][]rune, broken [][]bool) []lineStatus {
+	status := make([]lineStatus, len(r.signs))
+	for i, s := range r.signs {
+		ox, oy := s.other()
+		a, b := values[s.y][s.x], values[oy][ox]
+		aOK, bOK := latinDigit(a, r.size), latinDigit(b, r.size)
+		top, bottom := '0'+rune(r.size), '1'
+		switch {
+		case aOK && bOK:
+			if (a < b) == s.less {
+				status[i] = lineSatisfied
+				continue
+			}
+			broken[s.y][s.x], broken[oy][ox] = true, true
+		case aOK && ((s.less && a == top) || (!s.less && a == bottom)):
+			broken[s.y][s.x] = true
+		case bOK && ((s.less && b == bottom) || (!s.less && b == top)):
+			broken[oy][ox] = true
+		default:
+			continue
+		}
+		status[i] = lineBroken
+	}
+	return status
+}
+
+// validateFutoshiki refuses levels whose signs are invalid, whose solution
+// breaks the rules or whose givens don't match the solution.
+func validateFutoshiki(l Level) (string, error) {
+	r, err := futoshikiRulesFor(l)
+	if err != nil {
+		return "", err
+	}
+	return validateLatin(l, r)
+}
+
+// countFutoshikiSolutions counts the ways to fill in a level's grid, up to
+// limit.
+func countFutoshikiSolutions(l Level, limit int) (int, bool) {
+	r, err := futoshikiRulesFor(l)
+	if err != nil {
+		return 0, true
+	}
+	return countLatinSolutions(l, r, limit)
+}
+
+type FutoshikiEngine struct {
+	LatinSquareEngine
+	rules *futoshikiRules
+}
+
+func (e *FutoshikiEngine) New(l Level, s *Save) (GameEngine, error) {
+	rules, err := futoshikiRulesFor(l)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.newLatin(l, s, rules); err != nil {
+		return nil, err
+	}
+	e.rules = rules
+	return e, nil
+}
+
+func (e *FutoshikiEngine) View(m model) string {
+	return lipgloss.JoinVertical(lipgloss.Left, e.gridView(m), e.helpView(m))
+}
+
+// CellAt maps a position within the rendered view to grid coordinates. The
+// gaps for signs between cells aren't cells.
+func (e *FutoshikiEngine) CellAt(col, row int) (int, int, bool) {
+	step := latinCellWidth + 1
+	if col < 0 || row < 0 || col%step == latinCellWidth || row%2 == 1 {
+		return 0, 0, false
+	}
+	x, y := col/step, row/2
+	if !e.HasCell(x, y) {
+		return 0, 0, false
+	}
+	return x, y, true
+}
+
+// --- Private Functions ---
+
+// signView draws the sign after a cell, or a gap when there is none.
+func (e *FutoshikiEngine) signView(i, width int) string {
+	style := lipgloss.NewStyle()
+	g := " "
+	if i >= 0 {
+		style = hintLineStyle(e.clueStatus[i])
+		g = glyphSetFor(futoshikiSignGlyphs)[e.rules.signs[i].glyph()]
+	}
+	return style.Width(width).AlignHorizontal(lipgloss.Center).Render(g)
+}
+
+// gridView draws the digits with the signs between them, leaving a column
+// between cells and a line between rows.
+func (e *FutoshikiEngine) gridView(m model) string {
+	var rows []string
+	for y := 0; y < e.size; y++ {
+		var cells, below []string
+		for x := 0; x < e.size; x++ {
+			cells = append(cells, e.cellView(x, y, m))
+			below = append(below, e.signView(e.rules.down[y][x], latinCellWidth))
+			if x < e.size-1 {
+				cells = append(cells, e.signView(e.rules.right[y][x], 1))
+				below = append(below, " ")
+			}
+		}
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, cells...))
+		if y < e.size-1 {
+			rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, below...))
+		}
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, rows...)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	// testFutoshikiCells settle testLatinSolution without givens.
+	testFutoshikiCells = "v v < .\n. > . .\n^ . . ^\n. . . ."
+)
+
+func TestFutoshikiRulesErrors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		cells string
+		want  string
+	}{
+		{name: "unknown sign", cells: "= . . .\n. . . .\n. . . .\n. . . .", want: "unknown sign"},
+		{name: "off the right", cells: ". . . <\n. . . .\n. . . .\n. . . .", want: "off the grid"},
+		{name: "off the bottom", cells: ". . . .\n. . . .\n. . . .\n^ . . .", want: "off the grid"},
+		{name: "two on one side", cells: "<\\> . . .\n. . . .\n. . . .\n. . . .", want: "same side"},
+		{name: "short row", cells: ". . .\n. . . .\n. . . .\n. . . .", want: "want 4"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := futoshikiRulesFor(Level{Solution: testLatinSolution, Cells: tc.cells})
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected an error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestFutoshikiPlay(t *testing.T) {
+	e := newTestEngine(t, Level{Engine: "futoshiki", Initial: testLatinBlank, Solution: testLatinSolution, Cells: testFutoshikiCells}).(*FutoshikiEngine)
+	if len(e.rules.signs) != 6 {
+		t.Fatalf("expected 6 signs, got %d", len(e.rules.signs))
+	}
+
+	// A 4 can't be smaller than the cell to its right.
+	e.EnterValue(2, 0, '4')
+	if e.Grid[0][2].state != invalid || e.clueStatus[e.rules.right[0][2]] != lineBroken {
+		t.Error("expected a 4 before < to break the sign")
+	}
+	e.EnterValue(2, 0, '1')
+	e.EnterValue(3, 0, '3')
+	if e.Grid[0][2].state == invalid || e.clueStatus[e.rules.right[0][2]] != lineSatisfied {
+		t.Error("expected 1 < 3 to satisfy the sign")
+	}
+	e.EnterValue(0, 0, '1')
+	e.EnterValue(0, 1, '2')
+	if e.Grid[0][0].state != invalid || e.Grid[1][0].state != invalid {
+		t.Error("expected 1 above 2 to break a v sign")
+	}
+
+	for y, row := range strings.Split(testLatinSolution, "\n") {
+		for x, v := range row {
+			e.EnterValue(x, y, v)
+		}
+	}
+	if !e.Save.Solved {
+		t.Errorf("expected the grid to be solved, got %q", e.Save.State)
+	}
+	e.SecondaryAction(0, 0)
+	if e.Grid[0][0].value != '1' || e.Save.Solved {
+		t.Errorf("expected stepping 2 down to 1 to unsolve the grid, got %q", e.Grid[0][0].value)
+	}
+}
+
+func TestFutoshikiView(t *testing.T) {
+	// Each row of cells has a line of signs below it, and signs between
+	// cells take a column.
+	e := newTestEngine(t, Level{Engine: "futoshiki", Initial: testLatinBlank, Solution: testLatinSolution, Cells: testFutoshikiCells}).(*FutoshikiEngine)
+	lines := strings.Split(e.gridView(model{}), "\n")
+	if len(lines) != 7 {
+		t.Fatalf("expected 7 lines, got %d", len(lines))
+	}
+	if !strings.Contains(lines[1], "∨") || !strings.Contains(lines[2], ">") {
+		t.Errorf("expected signs between the cells, got\n%s", strings.Join(lines, "\n"))
+	}
+	if x, y, ok := e.CellAt(5, 2); !ok || x != 1 || y != 1 {
+		t.Errorf("expected column 5 row 2 to be cell 1,1, got %d,%d %v", x, y, ok)
+	}
+	if _, _, ok := e.CellAt(3, 0); ok {
+		t.Error("expected the gap for a sign not to be a cell")
+	}
+	if _, _, ok := e.CellAt(0, 1); ok {
+		t.Error("expected the line of signs not to be a cell")
+	}
+}
+
+func TestCountFutoshikiSolutions(t *testing.T) {
+	testCases := []struct {
+		name  string
+		cells string
+		want  int
+	}{
+		{name: "unique", cells: testFutoshikiCells, want: 1},
+		{name: "not enough signs", cells: "v v . .\n. > . .\n^ . . ^\n. . . .", want: 2},
+		{name: "signs in a circle", cells: "<\\v ^ . .\n> . . .\n. . . .\n. . . .", want: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := Level{Initial: testLatinBlank, Solution: testLatinSolution, Cells: tc.cells}
+			got, ok := countFutoshikiSolutions(l, 2)
+			if !ok || got != tc.want {
+				t.Errorf("expected %d solutions, got %d (ok %v)", tc.want, got, ok)
+			}
+		})
+	}
+}
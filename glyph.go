@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+)
+
+const (
+	unicodeGlyphs = "unicode"
+	asciiGlyphs   = "ascii"
+	emojiGlyphs   = "emoji"
+)
+
+// GlyphSet maps the cell values of an engine to the text drawn for them.
+type GlyphSet map[rune]string
+
+// activeGlyphSet is the name of the glyph set every engine draws with.
+var activeGlyphSet = unicodeGlyphs
+
+// glyphSetNames returns the names of the glyph sets engines can provide.
+func glyphSetNames() []string {
+	names := []string{unicodeGlyphs, asciiGlyphs, emojiGlyphs}
+	sort.Strings(names)
+	return names
+}
+
+// detectGlyphSet picks the unicode glyph set when the locale is UTF-8 and
+// falls back to ascii otherwise. The locale variables are checked in the
+// order the C library uses.
+func detectGlyphSet(getenv func(string) string) string {
+	for _, key := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		v := getenv(key)
+		if v == "" {
+			continue
+		}
+		v = strings.ToLower(v)
+		if strings.Contains(v, "utf-8") || strings.Contains(v, "utf8") {
+			return unicodeGlyphs
+		}
+		return asciiGlyphs
+	}
+	return asciiGlyphs
+}
+
+// glyphSetFor returns the active set from an engine's glyph sets, falling
+// back to its ascii set, which every engine is expected to provide.
+func glyphSetFor(sets map[string]GlyphSet) GlyphSet {
+	if set, ok := sets[activeGlyphSet]; ok {
+		return set
+	}
+	return sets[asciiGlyphs]
+}
+
+// glyphWidth measures how many columns a glyph takes. Ambiguous width
+// characters are counted as wide when the locale asks for it, since that is
+// how those terminals draw them.
+func glyphWidth(s string) int {
+	return max(lipgloss.Width(s), runewidth.StringWidth(s))
+}
+
+// widestGlyph returns the width of the widest glyph in a set.
+func widestGlyph(set GlyphSet) int {
+	w := 0
+	for _, g := range set {
+		w = max(w, glyphWidth(g))
+	}
+	return w
+}
+
+// envGlyphSet is the glyph set detected from the process locale.
+func envGlyphSet() string {
+	return detectGlyphSet(os.Getenv)
+}
@@ -0,0 +1,70 @@
+package main
+
+import "testing"
+
+func TestDetectGlyphSet(t *testing.T) {
+	testCases := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{name: "no locale", env: map[string]string{}, want: asciiGlyphs},
+		{name: "utf-8 lang", env: map[string]string{"LANG": "en_US.UTF-8"}, want: unicodeGlyphs},
+		{name: "utf8 lang", env: map[string]string{"LANG": "C.utf8"}, want: unicodeGlyphs},
+		{name: "posix lang", env: map[string]string{"LANG": "C"}, want: asciiGlyphs},
+		{name: "lc_all wins", env: map[string]string{"LC_ALL": "C", "LANG": "en_US.UTF-8"}, want: asciiGlyphs},
+		{name: "lc_ctype before lang", env: map[string]string{"LC_CTYPE": "en_GB.UTF-8", "LANG": "C"}, want: unicodeGlyphs},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := detectGlyphSet(func(key string) string { return tc.env[key] })
+			if got != tc.want {
+				t.Errorf("detectGlyphSet() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGlyphSetFor(t *testing.T) {
+	defer func(name string) { activeGlyphSet = name }(activeGlyphSet)
+
+	activeGlyphSet = emojiGlyphs
+	if got := glyphSetFor(nonogramGlyphs)[FilledTile]; got != nonogramGlyphs[emojiGlyphs][FilledTile] {
+		t.Errorf("expected the emoji glyph, got %q", got)
+	}
+	if got := glyphSetFor(debugGlyphs)[DebugPrimaryTile]; got != "P" {
+		t.Errorf("expected engines without the active set to fall back to ascii, got %q", got)
+	}
+}
+
+func TestEngineGlyphSetsComplete(t *testing.T) {
+	engines := map[string]struct {
+		sets  map[string]GlyphSet
+		tiles []rune
+	}{
+		"nonogram": {nonogramGlyphs, []rune{FilledTile, KnownEmptyTile, EmptyTile}},
+		"debug":    {debugGlyphs, []rune{DebugPrimaryTile, DebugSecondaryTile, DebugEmptyTile}},
+	}
+	for name, e := range engines {
+		if _, ok := e.sets[asciiGlyphs]; !ok {
+			t.Errorf("engine %q has no ascii glyph set", name)
+		}
+		for setName, set := range e.sets {
+			for _, tile := range e.tiles {
+				if _, ok := set[tile]; !ok {
+					t.Errorf("engine %q glyph set %q has no glyph for %q", name, setName, tile)
+				}
+			}
+		}
+	}
+}
+
+func TestWidestGlyph(t *testing.T) {
+	if got := widestGlyph(nonogramGlyphs[asciiGlyphs]); got != 1 {
+		t.Errorf("expected ascii glyphs to be one column wide, got %d", got)
+	}
+	if got := widestGlyph(nonogramGlyphs[emojiGlyphs]); got != 2 {
+		t.Errorf("expected emoji glyphs to be two columns wide, got %d", got)
+	}
+}
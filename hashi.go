@@ -0,0 +1,655 @@
+// This file implements the Hashi game logic, also known as Bridges.
+//
+// An island holds the number of bridges that end at it, from 1 to 8, and
+// the water between islands holds the bridges: - or = for a single or
+// double bridge across, | or H for one down, and . for open water. Bridges
+// run straight between two islands in the same row or column with only
+// water between them, at most two to a pair, and never cross. Every island
+// has to end up with its count of bridges, and the bridges join the islands
+// into one connected group. Islands side by side can't be joined.
+//
+// The islands, and any bridges in the initial state, are given. Bridges are
+// checked against the rules rather than the solution.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	HashiAcrossTile       = rune('-')
+	HashiDoubleAcrossTile = rune('=')
+	HashiDownTile         = rune('|')
+	HashiDoubleDownTile   = rune('H')
+)
+
+var hashiGlyphs = map[string]GlyphSet{
+	unicodeGlyphs: {
+		HashiAcrossTile:       "─",
+		HashiDoubleAcrossTile: "═",
+		HashiDownTile:         "│",
+		HashiDoubleDownTile:   "║",
+		EmptyTile:             " ",
+	},
+	asciiGlyphs: {
+		HashiAcrossTile:       "-",
+		HashiDoubleAcrossTile: "=",
+		HashiDownTile:         "|",
+		HashiDoubleDownTile:   "H",
+		EmptyTile:             " ",
+	},
+	emojiGlyphs: {
+		HashiAcrossTile:       "─",
+		HashiDoubleAcrossTile: "═",
+		HashiDownTile:         "│",
+		HashiDoubleDownTile:   "║",
+		EmptyTile:             " ",
+	},
+}
+
+// hashiCellWidth is the width an island or a stretch of water is drawn.
+const hashiCellWidth = 3
+
+// hashiDirections are the steps up, right, down and left, in the order of
+// an island's links.
+var hashiDirections = [4][2]int{{0, -1}, {1, 0}, {0, 1}, {-1, 0}}
+
+// hashiIsland reports whether a value is an island.
+func hashiIsland(v rune) bool {
+	return v >= '1' && v <= '8'
+}
+
+// hashiBridge reads a bridge value: how many bridges it holds and whether
+// they run down. ok is false for anything else.
+func hashiBridge(v rune) (n int, down bool, ok bool) {
+	switch v {
+	case HashiAcrossTile:
+		return 1, false, true
+	case HashiDoubleAcrossTile:
+		return 2, false, true
+	case HashiDownTile:
+		return 1, true, true
+	case HashiDoubleDownTile:
+		return 2, true, true
+	}
+	return 0, false, false
+}
+
+// hashiBridgeTile returns the value for n bridges running across or down.
+func hashiBridgeTile(n int, down bool) rune {
+	switch {
+	case n == 1 && down:
+		return HashiDownTile
+	case n == 2 && down:
+		return HashiDoubleDownTile
+	case n == 1:
+		return HashiAcrossTile
+	case n == 2:
+		return HashiDoubleAcrossTile
+	}
+	return EmptyTile
+}
+
+// hashiGrid reads a grid of islands and water, one line per row, checking
+// that every row is as wide as the first.
+func hashiGrid(s string) ([][]rune, error) {
+	var values [][]rune
+	for _, row := range strings.Split(strings.Trim(s, "\n"), "\n") {
+		values = append(values, []rune(row))
+	}
+	for y, row := range values {
+		if len(row) != len(values[0]) {
+			return nil, fmt.Errorf("row %d has %d cells, want %d", y+1, len(row), len(values[0]))
+		}
+	}
+	return values, nil
+}
+
+// hashiIslandAt is an island and the links to its neighbours up, right,
+// down and left, each an index into the links or -1.
+type hashiIslandAt struct {
+	x, y  int
+	need  int
+	links [4]int
+}
+
+// hashiLink is where bridges can run between two islands: the water cells
+// between them, in order from the first.
+type hashiLink struct {
+	a, b  int
+	down  bool
+	cells [][2]int
+}
+
+// hashiRules are the islands of a level and the links between them.
+type hashiRules struct {
+	width, height int
+	islands       []hashiIslandAt
+	at            map[[2]int]int
+	links         []hashiLink
+	// across and down index the link running each way through a water
+	// cell.
+	across, down map[[2]int]int
+	// given holds the bridges the initial state gives on each link, or -1.
+	given []int
+}
+
+// hashiRulesFor reads the islands of a level, and any bridges it gives,
+// from its initial state.
+func hashiRulesFor(l Level) (*hashiRules, error) {
+	initial, err := hashiGrid(l.Initial)
+	if err != nil {
+		return nil, fmt.Errorf("initial state: %w", err)
+	}
+	r := &hashiRules{
+		height: len(initial),
+		width:  len(initial[0]),
+		at:     make(map[[2]int]int),
+		across: make(map[[2]int]int),
+		down:   make(map[[2]int]int),
+	}
+	for y, row := range initial {
+		for x, v := range row {
+			_, _, bridge := hashiBridge(v)
+			switch {
+			case hashiIsland(v):
+				r.at[[2]int{x, y}] = len(r.islands)
+				r.islands = append(r.islands, hashiIslandAt{x: x, y: y, need: int(v - '0'), links: [4]int{-1, -1, -1, -1}})
+			case !bridge && v != '.' && v != ' ':
+				return nil, fmt.Errorf("cell at row %d column %d should be an island from 1 to 8, a bridge or ., got %q", y+1, x+1, v)
+			}
+		}
+	}
+	if len(r.islands) == 0 {
+		return nil, errors.New("no islands")
+	}
+
+	for i, island := range r.islands {
+		for _, d := range []int{1, 2} {
+			step := hashiDirections[d]
+			link := hashiLink{a: i, down: d == 2}
+			x, y := island.x+step[0], island.y+step[1]
+			for r.in(x, y) && !hashiIsland(initial[y][x]) {
+				link.cells = append(link.cells, [2]int{x, y})
+				x, y = x+step[0], y+step[1]
+			}
+			if !r.in(x, y) || len(link.cells) == 0 {
+				continue
+			}
+			link.b = r.at[[2]int{x, y}]
+			n := len(r.links)
+			r.islands[i].links[d] = n
+			r.islands[link.b].links[(d+2)%4] = n
+			for _, c := range link.cells {
+				if link.down {
+					r.down[c] = n
+				} else {
+					r.across[c] = n
+				}
+			}
+			r.links = append(r.links, link)
+		}
+	}
+
+	counts, err := r.bridges(initial)
+	if err != nil {
+		return nil, fmt.Errorf("initial state: %w", err)
+	}
+	r.given = make([]int, len(r.links))
+	for i := range r.links {
+		r.given[i] = -1
+		if counts[i] > 0 {
+			r.given[i] = counts[i]
+		}
+	}
+	return r, nil
+}
+
+// in reports whether a cell is on the grid.
+func (r *hashiRules) in(x, y int) bool {
+	return x >= 0 && y >= 0 && x < r.width && y < r.height
+}
+
+// bridges reads how many bridges run along each link, and returns an error
+// for a bridge that doesn't run the whole way between two islands. The
+// counts read so far come back with the error.
+func (r *hashiRules) bridges(values [][]rune) ([]int, error) {
+	counts := make([]int, len(r.links))
+	for y, row := range values {
+		for x, v := range row {
+			_, down, ok := hashiBridge(v)
+			if !ok {
+				continue
+			}
+			links := r.across
+			if down {
+				links = r.down
+			}
+			if _, ok := links[[2]int{x, y}]; !ok {
+				return counts, fmt.Errorf("bridge at row %d column %d doesn't run between two islands", y+1, x+1)
+			}
+		}
+	}
+	for i, link := range r.links {
+		v := EmptyTile
+		for _, c := range link.cells {
+			if n, down, ok := hashiBridge(values[c[1]][c[0]]); ok && down == link.down {
+				v, counts[i] = values[c[1]][c[0]], n
+			}
+		}
+		for _, c := range link.cells {
+			if counts[i] > 0 && values[c[1]][c[0]] != v {
+				return counts, fmt.Errorf("bridge at row %d column %d doesn't run the whole way between two islands", c[1]+1, c[0]+1)
+			}
+		}
+	}
+	return counts, nil
+}
+
+// blocked reports whether a bridge runs across a link the other way, so no
+// bridge can be built along it.
+func (r *hashiRules) blocked(values [][]rune, link int) bool {
+	for _, c := range r.links[link].cells {
+		if _, down, ok := hashiBridge(values[c[1]][c[0]]); ok && down != r.links[link].down {
+			return true
+		}
+	}
+	return false
+}
+
+// check reports how each island stands: done once it has its count of
+// bridges, and broken when it has too many or too few links left open to
+// reach it.
+func (r *hashiRules) check(values [][]rune, counts []int) []lineStatus {
+	status := make([]lineStatus, len(r.islands))
+	for i, island := range r.islands {
+		total, open := 0, 0
+		for _, link := range island.links {
+			switch {
+			case link < 0:
+			case counts[link] > 0:
+				total += counts[link]
+				open += 2 - counts[link]
+			case !r.blocked(values, link):
+				open += 2
+			}
+		}
+		switch {
+		case total > island.need || total+open < island.need:
+			status[i] = lineBroken
+		case total == island.need:
+			status[i] = lineSatisfied
+		}
+	}
+	return status
+}
+
+// groups counts the groups of islands the bridges join.
+func (r *hashiRules) groups(counts []int) int {
+	group := make([]int, len(r.islands))
+	for i := range group {
+		group[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if group[i] != i {
+			group[i] = find(group[i])
+		}
+		return group[i]
+	}
+	n := len(r.islands)
+	for i, link := range r.links {
+		if counts[i] == 0 {
+			continue
+		}
+		if a, b := find(link.a), find(link.b); a != b {
+			group[a] = b
+			n--
+		}
+	}
+	return n
+}
+
+// solved reports whether every island has its count of bridges and the
+// bridges join them all into one group.
+func (r *hashiRules) solved(values [][]rune) bool {
+	counts, err := r.bridges(values)
+	if err != nil {
+		return false
+	}
+	for _, s := range r.check(values, counts) {
+		if s != lineSatisfied {
+			return false
+		}
+	}
+	return r.groups(counts) == 1
+}
+
+// validateHashi refuses levels whose solution moves the islands, whose
+// bridges don't follow the rules, or whose given bridges don't match it.
+func validateHashi(l Level) (string, error) {
+	r, err := hashiRulesFor(l)
+	if err != nil {
+		return "", err
+	}
+	solution, err := hashiGrid(l.Solution)
+	if err != nil {
+		return "", fmt.Errorf("solution: %w", err)
+	}
+	if len(solution) != r.height || len(solution[0]) != r.width {
+		return "", errors.New("initial state and solution are different sizes")
+	}
+	initial, _ := hashiGrid(l.Initial)
+	for y, row := range solution {
+		for x, v := range row {
+			if (hashiIsland(v) || hashiIsland(initial[y][x])) && v != initial[y][x] {
+				return "", fmt.Errorf("solution doesn't match the island at row %d column %d", y+1, x+1)
+			}
+		}
+	}
+	counts, err := r.bridges(solution)
+	if err != nil {
+		return "", fmt.Errorf("solution: %w", err)
+	}
+	for i, n := range r.given {
+		if n >= 0 && n != counts[i] {
+			c := r.links[i].cells[0]
+			return "", fmt.Errorf("given bridge at row %d column %d doesn't match the solution", c[1]+1, c[0]+1)
+		}
+	}
+	if !r.solved(solution) {
+		return "", errors.New("solution doesn't give every island its bridges in one connected group")
+	}
+	return "", nil
+}
+
+type HashiEngine struct {
+	Engine
+	rules        *hashiRules
+	islandStatus []lineStatus
+	// selected is the island the next direction builds a bridge from, or
+	// -1.
+	selected int
+}
+
+func (e *HashiEngine) New(l Level, s *Save) (GameEngine, error) {
+	rules, err := hashiRulesFor(l)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := e.Engine.New(l, s); err != nil {
+		return nil, err
+	}
+	e.rules = rules
+	e.selected = -1
+	e.updateStatus()
+	return e, nil
+}
+
+// PrimaryAction selects an island to build a bridge from, or lets it go
+// again. With an island selected, pressing on an island it links to builds
+// a bridge between them instead. Pressing on a bridge adds another bridge
+// alongside it, or takes both away.
+func (e *HashiEngine) PrimaryAction(x, y int) error {
+	if !e.HasCell(x, y) {
+		return errors.New("coordinates out of bounds")
+	}
+	if i, ok := e.rules.at[[2]int{x, y}]; ok {
+		if e.selected >= 0 && e.selected != i {
+			for _, link := range e.rules.islands[e.selected].links {
+				if link >= 0 && (e.rules.links[link].a == i || e.rules.links[link].b == i) {
+					e.selected = -1
+					return e.cycle(link)
+				}
+			}
+		}
+		if e.selected == i {
+			e.selected = -1
+		} else {
+			e.selected = i
+		}
+		return nil
+	}
+	e.selected = -1
+	link, ok := e.bridgeAt(x, y)
+	if !ok {
+		return errors.New("no island or bridge here")
+	}
+	return e.cycle(link)
+}
+
+// SecondaryAction takes away a bridge, or every bridge of an island.
+func (e *HashiEngine) SecondaryAction(x, y int) error {
+	return e.ClearCell(x, y)
+}
+
+// ClearCell takes away the bridge over a cell, or every bridge of an
+// island, along the whole of their length.
+func (e *HashiEngine) ClearCell(x, y int) error {
+	if !e.HasCell(x, y) {
+		return errors.New("coordinates out of bounds")
+	}
+	e.selected = -1
+	var links []int
+	if i, ok := e.rules.at[[2]int{x, y}]; ok {
+		links = e.rules.islands[i].links[:]
+	} else if link, ok := e.bridgeAt(x, y); ok {
+		links = []int{link}
+	}
+	for _, link := range links {
+		if link < 0 || e.rules.given[link] >= 0 {
+			continue
+		}
+		e.build(link, 0)
+	}
+	e.updateSaveState()
+	e.updateStatus()
+	return nil
+}
+
+// Evaluate reports whether every island has its bridges and they join the
+// islands into one group, whether or not they are the level's solution.
+func (e *HashiEngine) Evaluate() (bool, error) {
+	return e.rules.solved(e.values()), nil
+}
+
+// MoveCursor builds a bridge the way the cursor is moved when an island is
+// selected, leaving the cursor where it is. Otherwise the cursor steps to
+// the next cell.
+func (e *HashiEngine) MoveCursor(x, y, dx, dy int) (int, int, bool) {
+	if i := e.selected; i >= 0 {
+		e.selected = -1
+		island := e.rules.islands[i]
+		if island.x == x && island.y == y {
+			for d, step := range hashiDirections {
+				if step == [2]int{dx, dy} && island.links[d] >= 0 {
+					e.cycle(island.links[d])
+				}
+			}
+			return x, y, false
+		}
+	}
+	if !e.HasCell(x+dx, y+dy) {
+		return x, y, false
+	}
+	return x + dx, y + dy, true
+}
+
+func (e *HashiEngine) View(m model) string {
+	return lipgloss.JoinVertical(lipgloss.Left, e.gridView(m), e.helpView(m))
+}
+
+// CellAt maps a position within the rendered view to grid coordinates.
+func (e *HashiEngine) CellAt(col, row int) (int, int, bool) {
+	if col < 0 || row < 0 {
+		return 0, 0, false
+	}
+	x, y := col/hashiCellWidth, row
+	if !e.HasCell(x, y) {
+		return 0, 0, false
+	}
+	return x, y, true
+}
+
+// GlyphSets returns the glyphs bridges are drawn with.
+func (e *HashiEngine) GlyphSets() map[string]GlyphSet {
+	return hashiGlyphs
+}
+
+// --- Private Functions ---
+
+// bridgeAt returns the link whose bridge runs over a water cell.
+func (e *HashiEngine) bridgeAt(x, y int) (int, bool) {
+	_, down, ok := hashiBridge(e.Grid[y][x].value)
+	if !ok {
+		return 0, false
+	}
+	links := e.rules.across
+	if down {
+		links = e.rules.down
+	}
+	link, ok := links[[2]int{x, y}]
+	return link, ok
+}
+
+// cycle builds another bridge along a link, going from none to one to two
+// and back to none.
+func (e *HashiEngine) cycle(link int) error {
+	if e.rules.given[link] >= 0 {
+		return errors.New("bridge is given")
+	}
+	values := e.values()
+	counts, _ := e.rules.bridges(values)
+	n := (counts[link] + 1) % 3
+	if n > 0 && e.rules.blocked(values, link) {
+		return errors.New("bridge would cross another")
+	}
+	e.build(link, n)
+	e.updateSaveState()
+	e.updateStatus()
+	return nil
+}
+
+// build puts n bridges along a link, without saving.
+func (e *HashiEngine) build(link, n int) {
+	l := e.rules.links[link]
+	for _, c := range l.cells {
+		if n == 0 {
+			e.Grid[c[1]][c[0]].Clear()
+		} else {
+			e.Grid[c[1]][c[0]].EnterValue(hashiBridgeTile(n, l.down))
+		}
+	}
+}
+
+// updateStatus checks the islands and refreshes the solved flag on the
+// save.
+func (e *HashiEngine) updateStatus() {
+	values := e.values()
+	counts, _ := e.rules.bridges(values)
+	e.islandStatus = e.rules.check(values, counts)
+	e.Save.Solved = e.rules.solved(values)
+}
+
+// islandView draws an island with any bridges across reaching out to its
+// sides, or in brackets when it is selected.
+func (e *HashiEngine) islandView(i int, m model) string {
+	glyphs := glyphSetFor(hashiGlyphs)
+	island := e.rules.islands[i]
+	side := func(d int) string {
+		if link := island.links[d]; link >= 0 {
+			c := e.rules.links[link].cells[0]
+			if n, down, ok := hashiBridge(e.Grid[c[1]][c[0]].value); ok && !down && n > 0 {
+				return focusedStyle.Render(glyphs[e.Grid[c[1]][c[0]].value])
+			}
+		}
+		return " "
+	}
+	left, right := side(3), side(1)
+	if i == e.selected {
+		left, right = "[", "]"
+	}
+	s := hintLineStyle(e.islandStatus[i]).Bold(true)
+	if island.x == m.cursorX && island.y == m.cursorY {
+		s = highlightStyle
+	}
+	return left + s.Render(fmt.Sprint(island.need)) + right
+}
+
+// waterView draws a stretch of water and any bridge over it.
+func (e *HashiEngine) waterView(x, y int, m model) string {
+	glyphs := glyphSetFor(hashiGlyphs)
+	v := e.Grid[y][x].value
+	g := glyphs[EmptyTile]
+	s := focusedStyle
+	if _, down, ok := hashiBridge(v); ok {
+		g = glyphs[v]
+		if !down {
+			g = strings.Repeat(g, hashiCellWidth)
+		}
+		if link, ok := e.bridgeAt(x, y); ok && e.rules.given[link] >= 0 {
+			s = digitGivenStyle
+		}
+	}
+	if x == m.cursorX && y == m.cursorY {
+		s = highlightStyle
+	}
+	return s.Width(hashiCellWidth).AlignHorizontal(lipgloss.Center).Render(g)
+}
+
+func (e *HashiEngine) gridView(m model) string {
+	var rows []string
+	for y := 0; y < e.GetHeight(); y++ {
+		var cells []string
+		for x := 0; x < e.GetWidth(); x++ {
+			if i, ok := e.rules.at[[2]int{x, y}]; ok {
+				cells = append(cells, e.islandView(i, m))
+			} else {
+				cells = append(cells, e.waterView(x, y, m))
+			}
+		}
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, cells...))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, rows...)
+}
+
+func (e *HashiEngine) helpView(m model) string {
+	help := "\n"
+	_, island := e.rules.at[[2]int{m.cursorX, m.cursorY}]
+	_, bridge := e.bridgeAt(m.cursorX, m.cursorY)
+	switch {
+	case island && e.selected >= 0:
+		help += "arrow keys: Bridge\tz: Cancel\n"
+	case island:
+		help += "z: Select\tx: Remove its bridges\n"
+	case bridge:
+		help += "z: Double or remove\tx: Remove\n"
+	default:
+		help += "\n"
+	}
+	help += e.statusView()
+	help += "arrow keys or hjkl to move\n"
+	help += "Press 'esc' to return to the menu.\n"
+	if e.Save.Solved {
+		help += "Congrats!\n"
+	}
+	return help
+}
+
+// statusView counts the islands with their bridges and the groups the
+// bridges join them into.
+func (e *HashiEngine) statusView() string {
+	done := 0
+	for _, s := range e.islandStatus {
+		if s == lineSatisfied {
+			done++
+		}
+	}
+	counts, _ := e.rules.bridges(e.values())
+	return fmt.Sprintf("Islands: %d/%d\tGroups: %d\n", done, len(e.rules.islands), e.rules.groups(counts))
+}
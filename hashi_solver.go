@@ -0,0 +1,112 @@
+package main
+
+// hashiSearchBudget caps how many bridge counts countHashiSolutions tries
+// before giving up on a level.
+const hashiSearchBudget = 2000000
+
+// hashiSolver decides how many bridges run along each link in turn, backing
+// off as soon as an island has too many bridges or too few links left to
+// reach its count.
+type hashiSolver struct {
+	rules  *hashiRules
+	counts []int
+	totals []int
+	// crossing holds the links that cross each link.
+	crossing [][]int
+	budget   int
+}
+
+// countHashiSolutions counts the ways to build a level's bridges, up to
+// limit, keeping the bridges its initial state gives.
+func countHashiSolutions(l Level, limit int) (int, bool) {
+	rules, err := hashiRulesFor(l)
+	if err != nil {
+		return 0, true
+	}
+	s := &hashiSolver{
+		rules:    rules,
+		counts:   make([]int, len(rules.links)),
+		totals:   make([]int, len(rules.islands)),
+		crossing: make([][]int, len(rules.links)),
+		budget:   hashiSearchBudget,
+	}
+	for i, link := range rules.links {
+		for _, c := range link.cells {
+			if j, ok := rules.down[c]; ok && !link.down {
+				s.crossing[i] = append(s.crossing[i], j)
+				s.crossing[j] = append(s.crossing[j], i)
+			}
+		}
+	}
+	count := s.search(0, limit)
+	return count, s.budget > 0
+}
+
+// search counts the ways to decide the links from the i'th on, up to limit.
+func (s *hashiSolver) search(i, limit int) int {
+	if i == len(s.counts) {
+		for j, island := range s.rules.islands {
+			if s.totals[j] != island.need {
+				return 0
+			}
+		}
+		if s.rules.groups(s.counts) != 1 {
+			return 0
+		}
+		return 1
+	}
+
+	choices := []int{0, 1, 2}
+	if n := s.rules.given[i]; n >= 0 {
+		choices = []int{n}
+	}
+	link := s.rules.links[i]
+	count := 0
+	for _, n := range choices {
+		if n > 0 && s.crossed(i) {
+			break
+		}
+		if s.budget--; s.budget <= 0 {
+			break
+		}
+		s.counts[i] = n
+		s.totals[link.a] += n
+		s.totals[link.b] += n
+		if s.fits(link.a, i) && s.fits(link.b, i) {
+			count += s.search(i+1, limit-count)
+		}
+		s.totals[link.a] -= n
+		s.totals[link.b] -= n
+		s.counts[i] = 0
+		if count >= limit {
+			break
+		}
+	}
+	return count
+}
+
+// crossed reports whether a link crosses one already holding a bridge.
+func (s *hashiSolver) crossed(i int) bool {
+	for _, j := range s.crossing[i] {
+		if j < i && s.counts[j] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// fits reports whether an island can still reach its count once the links
+// up to the i'th are decided.
+func (s *hashiSolver) fits(island, i int) bool {
+	need := s.rules.islands[island].need
+	if s.totals[island] > need {
+		return false
+	}
+	open := 0
+	for _, link := range s.rules.islands[island].links {
+		if link > i && !s.crossed(link) {
+			open += 2
+		}
+	}
+	return s.totals[island]+open >= need
+}
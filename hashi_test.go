@@ -0,0 +1,207 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	testHashiInitial  = "3.4.2.\n......\n......\n......\n1...1.\n1.3..1"
+	testHashiSolution = "3=4-2.\n|.|.|.\n|.|.|.\n|.|.|.\n1.|.1.\n1-3--1"
+)
+
+var testHashiLevel = Level{
+	Engine:   "hashi",
+	Initial:  testHashiInitial,
+	Solution: testHashiSolution,
+}
+
+// buildBridges builds the bridges of a grid by selecting each island and
+// moving right or down from it.
+func buildBridges(t *testing.T, e *HashiEngine, grid string) {
+	t.Helper()
+	rows := strings.Split(grid, "\n")
+	for _, island := range e.rules.islands {
+		for _, d := range []int{1, 2} {
+			step := hashiDirections[d]
+			x, y := island.x+step[0], island.y+step[1]
+			if y >= len(rows) || x >= len(rows[y]) {
+				continue
+			}
+			n, down, ok := hashiBridge(rune(rows[y][x]))
+			if !ok || down != (d == 2) {
+				continue
+			}
+			for ; n > 0; n-- {
+				if err := e.PrimaryAction(island.x, island.y); err != nil {
+					t.Fatalf("failed to select the island at %d,%d: %v", island.x, island.y, err)
+				}
+				e.MoveCursor(island.x, island.y, step[0], step[1])
+			}
+		}
+	}
+}
+
+func TestHashiRulesErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		initial string
+		want    string
+	}{
+		{name: "bad cell", initial: "1.x", want: "island from 1 to 8"},
+		{name: "no islands", initial: "...\n...", want: "no islands"},
+		{name: "ragged", initial: "1.1\n..", want: "row 2 has 2 cells"},
+		{name: "stray bridge", initial: "1.1\n-..", want: "doesn't run between two islands"},
+		{name: "short bridge", initial: "1-.1", want: "the whole way"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := hashiRulesFor(Level{Initial: tc.initial})
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected an error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestHashiPlay(t *testing.T) {
+	e := newTestEngine(t, testHashiLevel).(*HashiEngine)
+	if err := e.PrimaryAction(1, 1); err == nil {
+		t.Error("expected pressing on open water to be refused")
+	}
+
+	// Selecting an island and moving right builds a bridge, then a second.
+	e.PrimaryAction(0, 0)
+	if x, y, moved := e.MoveCursor(0, 0, 1, 0); moved || x != 0 || y != 0 {
+		t.Errorf("expected the cursor to stay on the island, got %d,%d %v", x, y, moved)
+	}
+	if v := e.Grid[0][1].value; v != HashiAcrossTile {
+		t.Errorf("expected a bridge across, got %q", v)
+	}
+	e.PrimaryAction(0, 0)
+	e.MoveCursor(0, 0, 1, 0)
+	if v := e.Grid[0][1].value; v != HashiDoubleAcrossTile {
+		t.Errorf("expected a double bridge, got %q", v)
+	}
+	if _, _, moved := e.MoveCursor(0, 0, 1, 0); !moved {
+		t.Error("expected the cursor to move once no island is selected")
+	}
+
+	// Pressing on the bridge cycles it back to none.
+	e.PrimaryAction(1, 0)
+	if v := e.Grid[0][1].value; v != EmptyTile {
+		t.Errorf("expected the bridge to be taken away, got %q", v)
+	}
+
+	// With an island selected, pressing on the island it links to joins
+	// them.
+	e.PrimaryAction(0, 4)
+	e.PrimaryAction(4, 4)
+	for x := 1; x < 4; x++ {
+		if v := e.Grid[4][x].value; v != HashiAcrossTile {
+			t.Fatalf("expected a bridge across row 4 at column %d, got %q", x, v)
+		}
+	}
+	if err := e.PrimaryAction(2, 0); err != nil {
+		t.Fatal(err)
+	}
+	e.MoveCursor(2, 0, 0, 1)
+	if v := e.Grid[1][2].value; v != EmptyTile {
+		t.Errorf("expected a bridge crossing another to be refused, got %q", v)
+	}
+	e.SecondaryAction(2, 4)
+	if v := e.Grid[4][2].value; v != EmptyTile {
+		t.Errorf("expected the bridge to be taken away along its length, got %q", v)
+	}
+
+	buildBridges(t, e, testHashiSolution)
+	if !e.Save.Solved || e.Save.State != strings.ReplaceAll(testHashiSolution, ".", " ") {
+		t.Fatalf("expected the bridges to solve the level, got %q", e.Save.State)
+	}
+	if solved, _ := e.Evaluate(); !solved {
+		t.Error("expected Evaluate to find the islands joined")
+	}
+
+	// Taking away every bridge of an island unsolves the level.
+	e.SecondaryAction(2, 0)
+	if e.Save.Solved || e.Grid[0][1].value != EmptyTile || e.Grid[3][2].value != EmptyTile {
+		t.Errorf("expected the island's bridges to be taken away, got %q", e.Save.State)
+	}
+}
+
+func TestHashiStatus(t *testing.T) {
+	e := newTestEngine(t, testHashiLevel).(*HashiEngine)
+	if got := e.statusView(); got != "Islands: 0/8\tGroups: 8\n" {
+		t.Errorf("expected no islands done, got %q", got)
+	}
+	e.PrimaryAction(0, 5)
+	e.PrimaryAction(2, 5)
+	if e.islandStatus[5] != lineSatisfied {
+		t.Errorf("expected the 1 to be done, got %d", e.islandStatus[5])
+	}
+	if got := e.statusView(); got != "Islands: 1/8\tGroups: 7\n" {
+		t.Errorf("expected one island done in 7 groups, got %q", got)
+	}
+	e.PrimaryAction(1, 5)
+	if e.islandStatus[5] != lineBroken {
+		t.Errorf("expected a double bridge to break the 1, got %d", e.islandStatus[5])
+	}
+
+	// Two islands joined only to each other don't solve a level, even with
+	// their counts met.
+	e = newTestEngine(t, Level{Engine: "hashi", Initial: "1.1\n...\n1.1", Solution: testHashiSolution}).(*HashiEngine)
+	e.PrimaryAction(0, 0)
+	e.MoveCursor(0, 0, 1, 0)
+	e.PrimaryAction(0, 2)
+	e.MoveCursor(0, 2, 1, 0)
+	if e.Save.Solved || !strings.Contains(e.statusView(), "Groups: 2") {
+		t.Errorf("expected two groups not to solve the level, got %q", e.Save.State)
+	}
+}
+
+func TestHashiView(t *testing.T) {
+	e := newTestEngine(t, testHashiLevel).(*HashiEngine)
+	buildBridges(t, e, testHashiSolution)
+	lines := strings.Split(e.gridView(model{cursorX: 3, cursorY: 3}), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("expected 6 lines, got %d", len(lines))
+	}
+	if !strings.Contains(lines[0], "3═════4─────2") {
+		t.Errorf("expected the bridges to reach the islands, got\n%s", strings.Join(lines, "\n"))
+	}
+	if !strings.Contains(lines[2], "│") {
+		t.Errorf("expected bridges down, got\n%s", strings.Join(lines, "\n"))
+	}
+
+	e.PrimaryAction(2, 0)
+	if !strings.Contains(e.gridView(model{}), "[4]") {
+		t.Error("expected the selected island in brackets")
+	}
+	if x, y, ok := e.CellAt(7, 0); !ok || x != 2 || y != 0 {
+		t.Errorf("expected column 7 row 0 to be the 4, got %d,%d %v", x, y, ok)
+	}
+}
+
+func TestCountHashiSolutions(t *testing.T) {
+	testCases := []struct {
+		name    string
+		initial string
+		want    int
+	}{
+		{name: "unique", initial: testHashiInitial, want: 1},
+		{name: "two ways round", initial: "3.3\n...\n3.3", want: 2},
+		{name: "given bridge", initial: "3-3\n...\n3.3", want: 1},
+		{name: "impossible", initial: "1.2", want: 0},
+		{name: "split in two", initial: "2.3\n...\n3.2\n...\n2.2", want: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := countHashiSolutions(Level{Initial: tc.initial}, 2)
+			if !ok || got != tc.want {
+				t.Errorf("expected %d solutions, got %d (ok %v)", tc.want, got, ok)
+			}
+		})
+	}
+}
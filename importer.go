@@ -1,33 +1,173 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
+	"syscall"
 
 	"gopkg.in/yaml.v3"
 )
 
+// LevelPackYAML is the file format level packs are shared in, as YAML or
+// JSON.
 type LevelPackYAML struct {
-	Name        string  `yaml:"name"`
-	Author      string  `yaml:"author"`
-	Version     int     `yaml:"version"`
-	Description string  `yaml:"description"`
-	Levels      []Level `yaml:"levels"`
+	Name        string  `yaml:"name" json:"name" required:"true" doc:"Unique name of the pack. Importing a pack with the same name updates it."`
+	Author      string  `yaml:"author" json:"author" doc:"Who made the pack."`
+	Version     int     `yaml:"version" json:"version" doc:"Version of the pack, bumped when it changes."`
+	Description string  `yaml:"description" json:"description" doc:"A short description shown in the browser."`
+	Levels      []Level `yaml:"levels" json:"levels" required:"true" doc:"The levels in the pack."`
+	Hash        string  `yaml:"hash,omitempty" json:"hash,omitempty" doc:"SHA-256 of the pack content, set by chronical sign."`
+	Signature   string  `yaml:"signature,omitempty" json:"signature,omitempty" doc:"Base64 ed25519 signature of the hash, set by chronical sign."`
+	PublicKey   string  `yaml:"public_key,omitempty" json:"public_key,omitempty" doc:"Base64 ed25519 public key the pack was signed with."`
 }
 
+const (
+	formatYAML = "yaml"
+	formatJSON = "json"
+	// formatSokoban is a collection of sokoban levels in XSB notation, as
+	// .sok and .txt files are shared. It can be imported but not exported.
+	formatSokoban = "sokoban"
+)
+
+// ExportOptions controls how a level pack is written to a file.
+type ExportOptions struct {
+	// Format is yaml or json. When empty it is taken from the file
+	// extension, defaulting to yaml.
+	Format string
+	// Force allows an existing file to be overwritten.
+	Force bool
+}
+
+// formatFromPath picks an export format from a file extension.
+func formatFromPath(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return formatJSON
+	}
+	return formatYAML
+}
+
+// safeFileName turns a pack name into a file name that can't escape the
+// directory it is written to or trip up common file systems.
+func safeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '-'
+		}
+		return r
+	}, name)
+	name = strings.Trim(name, ". ")
+	if name == "" {
+		return "levelpack"
+	}
+	return name
+}
+
+// writeFileAtomic writes data to a temporary file next to path and moves
+// it into place, so readers never see a half written file. Without force an
+// existing file is left alone and an error returned: the temporary file is
+// linked to path rather than renamed over it, so a file created meanwhile is
+// never replaced.
+func writeFileAtomic(path string, data []byte, force bool) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	if force {
+		return os.Rename(tmp.Name(), path)
+	}
+
+	err = os.Link(tmp.Name(), path)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%s already exists: %w", path, fs.ErrExist)
+	}
+	if errors.Is(err, errors.ErrUnsupported) || errors.Is(err, syscall.EPERM) {
+		// Some file systems have no hard links. Creating the file
+		// exclusively still never replaces one, though a reader could see
+		// it half written.
+		return writeFileExclusive(path, data)
+	}
+	return err
+}
+
+// writeFileExclusive writes data to path, which must not exist yet.
+func writeFileExclusive(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%s already exists: %w", path, fs.ErrExist)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// ExportLevelPack writes a level pack to path, in the format its extension
+// names, replacing any file already there.
 func (s *Store) ExportLevelPack(levelPackID int, path string) error {
-	levelPack, err := s.GetLevelPack(levelPackID)
+	return s.ExportLevelPackFile(levelPackID, path, ExportOptions{Force: true})
+}
+
+// ExportLevelPackFile writes a level pack to path atomically.
+func (s *Store) ExportLevelPackFile(levelPackID int, path string, opts ExportOptions) error {
+	format := opts.Format
+	if format == "" {
+		format = formatFromPath(path)
+	}
+	data, err := s.EncodeLevelPack(levelPackID, format)
 	if err != nil {
 		return err
 	}
+	if err := writeFileAtomic(path, data, opts.Force); err != nil {
+		return err
+	}
+	log.Printf("event=\"exported_level_pack\" level_pack_id=%d path=\"%s\" format=%s", levelPackID, path, format)
+	return nil
+}
+
+// EncodeLevelPack renders a level pack in the given format.
+func (s *Store) EncodeLevelPack(levelPackID int, format string) ([]byte, error) {
+	levelPack, err := s.GetLevelPack(levelPackID)
+	if err != nil {
+		return nil, err
+	}
 
 	levels, err := s.GetLevelsByPack(levelPackID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	for i := range levels {
+		if engines[levels[i].Engine].KeepDots {
+			continue
+		}
 		levels[i].Initial = strings.ReplaceAll(levels[i].Initial, " ", ".")
 		levels[i].Solution = strings.ReplaceAll(levels[i].Solution, " ", ".")
 	}
@@ -39,24 +179,126 @@ func (s *Store) ExportLevelPack(levelPackID int, path string) error {
 		Description: levelPack.Description,
 		Levels:      levels,
 	}
+	return encodeLevelPackYAML(&levelPackYAML, format)
+}
 
-	data, err := yaml.Marshal(&levelPackYAML)
-	if err != nil {
-		return err
+// encodeLevelPackYAML renders a level pack file in the given format.
+func encodeLevelPackYAML(levelPackYAML *LevelPackYAML, format string) ([]byte, error) {
+	switch format {
+	case formatYAML:
+		// yaml.v3 only gets the indentation indicator of a block starting
+		// with spaces, such as a sokoban board, right when lists are
+		// indented as far as the keys they hold.
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(levelPackYAML); err != nil {
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case formatJSON:
+		data, err := json.MarshalIndent(levelPackYAML, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
 	}
+}
 
-	return os.WriteFile(path, data, 0644)
+// detectFormat works out whether a level pack file is YAML, JSON or a
+// sokoban collection, first from its extension and then from its content.
+// JSON is a subset of YAML, so anything that doesn't look like a JSON object
+// is read as YAML.
+func detectFormat(path string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return formatJSON
+	case ".yaml", ".yml":
+		return formatYAML
+	case ".sok", ".txt":
+		return formatSokoban
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return formatJSON
+	}
+	return formatYAML
 }
 
+// DecodeLevelPack parses a level pack in the given format.
+func DecodeLevelPack(data []byte, format string) (*LevelPackYAML, error) {
+	var levelPackYAML LevelPackYAML
+	switch format {
+	case formatYAML:
+		if err := yaml.Unmarshal(data, &levelPackYAML); err != nil {
+			return nil, err
+		}
+	case formatJSON:
+		if err := json.Unmarshal(data, &levelPackYAML); err != nil {
+			return nil, err
+		}
+	case formatSokoban:
+		return decodeSokobanCollection(data)
+	default:
+		return nil, fmt.Errorf("unknown level pack format %q", format)
+	}
+	return &levelPackYAML, nil
+}
+
+// DecodeLevelPackFile parses a level pack file, in the format detectFormat
+// picks for it. A sokoban collection without a title is named after the
+// file.
+func DecodeLevelPackFile(p string, data []byte) (*LevelPackYAML, error) {
+	format := detectFormat(p, data)
+	levelPackYAML, err := DecodeLevelPack(data, format)
+	if err != nil {
+		return nil, err
+	}
+	if format == formatSokoban && levelPackYAML.Name == "" {
+		levelPackYAML.Name = strings.TrimSuffix(filepath.Base(p), filepath.Ext(p))
+	}
+	return levelPackYAML, nil
+}
+
+// ImportLevelPack reads a YAML or JSON level pack file, or a sokoban
+// collection, into the store.
 func (s *Store) ImportLevelPack(path string) error {
+	_, err := s.ImportLevelPackFile(path)
+	return err
+}
+
+// ImportLevelPackFile reads a YAML or JSON level pack file, or a sokoban
+// collection, into the store and returns the imported pack.
+func (s *Store) ImportLevelPackFile(path string) (*LevelPack, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	var levelPackYAML LevelPackYAML
-	if err := yaml.Unmarshal(data, &levelPackYAML); err != nil {
+	levelPackYAML, err := DecodeLevelPackFile(path, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var pack *LevelPack
+	err = s.InTx(func(tx *Store) error {
+		pack, err = tx.importPack(levelPackYAML)
 		return err
+	})
+	return pack, err
+}
+
+// importPack adds a decoded level pack to the store, updating the pack and
+// levels that share its name, once its signature passes the trust policy.
+func (s *Store) importPack(levelPackYAML *LevelPackYAML) (*LevelPack, error) {
+	verification := VerifyLevelPack(levelPackYAML, activeTrust)
+	log.Printf("event=\"verified_level_pack\" name=\"%s\" integrity=\"%s\"", levelPackYAML.Name, verification)
+	if err := activeTrust.allow(verification); err != nil {
+		return nil, fmt.Errorf("%s: %w", levelPackYAML.Name, err)
 	}
 
 	levelPack := &LevelPack{
@@ -64,27 +306,56 @@ func (s *Store) ImportLevelPack(path string) error {
 		Author:      levelPackYAML.Author,
 		Version:     levelPackYAML.Version,
 		Description: levelPackYAML.Description,
+		Integrity:   verification.Status,
+		Signer:      verification.SignerName(),
 	}
 
 	if err := s.UpsertLevelPack(levelPack); err != nil {
-		return err
+		return nil, err
 	}
 
 	for _, level := range levelPackYAML.Levels {
-		level.Initial = strings.ReplaceAll(level.Initial, ".", " ")
-		level.Solution = strings.ReplaceAll(level.Solution, ".", " ")
+		if !engines[level.Engine].KeepDots {
+			level.Initial = strings.ReplaceAll(level.Initial, ".", " ")
+			level.Solution = strings.ReplaceAll(level.Solution, ".", " ")
+		}
 		level.SetDimensions()
+		note, err := validateLevel(level)
+		if err != nil {
+			return nil, fmt.Errorf("%s: level %s: %w", levelPackYAML.Name, level.Name, err)
+		}
+		if note != "" {
+			log.Printf("event=\"validated_level\" level=\"%s\" note=\"%s\"", level.Name, note)
+		}
+		if err := s.renameLevelByIdentity(levelPack.ID, level.LevelIdentity(), level.Name); err != nil {
+			return nil, err
+		}
 		if err := s.UpsertLevel(&level, levelPack.ID); err != nil {
-			return err
+			return nil, err
 		}
 	}
+	// A level without a uuid changes identity when its grids do, leaving
+	// the save for its old grids behind.
+	if err := s.deleteOrphanSaves(); err != nil {
+		return nil, err
+	}
+
+	duplicates, err := s.DuplicateLevels(levelPack.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range duplicates {
+		log.Printf("event=\"duplicate_level\" level=\"%s\" other_pack=\"%s\" other_level=\"%s\"", d.Level, d.OtherPack, d.OtherLevel)
+	}
 
 	log.Println("Successfully imported level pack:")
 	log.Printf("  Name: %s\n", levelPack.Name)
 	log.Printf("  Author: %s\n", levelPack.Author)
 	log.Printf("  Version: %d\n", levelPack.Version)
 	log.Printf("  Description: %s\n", levelPack.Description)
+	log.Printf("  Integrity: %s\n", verification)
 	log.Printf("  Levels: %d\n", len(levelPackYAML.Levels))
+	log.Printf("  Duplicates: %d\n", len(duplicates))
 
-	return nil
+	return levelPack, nil
 }
@@ -2,8 +2,12 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -202,3 +206,164 @@ levels:
 		}
 	})
 }
+
+func TestSafeFileName(t *testing.T) {
+	testCases := []struct {
+		name string
+		want string
+	}{
+		{name: "Nonogram Easy (5x5)", want: "Nonogram Easy (5x5)"},
+		{name: "../../etc/passwd", want: "-..-etc-passwd"},
+		{name: `a\b:c*d?e"f<g>h|i`, want: "a-b-c-d-e-f-g-h-i"},
+		{name: "tab\there", want: "tab-here"},
+		{name: " .. ", want: "levelpack"},
+		{name: "", want: "levelpack"},
+	}
+
+	for _, tc := range testCases {
+		if got := safeFileName(tc.name); got != tc.want {
+			t.Errorf("safeFileName(%q) = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestExportLevelPackFile(t *testing.T) {
+	store := newTestStore(t)
+	pack := &LevelPack{Name: "Export Pack", Author: "Tester", Version: 3}
+	if err := store.UpsertLevelPack(pack); err != nil {
+		t.Fatalf("failed to insert level pack: %v", err)
+	}
+	level := &Level{Name: "Level 1", Author: "Tester", Initial: "  \n  ", Solution: "1 \n 1", Engine: "nonogram"}
+	if err := store.UpsertLevel(level, pack.ID); err != nil {
+		t.Fatalf("failed to insert level: %v", err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pack.json")
+	if err := store.ExportLevelPackFile(pack.ID, path, ExportOptions{}); err != nil {
+		t.Fatalf("failed to export level pack: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read export: %v", err)
+	}
+	var exported LevelPackYAML
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatalf("expected a JSON export from the .json extension: %v", err)
+	}
+	if exported.Name != "Export Pack" || exported.Version != 3 || len(exported.Levels) != 1 {
+		t.Errorf("unexpected export %+v", exported)
+	}
+	if exported.Levels[0].Solution != "1.\n.1" {
+		t.Errorf("expected blanks exported as dots, got %q", exported.Levels[0].Solution)
+	}
+
+	err = store.ExportLevelPackFile(pack.ID, path, ExportOptions{Format: formatYAML})
+	if !errors.Is(err, fs.ErrExist) {
+		t.Errorf("expected an existing file to be refused without force, got %v", err)
+	}
+	if err := store.ExportLevelPackFile(pack.ID, path, ExportOptions{Format: formatYAML, Force: true}); err != nil {
+		t.Fatalf("failed to overwrite with force: %v", err)
+	}
+	data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read export: %v", err)
+	}
+	if err := yaml.Unmarshal(data, &exported); err != nil || exported.Name != "Export Pack" {
+		t.Errorf("expected the forced export to be YAML, got %q", data)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected no temp files left behind, got %d entries", len(entries))
+	}
+}
+
+func TestWriteFileAtomicKeepsExisting(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pack.yaml")
+	if err := writeFileAtomic(path, []byte("first"), false); err != nil {
+		t.Fatalf("failed to write a new file: %v", err)
+	}
+
+	for name, write := range map[string]func() error{
+		"link":      func() error { return writeFileAtomic(path, []byte("second"), false) },
+		"exclusive": func() error { return writeFileExclusive(path, []byte("second")) },
+	} {
+		if err := write(); !errors.Is(err, fs.ErrExist) {
+			t.Errorf("%s: expected an existing file to be refused, got %v", name, err)
+		}
+	}
+	if data, _ := os.ReadFile(path); string(data) != "first" {
+		t.Errorf("expected the existing file to be left alone, got %q", data)
+	}
+
+	if err := writeFileAtomic(path, []byte("third"), true); err != nil {
+		t.Fatalf("failed to overwrite with force: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "third" {
+		t.Errorf("expected force to replace the file, got %q", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected no temp files left behind, got %d entries", len(entries))
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	testCases := []struct {
+		path string
+		data string
+		want string
+	}{
+		{path: "pack.json", data: "name: yaml anyway", want: formatJSON},
+		{path: "pack.YAML", data: `{"name": "json anyway"}`, want: formatYAML},
+		{path: "pack.yml", data: "", want: formatYAML},
+		{path: "pack", data: "\n  {\"name\": \"Pack\"}", want: formatJSON},
+		{path: "pack", data: "name: Pack", want: formatYAML},
+	}
+
+	for _, tc := range testCases {
+		if got := detectFormat(tc.path, []byte(tc.data)); got != tc.want {
+			t.Errorf("detectFormat(%q, %q) = %q, want %q", tc.path, tc.data, got, tc.want)
+		}
+	}
+}
+
+func TestImportJSONLevelPack(t *testing.T) {
+	store := newTestStore(t)
+	path := filepath.Join(t.TempDir(), "pack")
+	data := `{
+  "name": "JSON Pack",
+  "author": "Tester",
+  "version": 2,
+  "levels": [
+    {"name": "Level 1", "engine": "nonogram", "initial": "..\n..", "solution": "1.\n.1"}
+  ]
+}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("failed to write pack: %v", err)
+	}
+
+	if err := store.ImportLevelPack(path); err != nil {
+		t.Fatalf("failed to import JSON level pack: %v", err)
+	}
+
+	pack, err := store.FindLevelPack("JSON Pack")
+	if err != nil {
+		t.Fatalf("failed to find imported pack: %v", err)
+	}
+	if pack.Version != 2 {
+		t.Errorf("expected version 2, got %d", pack.Version)
+	}
+	levels, err := store.GetLevelsByPack(pack.ID)
+	if err != nil {
+		t.Fatalf("failed to get levels: %v", err)
+	}
+	if len(levels) != 1 || levels[0].Solution != "1 \n 1" {
+		t.Errorf("unexpected levels %+v", levels)
+	}
+}
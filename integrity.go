@@ -0,0 +1,232 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The integrity of an imported pack, as recorded in the store.
+const (
+	integrityUnsigned = "unsigned"
+	integrityVerified = "verified"
+	integrityTampered = "tampered"
+)
+
+// Trust policies decide which packs may be imported. Tampered packs are
+// always refused.
+const (
+	trustAny     = "any"
+	trustSigned  = "signed"
+	trustTrusted = "trusted"
+)
+
+// TrustConfig is the pack signing policy from the config file.
+type TrustConfig struct {
+	// Policy is any, signed or trusted. Empty means any.
+	Policy string `yaml:"policy"`
+	// Keys maps author names to the base64 ed25519 public keys they sign
+	// packs with.
+	Keys map[string]string `yaml:"keys"`
+}
+
+// activeTrust is the trust policy imports are checked against.
+var activeTrust TrustConfig
+
+// Verification is the result of checking a pack's hash and signature.
+type Verification struct {
+	Status string
+	// Signer is the base64 public key that signed the pack.
+	Signer string
+	// Trusted is the name the signer has in the trust config, if any.
+	Trusted string
+	// Reason explains why a pack is tampered.
+	Reason string
+}
+
+func (v Verification) String() string {
+	switch {
+	case v.Status == integrityTampered:
+		return "tampered: " + v.Reason
+	case v.Status == integrityVerified && v.Trusted != "":
+		return "verified, signed by " + v.Trusted
+	case v.Status == integrityVerified:
+		return "verified, signed by untrusted key " + v.Signer
+	}
+	return v.Status
+}
+
+// SignerName is how the signer is recorded in the store: their trusted name,
+// or their key.
+func (v Verification) SignerName() string {
+	if v.Trusted != "" {
+		return v.Trusted
+	}
+	return v.Signer
+}
+
+// packIntegrity describes the integrity an imported pack was recorded with.
+func packIntegrity(pack LevelPack) string {
+	if pack.Integrity == integrityVerified {
+		return "verified, signed by " + pack.Signer
+	}
+	return pack.Integrity
+}
+
+// packHash returns the content hash of a pack: the SHA-256 of its JSON
+// encoding without the hash and signature fields. Hashing the decoded pack
+// rather than the file means YAML and JSON copies hash the same.
+func packHash(p *LevelPackYAML) (string, error) {
+	c := *p
+	c.Hash, c.Signature, c.PublicKey = "", "", ""
+	data, err := json.Marshal(&c)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// SignLevelPack sets the hash of a pack and signs it with key.
+func SignLevelPack(p *LevelPackYAML, key ed25519.PrivateKey) error {
+	hash, err := packHash(p)
+	if err != nil {
+		return err
+	}
+	p.Hash = hash
+	p.PublicKey = base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+	p.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(hash)))
+	return nil
+}
+
+// VerifyLevelPack checks a pack's hash and signature against its content
+// and names the signer if the trust config knows them.
+func VerifyLevelPack(p *LevelPackYAML, trust TrustConfig) Verification {
+	tampered := func(reason string) Verification {
+		return Verification{Status: integrityTampered, Reason: reason}
+	}
+	if p.Hash == "" && p.Signature == "" {
+		return Verification{Status: integrityUnsigned}
+	}
+
+	hash, err := packHash(p)
+	if err != nil {
+		return tampered(err.Error())
+	}
+	if p.Hash != "" && p.Hash != hash {
+		return tampered("content does not match its hash")
+	}
+	if p.Signature == "" {
+		return Verification{Status: integrityUnsigned}
+	}
+
+	pub, err := base64.StdEncoding.DecodeString(p.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return tampered("invalid public key")
+	}
+	sig, err := base64.StdEncoding.DecodeString(p.Signature)
+	if err != nil || !ed25519.Verify(pub, []byte(hash), sig) {
+		return tampered("signature does not match")
+	}
+
+	v := Verification{Status: integrityVerified, Signer: p.PublicKey}
+	for name, key := range trust.Keys {
+		if strings.TrimSpace(key) == p.PublicKey {
+			v.Trusted = name
+			break
+		}
+	}
+	return v
+}
+
+// allow reports why the trust policy refuses a pack, or nil if it may be
+// imported.
+func (t TrustConfig) allow(v Verification) error {
+	if v.Status == integrityTampered {
+		return fmt.Errorf("pack is %s", v)
+	}
+	switch t.Policy {
+	case "", trustAny:
+		return nil
+	case trustSigned:
+		if v.Status != integrityVerified {
+			return fmt.Errorf("trust policy %q refuses %s packs", t.Policy, v.Status)
+		}
+	case trustTrusted:
+		if v.Status != integrityVerified {
+			return fmt.Errorf("trust policy %q refuses %s packs", t.Policy, v.Status)
+		}
+		if v.Trusted == "" {
+			return fmt.Errorf("trust policy %q refuses packs signed by untrusted key %s", t.Policy, v.Signer)
+		}
+	default:
+		return fmt.Errorf("unknown trust policy %q", t.Policy)
+	}
+	return nil
+}
+
+// keysDir returns where signing keys are kept, next to the config file.
+func keysDir() (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "keys"), nil
+}
+
+// keyPath resolves a key given by name in the keys directory, or by path.
+func keyPath(ref string) (string, error) {
+	if strings.ContainsAny(ref, `/\`) || strings.HasSuffix(ref, ".key") {
+		return ref, nil
+	}
+	dir, err := keysDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, ref+".key"), nil
+}
+
+// GenerateKey writes a new ed25519 private key to path, readable only by the
+// user, and returns its public key. An existing key is never overwritten.
+func GenerateKey(path string) (ed25519.PublicKey, error) {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.WriteString(base64.StdEncoding.EncodeToString(key.Seed()) + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pub, nil
+}
+
+// LoadKey reads a private key written by GenerateKey.
+func LoadKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, errors.New("not an ed25519 key")
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func signedTestPack(t *testing.T) (*LevelPackYAML, ed25519.PublicKey) {
+	t.Helper()
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	p, err := DecodeLevelPack([]byte(bundlePackA), formatYAML)
+	if err != nil {
+		t.Fatalf("failed to decode pack: %v", err)
+	}
+	if err := SignLevelPack(p, key); err != nil {
+		t.Fatalf("failed to sign pack: %v", err)
+	}
+	return p, pub
+}
+
+func TestVerifyLevelPack(t *testing.T) {
+	signed, pub := signedTestPack(t)
+	trust := TrustConfig{Keys: map[string]string{"Alice": base64.StdEncoding.EncodeToString(pub)}}
+
+	tests := []struct {
+		name    string
+		edit    func(p *LevelPackYAML)
+		status  string
+		trusted string
+	}{
+		{"signed", func(p *LevelPackYAML) {}, integrityVerified, "Alice"},
+		{"unsigned", func(p *LevelPackYAML) { p.Hash, p.Signature, p.PublicKey = "", "", "" }, integrityUnsigned, ""},
+		{"hash only", func(p *LevelPackYAML) { p.Signature, p.PublicKey = "", "" }, integrityUnsigned, ""},
+		{"level changed", func(p *LevelPackYAML) { p.Levels[0].Solution = "11\n11" }, integrityTampered, ""},
+		{"hash replaced", func(p *LevelPackYAML) {
+			p.Levels[0].Solution = "11\n11"
+			p.Hash, _ = packHash(p)
+		}, integrityTampered, ""},
+		{"bad signature", func(p *LevelPackYAML) { p.Signature = base64.StdEncoding.EncodeToString(make([]byte, 64)) }, integrityTampered, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := *signed
+			p.Levels = append([]Level(nil), signed.Levels...)
+			tt.edit(&p)
+			v := VerifyLevelPack(&p, trust)
+			if v.Status != tt.status || v.Trusted != tt.trusted {
+				t.Errorf("got %+v, want status %s trusted %q", v, tt.status, tt.trusted)
+			}
+		})
+	}
+}
+
+func TestSignedPackSurvivesFormatChange(t *testing.T) {
+	signed, _ := signedTestPack(t)
+	for _, format := range []string{formatYAML, formatJSON} {
+		data, err := encodeLevelPackYAML(signed, format)
+		if err != nil {
+			t.Fatalf("failed to encode %s: %v", format, err)
+		}
+		p, err := DecodeLevelPack(data, format)
+		if err != nil {
+			t.Fatalf("failed to decode %s: %v", format, err)
+		}
+		if v := VerifyLevelPack(p, TrustConfig{}); v.Status != integrityVerified {
+			t.Errorf("expected %s copy to verify, got %s", format, v)
+		}
+	}
+}
+
+func TestTrustPolicy(t *testing.T) {
+	verified := Verification{Status: integrityVerified, Signer: "key"}
+	trusted := Verification{Status: integrityVerified, Signer: "key", Trusted: "Alice"}
+	unsigned := Verification{Status: integrityUnsigned}
+	tampered := Verification{Status: integrityTampered, Reason: "signature does not match"}
+
+	tests := []struct {
+		policy string
+		v      Verification
+		allow  bool
+	}{
+		{"", unsigned, true},
+		{trustAny, verified, true},
+		{trustAny, tampered, false},
+		{trustSigned, unsigned, false},
+		{trustSigned, verified, true},
+		{trustTrusted, verified, false},
+		{trustTrusted, trusted, true},
+		{"nobody", trusted, false},
+	}
+	for _, tt := range tests {
+		err := TrustConfig{Policy: tt.policy}.allow(tt.v)
+		if (err == nil) != tt.allow {
+			t.Errorf("policy %q with %s: got %v, want allow %v", tt.policy, tt.v, err, tt.allow)
+		}
+	}
+}
+
+func TestImportRecordsIntegrity(t *testing.T) {
+	signed, pub := signedTestPack(t)
+	old := activeTrust
+	defer func() { activeTrust = old }()
+	activeTrust = TrustConfig{
+		Policy: trustTrusted,
+		Keys:   map[string]string{"Alice": base64.StdEncoding.EncodeToString(pub)},
+	}
+
+	store := newTestStore(t)
+	unsigned := *signed
+	unsigned.Hash, unsigned.Signature, unsigned.PublicKey = "", "", ""
+	if _, err := store.importPack(&unsigned); err == nil || !strings.Contains(err.Error(), "refuses") {
+		t.Errorf("expected the trusted policy to refuse an unsigned pack, got %v", err)
+	}
+
+	if _, err := store.importPack(signed); err != nil {
+		t.Fatalf("failed to import signed pack: %v", err)
+	}
+	pack, err := store.FindLevelPack("Pack A")
+	if err != nil {
+		t.Fatalf("failed to find pack: %v", err)
+	}
+	if pack.Integrity != integrityVerified || pack.Signer != "Alice" {
+		t.Errorf("expected pack verified and signed by Alice, got %s by %q", pack.Integrity, pack.Signer)
+	}
+}
+
+func TestGenerateAndLoadKey(t *testing.T) {
+	path := t.TempDir() + "/test.key"
+	pub, err := GenerateKey(path)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if _, err := GenerateKey(path); err == nil {
+		t.Error("expected an existing key not to be overwritten")
+	}
+	key, err := LoadKey(path)
+	if err != nil {
+		t.Fatalf("failed to load key: %v", err)
+	}
+	if !pub.Equal(key.Public()) {
+		t.Error("loaded key does not match the generated one")
+	}
+}
@@ -0,0 +1,544 @@
+// This file implements the Kakuro game logic.
+//
+// Kakuro is a cross-sum puzzle. The solution marks blocks with
+// KakuroBlockTile and holds a digit from 1 to 9 in every other cell. Each
+// unbroken line of two or more digit cells, across or down, is a run: its
+// digits add up to the sum in the clue cell before it and no digit repeats.
+//
+// One rune per cell can't hold a clue cell's two sums, so clues are given
+// in the level's cells grid, where a clue with a down sum of 17 and an
+// across sum of 16 is written "17\16", a digit cell is "." and a block
+// without clues is "#". A level without cells takes its sums from the
+// solution.
+//
+// Digits are typed, or stepped through with the primary and secondary
+// actions. Repeated digits are flagged as they are entered, and a clue turns
+// red when its run goes over the sum or fills up without reaching it.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const KakuroBlockTile = rune('#')
+
+var kakuroGlyphs = map[string]GlyphSet{
+	unicodeGlyphs: {
+		KakuroBlockTile: "▒",
+		EmptyTile:       "·",
+		'\\':            "╲",
+	},
+	asciiGlyphs: {
+		KakuroBlockTile: "#",
+		EmptyTile:       ".",
+		'\\':            "\\",
+	},
+	emojiGlyphs: {
+		KakuroBlockTile: "▒",
+		EmptyTile:       "·",
+		'\\':            "╲",
+	},
+}
+
+// kakuroCellWidth fits a clue with two two digit sums.
+const kakuroCellWidth = 5
+
+// kakuroRun is a line of digit cells and the sum from its clue.
+type kakuroRun struct {
+	target int
+	down   bool
+	clueX  int
+	clueY  int
+	cells  [][2]int
+}
+
+// kakuroLayout reads the blocks of a level from its solution, and the runs
+// between them with their sums from the level's cells, or from the solution
+// when it has none.
+func kakuroLayout(l Level) ([][]bool, []kakuroRun, error) {
+	solution := strings.Split(strings.Trim(l.Solution, "\n"), "\n")
+	w, h := len(solution[0]), len(solution)
+	blocks := make([][]bool, h)
+	for y, row := range solution {
+		if len(row) != w {
+			return nil, nil, fmt.Errorf("solution row %d has %d cells, want %d", y+1, len(row), w)
+		}
+		blocks[y] = make([]bool, w)
+		for x, r := range row {
+			blocks[y][x] = r == KakuroBlockTile
+		}
+	}
+
+	var runs []kakuroRun
+	addRun := func(r kakuroRun) {
+		if len(r.cells) < 2 {
+			return
+		}
+		for _, c := range r.cells {
+			r.target += max(0, int(solution[c[1]][c[0]]-'0'))
+		}
+		runs = append(runs, r)
+	}
+	for y := 0; y < h; y++ {
+		r := kakuroRun{clueX: -1, clueY: y}
+		for x := 0; x <= w; x++ {
+			if x == w || blocks[y][x] {
+				addRun(r)
+				r = kakuroRun{clueX: x, clueY: y}
+				continue
+			}
+			r.cells = append(r.cells, [2]int{x, y})
+		}
+	}
+	for x := 0; x < w; x++ {
+		r := kakuroRun{down: true, clueX: x, clueY: -1}
+		for y := 0; y <= h; y++ {
+			if y == h || blocks[y][x] {
+				addRun(r)
+				r = kakuroRun{down: true, clueX: x, clueY: y}
+				continue
+			}
+			r.cells = append(r.cells, [2]int{x, y})
+		}
+	}
+
+	if strings.TrimSpace(l.Cells) == "" {
+		for _, r := range runs {
+			if r.clueX < 0 || r.clueY < 0 {
+				return nil, nil, fmt.Errorf("run at row %d column %d has no clue cell", r.cells[0][1]+1, r.cells[0][0]+1)
+			}
+		}
+		return blocks, runs, nil
+	}
+	clues, err := kakuroClues(l.Cells, blocks, w, h)
+	if err != nil {
+		return nil, nil, err
+	}
+	used := make(map[[3]int]bool)
+	for i, r := range runs {
+		dir := 1
+		if r.down {
+			dir = 0
+		}
+		key := [3]int{r.clueX, r.clueY, dir}
+		sum, ok := clues[key]
+		if !ok {
+			return nil, nil, fmt.Errorf("run at row %d column %d has no clue", r.cells[0][1]+1, r.cells[0][0]+1)
+		}
+		runs[i].target = sum
+		used[key] = true
+	}
+	for key := range clues {
+		if !used[key] {
+			return nil, nil, fmt.Errorf("clue at row %d column %d has no run", key[1]+1, key[0]+1)
+		}
+	}
+	return blocks, runs, nil
+}
+
+// kakuroClues reads the sums from a level's cells, keyed by the clue cell
+// and direction, with 0 for down and 1 for across.
+func kakuroClues(cells string, blocks [][]bool, w, h int) (map[[3]int]int, error) {
+	grid, err := parseCellGrid(cells, w, h)
+	if err != nil {
+		return nil, err
+	}
+	clues := make(map[[3]int]int)
+	for y, row := range grid {
+		for x, values := range row {
+			token := strings.Join(values, cellSeparator)
+			switch {
+			case !blocks[y][x]:
+				if token != "." {
+					return nil, fmt.Errorf("cell at row %d column %d is a digit cell, want \".\", got %q", y+1, x+1, token)
+				}
+				continue
+			case token == string(KakuroBlockTile):
+				continue
+			case len(values) != 2:
+				return nil, fmt.Errorf("clue at row %d column %d should be down\\across, got %q", y+1, x+1, token)
+			}
+			for dir, v := range values {
+				if v == "" {
+					continue
+				}
+				sum, err := strconv.Atoi(v)
+				if err != nil || sum < 1 || sum > 45 {
+					return nil, fmt.Errorf("clue at row %d column %d has an invalid sum %q", y+1, x+1, v)
+				}
+				clues[[3]int{x, y, dir}] = sum
+			}
+		}
+	}
+	return clues, nil
+}
+
+// validateKakuro refuses levels whose clues don't fit their layout or whose
+// solution breaks its own clues.
+func validateKakuro(l Level) (string, error) {
+	blocks, runs, err := kakuroLayout(l)
+	if err != nil {
+		return "", err
+	}
+	solution := strings.Split(strings.Trim(l.Solution, "\n"), "\n")
+	for y, row := range blocks {
+		for x, block := range row {
+			if r := solution[y][x]; !block && (r < '1' || r > '9') {
+				return "", fmt.Errorf("solution cell at row %d column %d should be a digit, got %q", y+1, x+1, r)
+			}
+		}
+	}
+	for _, r := range runs {
+		values := make([]rune, len(r.cells))
+		for i, c := range r.cells {
+			values[i] = rune(solution[c[1]][c[0]])
+		}
+		if kakuroRunStatus(values, r.target) != lineSatisfied {
+			return "", fmt.Errorf("solution breaks the clue at row %d column %d", r.clueY+1, r.clueX+1)
+		}
+	}
+	return "", nil
+}
+
+// kakuroRunStatus checks the digits of a run against its sum. A run is
+// broken when a digit repeats, its digits go over the sum, or it is full
+// without reaching it.
+func kakuroRunStatus(values []rune, target int) lineStatus {
+	sum, full := 0, true
+	seen := make(map[rune]bool)
+	for _, v := range values {
+		if v < '1' || v > '9' {
+			full = false
+			continue
+		}
+		if seen[v] {
+			return lineBroken
+		}
+		seen[v] = true
+		sum += int(v - '0')
+	}
+	switch {
+	case sum > target || (full && sum != target):
+		return lineBroken
+	case full:
+		return lineSatisfied
+	}
+	return lineOpen
+}
+
+type KakuroEngine struct {
+	Engine
+	blocks    [][]bool
+	runs      []kakuroRun
+	runsAt    map[[2]int][]int
+	runStatus []lineStatus
+	viewport  gridViewport
+}
+
+func (e *KakuroEngine) New(l Level, s *Save) (GameEngine, error) {
+	blocks, runs, err := kakuroLayout(l)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := e.Engine.New(l, s); err != nil {
+		return nil, err
+	}
+	if e.GetHeight() != len(blocks) || e.GetWidth() != len(blocks[0]) {
+		return nil, errors.New("initial state and solution are different sizes")
+	}
+
+	// Blocks come from the solution, so they can't be typed over even when
+	// the initial state leaves them out.
+	for y, row := range blocks {
+		for x, block := range row {
+			if block && e.HasCell(x, y) {
+				e.Grid[y][x].value, e.Grid[y][x].state = KakuroBlockTile, given
+			}
+		}
+	}
+
+	e.blocks, e.runs = blocks, runs
+	e.runsAt = make(map[[2]int][]int)
+	for i, r := range runs {
+		for _, c := range r.cells {
+			e.runsAt[c] = append(e.runsAt[c], i)
+		}
+	}
+	e.updateStatus()
+	e.viewport.fit(0, 0, kakuroCellWidth, e.GetWidth(), e.GetHeight(), 0, 0)
+	return e, nil
+}
+
+// PrimaryAction steps the digit in a cell up, from blank through 1 to 9.
+func (e *KakuroEngine) PrimaryAction(x, y int) error {
+	return stepDigit(e, x, y, 1, 9)
+}
+
+// SecondaryAction steps the digit in a cell down.
+func (e *KakuroEngine) SecondaryAction(x, y int) error {
+	return stepDigit(e, x, y, -1, 9)
+}
+
+// EnterValue puts a typed digit in a cell. Typing 0 clears it.
+func (e *KakuroEngine) EnterValue(x, y int, v rune) error {
+	switch {
+	case v == '0':
+		return e.ClearCell(x, y)
+	case v < '1' || v > '9':
+		return fmt.Errorf("%q is not a digit", v)
+	}
+	if err := e.setCellValue(x, y, v); err != nil {
+		return err
+	}
+	e.updateStatus()
+	return nil
+}
+
+func (e *KakuroEngine) ClearCell(x, y int) error {
+	if err := e.Engine.ClearCell(x, y); err != nil {
+		return err
+	}
+	e.updateStatus()
+	return nil
+}
+
+// Evaluate reports whether every run is full and adds up to its sum without
+// repeats.
+func (e *KakuroEngine) Evaluate() (bool, error) {
+	for y, row := range e.Grid {
+		for x, cell := range row {
+			if !e.blocks[y][x] && (cell.value < '1' || cell.value > '9') {
+				return false, nil
+			}
+		}
+	}
+	for i := range e.runs {
+		if e.checkRun(i) != lineSatisfied {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (e *KakuroEngine) View(m model) string {
+	h := e.helpView(m)
+	e.viewport.fitTo(m, 0, lipgloss.Height(h), kakuroCellWidth, e.GetWidth(), e.GetHeight())
+	return lipgloss.JoinVertical(lipgloss.Left, e.gridView(m), h)
+}
+
+// CellAt maps a position within the rendered view to grid coordinates.
+func (e *KakuroEngine) CellAt(col, row int) (int, int, bool) {
+	return e.viewport.cellAt(col, row, kakuroCellWidth)
+}
+
+// GlyphSets returns the glyphs blocks and empty cells are drawn with.
+// Digits are drawn as they are.
+func (e *KakuroEngine) GlyphSets() map[string]GlyphSet {
+	return kakuroGlyphs
+}
+
+// --- Private Functions ---
+
+// checkRun compares a run with its sum.
+func (e *KakuroEngine) checkRun(i int) lineStatus {
+	r := e.runs[i]
+	values := make([]rune, len(r.cells))
+	for j, c := range r.cells {
+		values[j] = e.Grid[c[1]][c[0]].value
+	}
+	return kakuroRunStatus(values, r.target)
+}
+
+// updateStatus checks every run, flags digits that repeat within a run and
+// refreshes the solved flag on the save.
+func (e *KakuroEngine) updateStatus() {
+	e.runStatus = make([]lineStatus, len(e.runs))
+	for i := range e.runs {
+		e.runStatus[i] = e.checkRun(i)
+	}
+	for y, row := range e.Grid {
+		for x := range row {
+			if !e.blocks[y][x] {
+				e.Grid[y][x].RunValidation(!e.repeated(x, y))
+			}
+		}
+	}
+	solved, err := e.Evaluate()
+	e.Save.Solved = err == nil && solved
+}
+
+// repeated reports whether the digit in a cell appears elsewhere in one of
+// its runs.
+func (e *KakuroEngine) repeated(x, y int) bool {
+	v := e.Grid[y][x].value
+	if v < '1' || v > '9' {
+		return false
+	}
+	for _, i := range e.runsAt[[2]int{x, y}] {
+		for _, c := range e.runs[i].cells {
+			if c != [2]int{x, y} && e.Grid[c[1]][c[0]].value == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cursorRuns returns the runs through the cursor.
+func (e *KakuroEngine) cursorRuns(m model) map[int]bool {
+	runs := make(map[int]bool)
+	for _, i := range e.runsAt[[2]int{m.cursorX, m.cursorY}] {
+		runs[i] = true
+	}
+	return runs
+}
+
+func (e *KakuroEngine) gridView(m model) string {
+	glyphs := glyphSetFor(kakuroGlyphs)
+	active := e.cursorRuns(m)
+	inActive := make(map[[2]int]bool)
+	for i := range active {
+		for _, c := range e.runs[i].cells {
+			inActive[c] = true
+		}
+	}
+
+	// Index the runs by their clue cell.
+	clueRuns := make(map[[2]int][2]int)
+	for i, r := range e.runs {
+		key := [2]int{r.clueX, r.clueY}
+		pair, ok := clueRuns[key]
+		if !ok {
+			pair = [2]int{-1, -1}
+		}
+		if r.down {
+			pair[0] = i
+		} else {
+			pair[1] = i
+		}
+		clueRuns[key] = pair
+	}
+
+	v := e.viewport
+	var rows []string
+	for y := v.offsetY; y < v.offsetY+v.rows; y++ {
+		var cells []string
+		for x := v.offsetX; x < v.offsetX+v.cols; x++ {
+			var cell string
+			if e.blocks[y][x] {
+				pair, ok := clueRuns[[2]int{x, y}]
+				cursor := x == m.cursorX && y == m.cursorY
+				switch {
+				case ok:
+					cell = e.clueView(pair, active, cursor, glyphs)
+				case cursor:
+					cell = highlightStyle.Width(kakuroCellWidth).Render("")
+				default:
+					cell = subtleStyle.Render(strings.Repeat(glyphs[KakuroBlockTile], kakuroCellWidth/glyphWidth(glyphs[KakuroBlockTile])))
+				}
+			} else {
+				cell = e.digitView(x, y, m, inActive[[2]int{x, y}], glyphs)
+			}
+			cells = append(cells, lipgloss.NewStyle().Width(kakuroCellWidth).Render(cell))
+		}
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, cells...))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, rows...)
+}
+
+// clueView draws a clue cell, the down sum before the across sum, each
+// coloured by how its run is doing and bold when its run is under the
+// cursor. Under the cursor itself it is drawn highlighted instead.
+func (e *KakuroEngine) clueView(pair [2]int, active map[int]bool, cursor bool, glyphs GlyphSet) string {
+	sum := func(i int, format string) string {
+		if i < 0 {
+			return fmt.Sprintf(format, "")
+		}
+		return fmt.Sprintf(format, strconv.Itoa(e.runs[i].target))
+	}
+	if cursor {
+		return highlightStyle.Render(sum(pair[0], "%2s") + glyphs['\\'] + sum(pair[1], "%-2s"))
+	}
+	part := func(i int, format string) string {
+		if i < 0 {
+			return sum(i, format)
+		}
+		style := hintLineStyle(e.runStatus[i])
+		if active[i] {
+			style = style.Inherit(crosshairStyle).Bold(true)
+		}
+		return style.Render(sum(i, format))
+	}
+	return part(pair[0], "%2s") + subtleStyle.Render(glyphs['\\']) + part(pair[1], "%-2s")
+}
+
+// digitView draws a digit cell.
+func (e *KakuroEngine) digitView(x, y int, m model, active bool, glyphs GlyphSet) string {
+	cell := e.Grid[y][x]
+	s := digitStyle
+	switch cell.state {
+	case given:
+		s = digitGivenStyle
+	case invalid:
+		s = digitInvalidStyle
+	}
+	if active {
+		s = s.Inherit(crosshairStyle)
+	}
+	if x == m.cursorX && y == m.cursorY {
+		s = highlightStyle
+	}
+	g := string(cell.value)
+	if cell.value < '1' || cell.value > '9' {
+		g = glyphs[EmptyTile]
+	}
+	return s.Width(kakuroCellWidth).AlignHorizontal(lipgloss.Center).Render(g)
+}
+
+func (e *KakuroEngine) helpView(m model) string {
+	help := "\n"
+	if !e.blocks[m.cursorY][m.cursorX] && e.Grid[m.cursorY][m.cursorX].state != given {
+		help += "1-9: Enter digit\tz/x: Next/previous digit\tbackspace: clear\n"
+	} else {
+		help += "\n"
+	}
+	help += e.statusView(m)
+	help += "arrow keys or hjkl to move\n"
+	help += "Press 'esc' to return to the menu.\n"
+	if e.viewport.clipped(e.GetWidth(), e.GetHeight()) {
+		v := e.viewport
+		help += fmt.Sprintf("Showing columns %d-%d of %d, rows %d-%d of %d\n",
+			v.offsetX+1, v.offsetX+v.cols, e.GetWidth(),
+			v.offsetY+1, v.offsetY+v.rows, e.GetHeight())
+	}
+	if e.Save.Solved {
+		help += "Congrats!\n"
+	}
+	return help
+}
+
+// statusView reports the running totals of the runs through the cursor.
+func (e *KakuroEngine) statusView(m model) string {
+	across, down := "-", "-"
+	for _, i := range e.runsAt[[2]int{m.cursorX, m.cursorY}] {
+		sum := 0
+		for _, c := range e.runs[i].cells {
+			if v := e.Grid[c[1]][c[0]].value; v >= '1' && v <= '9' {
+				sum += int(v - '0')
+			}
+		}
+		total := fmt.Sprintf("%d/%d", sum, e.runs[i].target)
+		if e.runs[i].down {
+			down = total
+		} else {
+			across = total
+		}
+	}
+	return fmt.Sprintf("Across: %s\tDown: %s\n", across, down)
+}
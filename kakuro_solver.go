@@ -0,0 +1,157 @@
+package main
+
+import "strings"
+
+// kakuroSearchBudget caps how many cells countKakuroSolutions fills in
+// before giving up on a level.
+const kakuroSearchBudget = 2000000
+
+// kakuroSolver fills in a kakuro grid by backtracking, always choosing the
+// open cell with the fewest digits left.
+type kakuroSolver struct {
+	runs   []kakuroRun
+	runsAt map[[2]int][]int
+	open   [][2]int
+	values map[[2]int]int
+	budget int
+}
+
+// countKakuroSolutions counts the ways to fill in a level's digit cells, up
+// to limit, keeping the digits its initial state gives.
+func countKakuroSolutions(l Level, limit int) (int, bool) {
+	_, runs, err := kakuroLayout(l)
+	if err != nil {
+		return 0, true
+	}
+	s := &kakuroSolver{
+		runs:   runs,
+		runsAt: make(map[[2]int][]int),
+		values: make(map[[2]int]int),
+		budget: kakuroSearchBudget,
+	}
+	initial := strings.Split(strings.Trim(l.Initial, "\n"), "\n")
+	for i, r := range runs {
+		for _, c := range r.cells {
+			if _, seen := s.runsAt[c]; !seen {
+				s.values[c] = 0
+				if c[1] < len(initial) && c[0] < len(initial[c[1]]) {
+					if v := initial[c[1]][c[0]]; v >= '1' && v <= '9' {
+						s.values[c] = int(v - '0')
+					}
+				}
+				if s.values[c] == 0 {
+					s.open = append(s.open, c)
+				}
+			}
+			s.runsAt[c] = append(s.runsAt[c], i)
+		}
+	}
+	for i := range runs {
+		if !s.runOK(i) {
+			return 0, true
+		}
+	}
+	count := s.search(limit)
+	return count, s.budget > 0
+}
+
+// search counts the completions of the grid up to limit.
+func (s *kakuroSolver) search(limit int) int {
+	best, bestCount := -1, 10
+	var bestDigits []int
+	for i, c := range s.open {
+		if s.values[c] != 0 {
+			continue
+		}
+		digits := s.candidates(c)
+		if len(digits) < bestCount {
+			best, bestCount, bestDigits = i, len(digits), digits
+		}
+		if bestCount == 0 {
+			return 0
+		}
+	}
+	if best < 0 {
+		return 1
+	}
+
+	c := s.open[best]
+	count := 0
+	for _, d := range bestDigits {
+		if s.budget--; s.budget <= 0 {
+			break
+		}
+		s.values[c] = d
+		count += s.search(limit - count)
+		if count >= limit {
+			break
+		}
+	}
+	s.values[c] = 0
+	return count
+}
+
+// candidates returns the digits that fit a cell given the rest of its runs.
+func (s *kakuroSolver) candidates(c [2]int) []int {
+	var digits []int
+	for d := 1; d <= 9; d++ {
+		s.values[c] = d
+		ok := true
+		for _, i := range s.runsAt[c] {
+			if !s.runOK(i) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			digits = append(digits, d)
+		}
+	}
+	s.values[c] = 0
+	return digits
+}
+
+// runOK reports whether a run can still reach its sum without repeating a
+// digit.
+func (s *kakuroSolver) runOK(i int) bool {
+	r := s.runs[i]
+	values := make([]int, len(r.cells))
+	for j, c := range r.cells {
+		values[j] = s.values[c]
+	}
+	return sumFits(values, r.target, 9)
+}
+
+// sumFits reports whether digits from 1 to maxDigit, with 0 for a blank,
+// can still add up to target without repeating: none repeats yet and the
+// target lies between the smallest and largest totals the blanks could
+// make with the digits left.
+func sumFits(values []int, target, maxDigit int) bool {
+	var used [10]bool
+	sum, open := 0, 0
+	for _, v := range values {
+		if v == 0 {
+			open++
+			continue
+		}
+		if used[v] {
+			return false
+		}
+		used[v] = true
+		sum += v
+	}
+	low, high := sum, sum
+	for d, n := 1, 0; d <= maxDigit && n < open; d++ {
+		if !used[d] {
+			low += d
+			n++
+		}
+	}
+	for d, n := maxDigit, 0; d >= 1 && n < open; d-- {
+		if !used[d] {
+			high += d
+			n++
+		}
+	}
+	return low <= target && target <= high
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const (
+	testKakuroSolution = "###\n#12\n#34"
+	testKakuroCells    = "# 4\\ 6\\\n\\3 . .\n\\7 . ."
+)
+
+var testKakuroLevel = Level{
+	Engine:   "kakuro",
+	Initial:  "###\n#..\n#..",
+	Solution: testKakuroSolution,
+	Cells:    testKakuroCells,
+}
+
+func TestKakuroLayout(t *testing.T) {
+	for _, cells := range []string{"", testKakuroCells} {
+		_, runs, err := kakuroLayout(Level{Solution: testKakuroSolution, Cells: cells})
+		if err != nil {
+			t.Fatalf("cells %q: unexpected error: %v", cells, err)
+		}
+		var got []string
+		for _, r := range runs {
+			dir := "across"
+			if r.down {
+				dir = "down"
+			}
+			got = append(got, fmt.Sprintf("%s %d,%d=%d", dir, r.clueX, r.clueY, r.target))
+		}
+		want := "across 0,1=3 across 0,2=7 down 1,0=4 down 2,0=6"
+		if strings.Join(got, " ") != want {
+			t.Errorf("cells %q: expected runs %q, got %q", cells, want, strings.Join(got, " "))
+		}
+	}
+}
+
+func TestKakuroLayoutErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		solution string
+		cells    string
+		want     string
+	}{
+		{name: "missing row", cells: "# 4\\ 6\\\n\\3 . .", want: "rows"},
+		{name: "digit cell with a clue", cells: "# 4\\ 6\\\n\\3 . 5\\\n\\7 . .", want: "digit cell"},
+		{name: "missing clue", cells: "# 4\\ 6\\\n# . .\n\\7 . .", want: "no clue"},
+		{name: "clue without a run", cells: "5\\ 4\\ 6\\\n\\3 . .\n\\7 . .", want: "no run"},
+		{name: "bad sum", cells: "# 4\\ 6\\\n\\x . .\n\\7 . .", want: "invalid sum"},
+		{name: "run from the edge", solution: "###\n12#", want: "has no clue cell"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.solution == "" {
+				tc.solution = testKakuroSolution
+			}
+			_, _, err := kakuroLayout(Level{Solution: tc.solution, Cells: tc.cells})
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected an error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestKakuroPlay(t *testing.T) {
+	e := newTestEngine(t, testKakuroLevel).(*KakuroEngine)
+	for _, c := range []struct {
+		x, y int
+		v    rune
+	}{{1, 1, '1'}, {2, 1, '2'}, {1, 2, '3'}} {
+		if err := e.EnterValue(c.x, c.y, c.v); err != nil {
+			t.Fatalf("failed to enter %c: %v", c.v, err)
+		}
+	}
+	if e.Save.Solved {
+		t.Fatal("expected an unfinished grid to be unsolved")
+	}
+	// Stepping up from blank enters a 1, and back down clears the cell.
+	e.PrimaryAction(2, 2)
+	e.SecondaryAction(2, 2)
+	if v := e.Grid[2][2].value; v != ' ' {
+		t.Errorf("expected stepping up and down to clear the cell, got %q", v)
+	}
+	e.EnterValue(2, 2, '4')
+	if !e.Save.Solved {
+		t.Errorf("expected the grid to be solved, got %q", e.Save.State)
+	}
+	if err := e.EnterValue(2, 2, 'a'); err == nil {
+		t.Error("expected a letter to be refused")
+	}
+	if v := e.Grid[0][1].value; v != KakuroBlockTile || e.Grid[0][1].state != given {
+		t.Errorf("expected blocks to be given, got %q", v)
+	}
+}
+
+func TestKakuroConflicts(t *testing.T) {
+	e := newTestEngine(t, testKakuroLevel).(*KakuroEngine)
+
+	// A repeated digit flags both cells and breaks the run.
+	e.EnterValue(1, 1, '1')
+	e.EnterValue(2, 1, '1')
+	if e.Grid[1][1].state != invalid || e.Grid[1][2].state != invalid {
+		t.Errorf("expected repeated digits to be invalid, got states %d and %d", e.Grid[1][1].state, e.Grid[1][2].state)
+	}
+	if e.runStatus[0] != lineBroken {
+		t.Errorf("expected the run with a repeat to be broken, got %d", e.runStatus[0])
+	}
+	e.EnterValue(2, 1, '2')
+	if e.Grid[1][1].state != filled || e.runStatus[0] != lineSatisfied {
+		t.Errorf("expected fixing the repeat to clear it, got state %d and status %d", e.Grid[1][1].state, e.runStatus[0])
+	}
+
+	// A digit bigger than the sum breaks the run before it is full.
+	e.EnterValue(1, 2, '8')
+	if e.runStatus[1] != lineBroken || e.runStatus[2] != lineBroken {
+		t.Errorf("expected going over the sums to break both runs, got %d and %d", e.runStatus[1], e.runStatus[2])
+	}
+	if e.Grid[2][1].state != filled {
+		t.Errorf("expected a digit that isn't repeated to stay valid, got state %d", e.Grid[2][1].state)
+	}
+}
+
+func TestKakuroRunStatus(t *testing.T) {
+	testCases := []struct {
+		values string
+		target int
+		want   lineStatus
+	}{
+		{values: "  ", target: 3, want: lineOpen},
+		{values: "1 ", target: 3, want: lineOpen},
+		{values: "12", target: 3, want: lineSatisfied},
+		{values: "13", target: 3, want: lineBroken},
+		{values: "11", target: 2, want: lineBroken},
+		{values: "4 ", target: 3, want: lineBroken},
+		{values: "12", target: 4, want: lineBroken},
+	}
+
+	for _, tc := range testCases {
+		if got := kakuroRunStatus([]rune(tc.values), tc.target); got != tc.want {
+			t.Errorf("%q against %d: expected %d, got %d", tc.values, tc.target, tc.want, got)
+		}
+	}
+}
+
+func TestCountKakuroSolutions(t *testing.T) {
+	testCases := []struct {
+		name     string
+		initial  string
+		solution string
+		cells    string
+		want     int
+	}{
+		{name: "unique", initial: "###\n#..\n#..", solution: testKakuroSolution, cells: testKakuroCells, want: 1},
+		{name: "two ways", initial: "###\n#..\n#..", solution: "###\n#12\n#21", want: 2},
+		{name: "given digit", initial: "###\n#1.\n#..", solution: "###\n#12\n#21", want: 1},
+		{name: "impossible", initial: "###\n#..\n#..", solution: testKakuroSolution, cells: "# 4\\ 6\\\n\\3 . .\n\\30 . .", want: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := Level{Initial: tc.initial, Solution: tc.solution, Cells: tc.cells}
+			got, ok := countKakuroSolutions(l, 2)
+			if !ok || got != tc.want {
+				t.Errorf("expected %d solutions, got %d (ok %v)", tc.want, got, ok)
+			}
+		})
+	}
+}
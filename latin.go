@@ -0,0 +1,256 @@
+// This file implements the Latin-square engine that Futoshiki and
+// Skyscrapers are built on.
+//
+// The solution is the filled grid: N rows of N digits from 1 to N, where N is
+// at most 9, and every row and column holds each digit once. A variant adds
+// its own clues, checked by its latinVariant, and draws them around or
+// between the cells. Levels are checked against the rules rather than the
+// solution.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var latinGlyphs = map[string]GlyphSet{
+	unicodeGlyphs: {EmptyTile: "·"},
+	asciiGlyphs:   {EmptyTile: "."},
+	emojiGlyphs:   {EmptyTile: "·"},
+}
+
+// latinCellWidth is the width of a digit cell.
+const latinCellWidth = 3
+
+// latinVariant is what a puzzle adds to the rows and columns of a Latin
+// square.
+type latinVariant interface {
+	// check marks the cells that break one of the variant's clues in broken
+	// and returns how each clue stands. It is given partly filled grids, so
+	// a clue is only broken once no digits in the blanks could satisfy it.
+	check(values [][]rune, broken [][]bool) []lineStatus
+}
+
+// latinSize reads the size of a Latin square from a level's solution.
+func latinSize(l Level) (int, error) {
+	rows := strings.Split(strings.Trim(l.Solution, "\n"), "\n")
+	n := len(rows)
+	if n > 9 {
+		return 0, errors.New("a latin square can't be bigger than 9x9")
+	}
+	for y, row := range rows {
+		if len(row) != n {
+			return 0, fmt.Errorf("solution row %d has %d cells, want %d for a square grid", y+1, len(row), n)
+		}
+	}
+	return n, nil
+}
+
+// latinDigit reports whether v is one of the digits of a grid of size n.
+func latinDigit(v rune, n int) bool {
+	return v >= '1' && v < '1'+rune(n)
+}
+
+// latinCheck flags the digits that repeat in their row or column or that
+// break one of the variant's clues, and returns how each clue stands.
+func latinCheck(values [][]rune, variant latinVariant) ([][]bool, []lineStatus) {
+	n := len(values)
+	broken := make([][]bool, n)
+	for y := range broken {
+		broken[y] = make([]bool, n)
+	}
+	for i := 0; i < n; i++ {
+		inRow := make(map[rune][]int)
+		inCol := make(map[rune][]int)
+		for j := 0; j < n; j++ {
+			if v := values[i][j]; latinDigit(v, n) {
+				inRow[v] = append(inRow[v], j)
+			}
+			if v := values[j][i]; latinDigit(v, n) {
+				inCol[v] = append(inCol[v], j)
+			}
+		}
+		for _, xs := range inRow {
+			for _, x := range xs {
+				broken[i][x] = broken[i][x] || len(xs) > 1
+			}
+		}
+		for _, ys := range inCol {
+			for _, y := range ys {
+				broken[y][i] = broken[y][i] || len(ys) > 1
+			}
+		}
+	}
+	return broken, variant.check(values, broken)
+}
+
+// latinSolved reports whether a grid is full and breaks no rule.
+func latinSolved(values [][]rune, variant latinVariant) bool {
+	broken, status := latinCheck(values, variant)
+	for y, row := range values {
+		for x, v := range row {
+			if !latinDigit(v, len(values)) || broken[y][x] {
+				return false
+			}
+		}
+	}
+	for _, s := range status {
+		if s != lineSatisfied {
+			return false
+		}
+	}
+	return true
+}
+
+// validateLatin refuses levels whose solution breaks the rules of the
+// variant or whose givens don't match the solution.
+func validateLatin(l Level, variant latinVariant) (string, error) {
+	n, err := latinSize(l)
+	if err != nil {
+		return "", err
+	}
+	values := make([][]rune, n)
+	for y, row := range strings.Split(strings.Trim(l.Solution, "\n"), "\n") {
+		values[y] = []rune(row)
+	}
+	if !latinSolved(values, variant) {
+		return "", errors.New("solution breaks the rules")
+	}
+	for y, row := range strings.Split(strings.Trim(l.Initial, "\n"), "\n") {
+		for x, v := range row {
+			if v != '.' && v != ' ' && (y >= n || x >= n || v != values[y][x]) {
+				return "", fmt.Errorf("given at row %d column %d doesn't match the solution", y+1, x+1)
+			}
+		}
+	}
+	return "", nil
+}
+
+// LatinSquareEngine plays the digits of a Latin square. Variants embed it
+// and draw their own clues.
+type LatinSquareEngine struct {
+	Engine
+	size       int
+	variant    latinVariant
+	clueStatus []lineStatus
+}
+
+// newLatin sets up the grid of a level for a variant.
+func (e *LatinSquareEngine) newLatin(l Level, s *Save, variant latinVariant) error {
+	n, err := latinSize(l)
+	if err != nil {
+		return err
+	}
+	if _, err := e.Engine.New(l, s); err != nil {
+		return err
+	}
+	if e.GetHeight() != n || e.GetWidth() != n {
+		return errors.New("initial state and solution are different sizes")
+	}
+	e.size = n
+	e.variant = variant
+	e.updateStatus()
+	return nil
+}
+
+// PrimaryAction steps the digit in a cell up, from blank through 1 to N.
+func (e *LatinSquareEngine) PrimaryAction(x, y int) error {
+	return stepDigit(e, x, y, 1, e.size)
+}
+
+// SecondaryAction steps the digit in a cell down.
+func (e *LatinSquareEngine) SecondaryAction(x, y int) error {
+	return stepDigit(e, x, y, -1, e.size)
+}
+
+// EnterValue puts a typed digit in a cell. Typing 0 clears it.
+func (e *LatinSquareEngine) EnterValue(x, y int, v rune) error {
+	switch {
+	case v == '0':
+		return e.ClearCell(x, y)
+	case !latinDigit(v, e.size):
+		return fmt.Errorf("%q is not a digit from 1 to %d", v, e.size)
+	}
+	if err := e.setCellValue(x, y, v); err != nil {
+		return err
+	}
+	e.updateStatus()
+	return nil
+}
+
+func (e *LatinSquareEngine) ClearCell(x, y int) error {
+	if err := e.Engine.ClearCell(x, y); err != nil {
+		return err
+	}
+	e.updateStatus()
+	return nil
+}
+
+// Evaluate reports whether the grid is full and breaks no rule.
+func (e *LatinSquareEngine) Evaluate() (bool, error) {
+	return latinSolved(e.values(), e.variant), nil
+}
+
+// GlyphSets returns the glyph empty cells are drawn with. Digits are drawn
+// as they are.
+func (e *LatinSquareEngine) GlyphSets() map[string]GlyphSet {
+	return latinGlyphs
+}
+
+// --- Private Functions ---
+
+// updateStatus flags the digits that break a rule, checks the clues and
+// refreshes the solved flag on the save.
+func (e *LatinSquareEngine) updateStatus() {
+	values := e.values()
+	broken, status := latinCheck(values, e.variant)
+	for y, row := range e.Grid {
+		for x := range row {
+			e.Grid[y][x].RunValidation(!broken[y][x])
+		}
+	}
+	e.clueStatus = status
+	e.Save.Solved = latinSolved(values, e.variant)
+}
+
+// cellView draws a digit cell, marking the row and column of the cursor.
+func (e *LatinSquareEngine) cellView(x, y int, m model) string {
+	cell := e.Grid[y][x]
+	s := digitStyle
+	switch cell.state {
+	case given:
+		s = digitGivenStyle
+	case invalid:
+		s = digitInvalidStyle
+	}
+	if x == m.cursorX || y == m.cursorY {
+		s = s.Inherit(crosshairStyle)
+	}
+	if x == m.cursorX && y == m.cursorY {
+		s = highlightStyle
+	}
+	g := string(cell.value)
+	if !latinDigit(cell.value, e.size) {
+		g = glyphSetFor(latinGlyphs)[EmptyTile]
+	}
+	return s.Width(latinCellWidth).AlignHorizontal(lipgloss.Center).Render(g)
+}
+
+func (e *LatinSquareEngine) helpView(m model) string {
+	help := "\n"
+	if e.HasCell(m.cursorX, m.cursorY) && e.Grid[m.cursorY][m.cursorX].state != given {
+		help += fmt.Sprintf("1-%d: Enter digit\tz/x: Next/previous digit\tbackspace: clear\n", e.size)
+	} else {
+		help += "\n"
+	}
+	help += "arrow keys or hjkl to move\n"
+	help += "Press 'esc' to return to the menu.\n"
+	if e.Save.Solved {
+		help += "Congrats!\n"
+	}
+	return help
+}
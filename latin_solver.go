@@ -0,0 +1,110 @@
+package main
+
+import "strings"
+
+// latinSearchBudget caps how many digits countLatinSolutions tries before
+// giving up on a level.
+const latinSearchBudget = 2000000
+
+// latinSolver fills in a Latin square by backtracking, always choosing the
+// blank cell with the fewest digits left in its row and column, and backing
+// off as soon as one of the variant's clues breaks.
+type latinSolver struct {
+	variant latinVariant
+	values  [][]rune
+	size    int
+	budget  int
+}
+
+// countLatinSolutions counts the ways to fill in a level's grid under a
+// variant's clues, up to limit, keeping the digits its initial state gives.
+func countLatinSolutions(l Level, variant latinVariant, limit int) (int, bool) {
+	n, err := latinSize(l)
+	if err != nil {
+		return 0, true
+	}
+	s := &latinSolver{variant: variant, size: n, budget: latinSearchBudget}
+	s.values = make([][]rune, n)
+	for y := range s.values {
+		s.values[y] = []rune(strings.Repeat(string(EmptyTile), n))
+	}
+	for y, row := range strings.Split(strings.Trim(l.Initial, "\n"), "\n") {
+		for x, v := range row {
+			if y < n && x < n && latinDigit(v, n) {
+				s.values[y][x] = v
+			}
+		}
+	}
+	if s.broken() {
+		return 0, true
+	}
+	count := s.search(limit)
+	return count, s.budget > 0
+}
+
+// search counts the completions of the grid up to limit.
+func (s *latinSolver) search(limit int) int {
+	bestX, bestY := -1, -1
+	var best []rune
+	for y, row := range s.values {
+		for x, v := range row {
+			if latinDigit(v, s.size) {
+				continue
+			}
+			digits := s.candidates(x, y)
+			if len(digits) == 0 {
+				return 0
+			}
+			if bestX < 0 || len(digits) < len(best) {
+				bestX, bestY, best = x, y, digits
+			}
+		}
+	}
+	if bestX < 0 {
+		return 1
+	}
+
+	count := 0
+	for _, d := range best {
+		if s.budget--; s.budget <= 0 {
+			break
+		}
+		s.values[bestY][bestX] = d
+		if !s.broken() {
+			count += s.search(limit - count)
+		}
+		if count >= limit {
+			break
+		}
+	}
+	s.values[bestY][bestX] = EmptyTile
+	return count
+}
+
+// candidates returns the digits that aren't elsewhere in a blank cell's row
+// or column.
+func (s *latinSolver) candidates(x, y int) []rune {
+	used := make(map[rune]bool)
+	for i := 0; i < s.size; i++ {
+		used[s.values[y][i]] = true
+		used[s.values[i][x]] = true
+	}
+	var digits []rune
+	for d := '1'; latinDigit(d, s.size); d++ {
+		if !used[d] {
+			digits = append(digits, d)
+		}
+	}
+	return digits
+}
+
+// broken reports whether the grid breaks a rule already.
+func (s *latinSolver) broken() bool {
+	broken, status := latinCheck(s.values, s.variant)
+	for _, st := range status {
+		if st == lineBroken {
+			return true
+		}
+	}
+	return anyBroken(broken)
+}
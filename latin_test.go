@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	testLatinSolution = "2413\n1324\n3241\n4132"
+	testLatinBlank    = "....\n....\n....\n...."
+)
+
+// noClues is a latin square with nothing added.
+type noClues struct{}
+
+func (noClues) check([][]rune, [][]bool) []lineStatus { return nil }
+
+func TestLatinCheck(t *testing.T) {
+	testCases := []struct {
+		name string
+		grid string
+		want string
+	}{
+		{name: "row", grid: "1..1\n....\n....\n....", want: "1001\n0000\n0000\n0000"},
+		{name: "column", grid: "..2.\n....\n....\n..2.", want: "0010\n0000\n0000\n0010"},
+		{name: "no boxes", grid: "3...\n.3..\n....\n....", want: "0000\n0000\n0000\n0000"},
+		{name: "digit too big", grid: "5..5\n....\n....\n....", want: "0000\n0000\n0000\n0000"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			broken, _ := latinCheck(takuzuGrid(tc.grid), noClues{})
+			var got []string
+			for _, row := range broken {
+				var b strings.Builder
+				for _, c := range row {
+					if c {
+						b.WriteByte('1')
+					} else {
+						b.WriteByte('0')
+					}
+				}
+				got = append(got, b.String())
+			}
+			if strings.Join(got, "\n") != tc.want {
+				t.Errorf("expected broken cells\n%s\ngot\n%s", tc.want, strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestLatinSize(t *testing.T) {
+	if n, err := latinSize(Level{Solution: testLatinSolution}); err != nil || n != 4 {
+		t.Errorf("expected size 4, got %d, %v", n, err)
+	}
+	if _, err := latinSize(Level{Solution: "12\n21\n12"}); err == nil || !strings.Contains(err.Error(), "square") {
+		t.Errorf("expected a non-square grid to be refused, got %v", err)
+	}
+}
+
+func TestCountLatinSolutions(t *testing.T) {
+	testCases := []struct {
+		name    string
+		initial string
+		want    int
+	}{
+		{name: "unique", initial: ".4..\n1...\n.2..\n4..2", want: 1},
+		{name: "blank", initial: testLatinBlank, want: 2},
+		{name: "clashing givens", initial: "1..1\n....\n....\n....", want: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := countLatinSolutions(Level{Initial: tc.initial, Solution: testLatinSolution}, noClues{}, 2)
+			if !ok || got != tc.want {
+				t.Errorf("expected %d solutions, got %d (ok %v)", tc.want, got, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// This file lays out grids whose state lives on the edges between cells as
+// well as in the cells, such as the loop of a slitherlink.
+//
+// A grid of w by h cells is kept as a lattice of 2w+1 by 2h+1 positions, so
+// the engine's Grid, saves and givens work for edges just as they do for
+// cells. Positions with both coordinates even are the points at the corners
+// of the cells and positions with both odd are the cells. The rest are the
+// edges between neighbouring points, running across when y is even and down
+// when x is even:
+//
+//	+-+-+
+//	|3|.|
+//	+-+-+
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// latticeKind is what sits at a position of a lattice.
+type latticeKind int
+
+const (
+	latticePoint latticeKind = iota
+	latticeCell
+	latticeEdgeAcross
+	latticeEdgeDown
+)
+
+// latticeKindAt returns what sits at a position of a lattice.
+func latticeKindAt(x, y int) latticeKind {
+	switch {
+	case x%2 == 0 && y%2 == 0:
+		return latticePoint
+	case x%2 == 1 && y%2 == 1:
+		return latticeCell
+	case y%2 == 0:
+		return latticeEdgeAcross
+	}
+	return latticeEdgeDown
+}
+
+// latticeIsEdge reports whether a position of a lattice is an edge.
+func latticeIsEdge(x, y int) bool {
+	k := latticeKindAt(x, y)
+	return k == latticeEdgeAcross || k == latticeEdgeDown
+}
+
+// latticeAround returns the four positions next to a position, in the order
+// up, right, down, left. Around a cell they are its edges, and around a
+// point they are the edges that meet there, some of which may be off the
+// lattice.
+func latticeAround(x, y int) [4][2]int {
+	return [4][2]int{{x, y - 1}, {x + 1, y}, {x, y + 1}, {x - 1, y}}
+}
+
+// latticeEnds returns the two points an edge joins.
+func latticeEnds(x, y int) [2][2]int {
+	if latticeKindAt(x, y) == latticeEdgeAcross {
+		return [2][2]int{{x - 1, y}, {x + 1, y}}
+	}
+	return [2][2]int{{x, y - 1}, {x, y + 1}}
+}
+
+// latticeMove steps the cursor from x, y by dx, dy within a lattice of
+// width by height positions. Points are stepped over, so the cursor moves
+// between edges and cells. It reports false when the step would leave the
+// lattice.
+func latticeMove(x, y, dx, dy, width, height int) (int, int, bool) {
+	nx, ny := x+dx, y+dy
+	if latticeKindAt(nx, ny) == latticePoint {
+		nx, ny = nx+dx, ny+dy
+	}
+	if nx < 0 || ny < 0 || nx >= width || ny >= height {
+		return x, y, false
+	}
+	return nx, ny, true
+}
+
+// latticeGrid reads a lattice from a level's grid text and checks its
+// shape: an odd number of rows and columns, at least three of each, with
+// point at every point.
+func latticeGrid(s string, point rune) ([][]rune, error) {
+	var values [][]rune
+	for _, row := range strings.Split(strings.Trim(s, "\n"), "\n") {
+		values = append(values, []rune(row))
+	}
+	h, w := len(values), len(values[0])
+	if h < 3 || w < 3 || h%2 == 0 || w%2 == 0 {
+		return nil, fmt.Errorf("a %dx%d lattice doesn't go round whole cells, want an odd number of rows and columns, at least 3 of each", w, h)
+	}
+	for y, row := range values {
+		if len(row) != w {
+			return nil, fmt.Errorf("row %d has %d positions, want %d", y+1, len(row), w)
+		}
+		for x := 0; x < w; x += 2 {
+			if y%2 == 0 && row[x] != point {
+				return nil, fmt.Errorf("row %d column %d should be a point %c", y+1, x+1, point)
+			}
+		}
+	}
+	return values, nil
+}
+
+// latticeCellAt maps a column and row of a drawn lattice to a position. Cells
+// and edges across are cellWidth wide, points and edges down one column.
+// Points aren't hit.
+func latticeCellAt(col, row, cellWidth, width, height int) (int, int, bool) {
+	if col < 0 || row < 0 || row >= height {
+		return 0, 0, false
+	}
+	step := cellWidth + 1
+	x := 2 * (col / step)
+	if col%step != 0 {
+		x++
+	}
+	if x >= width || latticeKindAt(x, row) == latticePoint {
+		return 0, 0, false
+	}
+	return x, row, true
+}
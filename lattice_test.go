@@ -0,0 +1,101 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLatticeKindAt(t *testing.T) {
+	testCases := []struct {
+		x, y int
+		want latticeKind
+	}{
+		{x: 0, y: 0, want: latticePoint},
+		{x: 2, y: 4, want: latticePoint},
+		{x: 1, y: 1, want: latticeCell},
+		{x: 1, y: 0, want: latticeEdgeAcross},
+		{x: 3, y: 2, want: latticeEdgeAcross},
+		{x: 0, y: 1, want: latticeEdgeDown},
+		{x: 2, y: 3, want: latticeEdgeDown},
+	}
+
+	for _, tc := range testCases {
+		if got := latticeKindAt(tc.x, tc.y); got != tc.want {
+			t.Errorf("%d,%d: expected kind %d, got %d", tc.x, tc.y, tc.want, got)
+		}
+	}
+}
+
+func TestLatticeMove(t *testing.T) {
+	// A lattice of 2 by 2 cells is 5 by 5 positions.
+	testCases := []struct {
+		name         string
+		x, y, dx, dy int
+		wantX, wantY int
+		wantOK       bool
+	}{
+		{name: "edge to cell", x: 0, y: 1, dx: 1, wantX: 1, wantY: 1, wantOK: true},
+		{name: "cell to edge", x: 1, y: 1, dy: 1, wantX: 1, wantY: 2, wantOK: true},
+		{name: "along edges across", x: 1, y: 0, dx: 1, wantX: 3, wantY: 0, wantOK: true},
+		{name: "along edges down", x: 0, y: 1, dy: 1, wantX: 0, wantY: 3, wantOK: true},
+		{name: "off a point", x: 0, y: 0, dx: 1, wantX: 1, wantY: 0, wantOK: true},
+		{name: "off the lattice", x: 3, y: 0, dx: 1, wantX: 3, wantY: 0},
+		{name: "off the top", x: 1, y: 0, dy: -1, wantX: 1, wantY: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			x, y, ok := latticeMove(tc.x, tc.y, tc.dx, tc.dy, 5, 5)
+			if x != tc.wantX || y != tc.wantY || ok != tc.wantOK {
+				t.Errorf("expected %d,%d %v, got %d,%d %v", tc.wantX, tc.wantY, tc.wantOK, x, y, ok)
+			}
+		})
+	}
+}
+
+func TestLatticeCellAt(t *testing.T) {
+	// Cells and edges across are 3 columns wide, points and edges down 1.
+	testCases := []struct {
+		col, row     int
+		wantX, wantY int
+		wantOK       bool
+	}{
+		{col: 0, row: 0},
+		{col: 1, row: 0, wantX: 1, wantY: 0, wantOK: true},
+		{col: 3, row: 0, wantX: 1, wantY: 0, wantOK: true},
+		{col: 4, row: 0},
+		{col: 4, row: 1, wantX: 2, wantY: 1, wantOK: true},
+		{col: 6, row: 1, wantX: 3, wantY: 1, wantOK: true},
+		{col: 9, row: 1},
+		{col: 1, row: 5},
+	}
+
+	for _, tc := range testCases {
+		x, y, ok := latticeCellAt(tc.col, tc.row, 3, 5, 5)
+		if x != tc.wantX || y != tc.wantY || ok != tc.wantOK {
+			t.Errorf("column %d row %d: expected %d,%d %v, got %d,%d %v", tc.col, tc.row, tc.wantX, tc.wantY, tc.wantOK, x, y, ok)
+		}
+	}
+}
+
+func TestLatticeGridErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		grid string
+		want string
+	}{
+		{name: "even", grid: "+.+.\n....", want: "odd number"},
+		{name: "too small", grid: "+", want: "odd number"},
+		{name: "ragged", grid: "+.+\n...\n+.", want: "row 3 has 2 positions"},
+		{name: "missing point", grid: "+.+\n...\n+..", want: "row 3 column 3 should be a point"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := latticeGrid(tc.grid, '+')
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected an error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
@@ -1,20 +1,108 @@
 package main
 
 import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
+	"sort"
 	"strings"
 )
 
 type Level struct {
-	ID       int    `yaml:"id" json:"id"`
-	Name     string `yaml:"name" json:"name"`
-	Author   string `yaml:"author" json:"author"`
-	Initial  string `yaml:"initial" json:"initial"`
-	Solution string `yaml:"solution" json:"solution"`
-	Engine   string `yaml:"engine" json:"engine"`
-	Width    int    `yaml:"width" json:"width"`
-	Height   int    `yaml:"height" json:"height"`
+	ID       int          `yaml:"id" json:"id" doc:"Id of the level when it was exported. Ignored on import."`
+	Name     string       `yaml:"name" json:"name" required:"true" doc:"Name of the level, unique within the pack."`
+	Author   string       `yaml:"author" json:"author" doc:"Who made the level."`
+	Initial  string       `yaml:"initial" json:"initial" doc:"Starting grid, one line per row. A dot is a blank cell and anything else is given."`
+	Solution string       `yaml:"solution" json:"solution" required:"true" doc:"Solved grid, one line per row, with dots for blank cells."`
+	Engine   string       `yaml:"engine" json:"engine" required:"true" doc:"Game engine that plays the level, such as nonogram."`
+	Width    int          `yaml:"width" json:"width" doc:"Width of the grid. Worked out from the initial grid on import."`
+	Height   int          `yaml:"height" json:"height" doc:"Height of the grid. Worked out from the initial grid on import."`
+	UUID     string       `yaml:"uuid,omitempty" json:"uuid,omitempty" doc:"Stable identity of the level, kept when its grids change. Derived from the engine and grids when not set."`
+	Options  LevelOptions `yaml:"options,omitempty" json:"options,omitempty" doc:"Engine specific rules for the level, such as wrap for lights out."`
+	Cells    string       `yaml:"cells,omitempty" json:"cells,omitempty" doc:"Grid of cells that hold several values, such as kakuro clues. One line per row, cells separated by spaces and the values in a cell by a backslash."`
+	Regions  string       `yaml:"regions,omitempty" json:"regions,omitempty" doc:"Grid naming the region each cell belongs to, one line per row and one character per cell, such as jigsaw sudoku regions."`
+	Edges    string       `yaml:"edges,omitempty" json:"edges,omitempty" doc:"Clues around the edge of the grid, such as skyscraper counts. Four lines for the top, right, bottom and left sides, each read from left to right or top to bottom, with clues separated by spaces and . for none."`
+	// Identity is the level's stable identity as stored, see LevelIdentity.
+	Identity string `yaml:"-" json:"-"`
+}
+
+// LevelOptions holds engine specific rules for a level. Engines document the
+// options they read and ignore the rest.
+type LevelOptions map[string]string
+
+// String formats the options as sorted key=value pairs.
+func (o LevelOptions) String() string {
+	var pairs []string
+	for k, v := range o {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
+
+// Value stores the options as JSON, or an empty string when there are none.
+func (o LevelOptions) Value() (driver.Value, error) {
+	if len(o) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(map[string]string(o))
+	return string(data), err
+}
+
+// Scan reads options stored by Value.
+func (o *LevelOptions) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("cannot scan %T into level options", src)
+	}
+	*o = nil
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, (*map[string]string)(o))
+}
+
+// LevelIdentity returns the identity saves are kept under: the level's uuid
+// if it has one, or else a uuid derived from its engine, initial state,
+// solution, options, cells, regions and edges. The same puzzle therefore has
+// the same identity in any pack and under any name.
+func (l Level) LevelIdentity() string {
+	if id := strings.ToLower(strings.TrimSpace(l.UUID)); id != "" {
+		return id
+	}
+	grid := func(s string) string {
+		if !engines[l.Engine].KeepDots {
+			s = strings.ReplaceAll(s, ".", " ")
+		}
+		return strings.Trim(s, "\n")
+	}
+	key := l.Engine + "\x00" + grid(l.Initial) + "\x00" + grid(l.Solution)
+	if len(l.Options) > 0 {
+		key += "\x00" + l.Options.String()
+	}
+	if cells := strings.TrimSpace(l.Cells); cells != "" {
+		key += "\x00cells=" + cells
+	}
+	if regions := strings.TrimSpace(l.Regions); regions != "" {
+		key += "\x00regions=" + regions
+	}
+	if edges := strings.TrimSpace(l.Edges); edges != "" {
+		key += "\x00edges=" + edges
+	}
+	sum := sha256.Sum256([]byte(key))
+	// Format the hash as a version 8 (custom) uuid.
+	sum[6] = sum[6]&0x0f | 0x80
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
 }
 
 func (l *Level) Validate() error {
@@ -53,12 +141,12 @@ func (l *Level) SetDimensions() {
 		l.Height = 0
 		return
 	}
-	lines := strings.Split(strings.TrimSpace(l.Initial), "\n")
+	// Rows can be ragged, as in sokoban boards, so the width is the widest.
+	lines := strings.Split(strings.Trim(l.Initial, "\n"), "\n")
 	l.Height = len(lines)
-	if l.Height > 0 {
-		l.Width = len(lines[0])
-	} else {
-		l.Width = 0
+	l.Width = 0
+	for _, line := range lines {
+		l.Width = max(l.Width, len(line))
 	}
 }
 
@@ -7,6 +7,10 @@ type LevelPack struct {
 	Author      string
 	Version     int
 	Description string
+	// Integrity is whether the pack was verified or unsigned on import.
+	Integrity string
+	// Signer names who signed a verified pack.
+	Signer string
 }
 
 func (lp LevelPack) FilterValue() string { return lp.Name }
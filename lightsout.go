@@ -0,0 +1,294 @@
+// This file implements the Lights Out game logic.
+//
+// The initial state is the board of lights, with LightOnTile marking a light
+// that is on. The primary action presses a cell, toggling it and its
+// orthogonal neighbours, and the puzzle is solved when every light is off.
+// The solution is not used, since the goal is always a dark board.
+//
+// Levels can change the rules with options:
+//
+//	wrap: "true"       presses wrap around the edges of the board
+//	pattern: |-        the cells a press toggles, centred on the pressed cell
+//	  .1.
+//	  111
+//	  .1.
+//
+// Every level has a par, the fewest presses that turn all the lights off,
+// worked out by lightsOutPar. Once a light has been pressed, the save holds
+// the number of presses on a line after the board, so the count carries on
+// when the level is opened again.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	LightOnTile  = rune('1')
+	LightOffTile = rune(' ')
+)
+
+var lightsOutGlyphs = map[string]GlyphSet{
+	unicodeGlyphs: {
+		LightOnTile:  "●",
+		LightOffTile: "○",
+	},
+	asciiGlyphs: {
+		LightOnTile:  "O",
+		LightOffTile: ".",
+	},
+	emojiGlyphs: {
+		LightOnTile:  "🟨",
+		LightOffTile: "⬛",
+	},
+}
+
+// defaultLightsOutPattern is the classic plus shaped press.
+const defaultLightsOutPattern = ".1.\n111\n.1."
+
+// lightsOutRules are the rules a level is played by, read from its options.
+type lightsOutRules struct {
+	w, h    int
+	wrap    bool
+	pattern [][2]int
+}
+
+// lightsOutRulesFor reads the rules of a level from its size and options.
+func lightsOutRulesFor(l Level) (lightsOutRules, error) {
+	l.SetDimensions()
+	r := lightsOutRules{w: l.Width, h: l.Height}
+	if v, ok := l.Options["wrap"]; ok {
+		wrap, err := strconv.ParseBool(v)
+		if err != nil {
+			return r, fmt.Errorf("invalid wrap option %q", v)
+		}
+		r.wrap = wrap
+	}
+
+	pattern := defaultLightsOutPattern
+	if v, ok := l.Options["pattern"]; ok {
+		pattern = v
+	}
+	rows := strings.Split(strings.Trim(pattern, "\n"), "\n")
+	ph, pw := len(rows), len(rows[0])
+	if ph%2 == 0 || pw%2 == 0 {
+		return r, errors.New("pattern must have an odd number of rows and columns")
+	}
+	for y, row := range rows {
+		if len(row) != pw {
+			return r, errors.New("pattern rows must all be the same width")
+		}
+		for x, c := range row {
+			if c != '.' && c != ' ' {
+				r.pattern = append(r.pattern, [2]int{x - pw/2, y - ph/2})
+			}
+		}
+	}
+	if len(r.pattern) == 0 {
+		return r, errors.New("pattern toggles nothing")
+	}
+	return r, nil
+}
+
+// targets returns the cells pressing (x, y) toggles. Cells off the board are
+// dropped, or wrap around when the level wraps. A cell is never listed twice,
+// even when a wrapped pattern is wider than the board.
+func (r lightsOutRules) targets(x, y int) [][2]int {
+	var cells [][2]int
+	seen := make(map[[2]int]bool)
+	for _, d := range r.pattern {
+		tx, ty := x+d[0], y+d[1]
+		if r.wrap {
+			tx, ty = (tx%r.w+r.w)%r.w, (ty%r.h+r.h)%r.h
+		} else if tx < 0 || tx >= r.w || ty < 0 || ty >= r.h {
+			continue
+		}
+		p := [2]int{tx, ty}
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		cells = append(cells, p)
+	}
+	return cells
+}
+
+type LightsOutEngine struct {
+	Engine
+	rules    lightsOutRules
+	par      int
+	parExact bool
+	solvable bool
+	moves    int
+	viewport gridViewport
+}
+
+func (e *LightsOutEngine) New(l Level, s *Save) (GameEngine, error) {
+	rules, err := lightsOutRulesFor(l)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := e.Engine.New(l, s); err != nil {
+		return nil, err
+	}
+
+	// Every light can be pressed, so none of them are given. The grid is
+	// read from the save rather than the initial state.
+	rows := strings.Split(e.Save.State, "\n")
+	for y := range e.Grid {
+		for x := range e.Grid[y] {
+			cell := &e.Grid[y][x]
+			cell.value, cell.state = LightOffTile, empty
+			if y < len(rows) && x < len(rows[y]) && rune(rows[y][x]) == LightOnTile {
+				cell.value, cell.state = LightOnTile, filled
+			}
+		}
+	}
+
+	if len(rows) > len(e.Grid) {
+		e.moves, _ = strconv.Atoi(rows[len(e.Grid)])
+	}
+
+	e.rules = rules
+	e.par, e.parExact, e.solvable = lightsOutPar(l.Initial, rules)
+	e.updateSolved()
+	e.viewport.fit(0, 0, e.cellWidth(), e.GetWidth(), e.GetHeight(), 0, 0)
+	return e, nil
+}
+
+// PrimaryAction presses a cell, toggling the lights in its pattern.
+func (e *LightsOutEngine) PrimaryAction(x, y int) error {
+	if !e.HasCell(x, y) {
+		return errors.New("coordinates out of bounds")
+	}
+	for _, p := range e.rules.targets(x, y) {
+		cell := &e.Grid[p[1]][p[0]]
+		if cell.value == LightOnTile {
+			cell.Clear()
+		} else {
+			cell.EnterValue(LightOnTile)
+		}
+	}
+	e.moves++
+	e.updateSaveState()
+	e.Save.State += "\n" + strconv.Itoa(e.moves)
+	e.updateSolved()
+	return nil
+}
+
+// SecondaryAction does nothing; the only move is a press.
+func (e *LightsOutEngine) SecondaryAction(x, y int) error {
+	return nil
+}
+
+// ClearCell does nothing, since a light can only be turned off by pressing.
+func (e *LightsOutEngine) ClearCell(x, y int) error {
+	return nil
+}
+
+// Evaluate reports whether every light is off.
+func (e *LightsOutEngine) Evaluate() (bool, error) {
+	for _, row := range e.Grid {
+		for _, cell := range row {
+			if cell.value == LightOnTile {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+func (e *LightsOutEngine) View(m model) string {
+	h := e.helpView(m)
+	e.viewport.fitTo(m, 0, lipgloss.Height(h), e.cellWidth(), e.GetWidth(), e.GetHeight())
+	return lipgloss.JoinVertical(lipgloss.Left, e.gridView(m), h)
+}
+
+// CellAt maps a position within the rendered view to grid coordinates.
+func (e *LightsOutEngine) CellAt(col, row int) (int, int, bool) {
+	return e.viewport.cellAt(col, row, e.cellWidth())
+}
+
+// GlyphSets returns the glyphs lights can be drawn with.
+func (e *LightsOutEngine) GlyphSets() map[string]GlyphSet {
+	return lightsOutGlyphs
+}
+
+// --- Private Functions ---
+
+// updateSolved refreshes the solved flag on the save.
+func (e *LightsOutEngine) updateSolved() {
+	solved, err := e.Evaluate()
+	e.Save.Solved = err == nil && solved
+}
+
+// cellWidth is wide enough for the glyphs with a space either side.
+func (e *LightsOutEngine) cellWidth() int {
+	return widestGlyph(glyphSetFor(lightsOutGlyphs)) + 2
+}
+
+// gridView draws the lights, marking the cells a press under the cursor
+// would toggle.
+func (e *LightsOutEngine) gridView(m model) string {
+	pressed := make(map[[2]int]bool)
+	for _, p := range e.rules.targets(m.cursorX, m.cursorY) {
+		pressed[p] = true
+	}
+	glyphs := glyphSetFor(lightsOutGlyphs)
+
+	v := e.viewport
+	var rows []string
+	for y := v.offsetY; y < v.offsetY+v.rows; y++ {
+		var cells []string
+		for x := v.offsetX; x < v.offsetX+v.cols; x++ {
+			value := e.Grid[y][x].value
+			s, ok := lightStyles[value]
+			if !ok {
+				s = lightStyles[LightOffTile]
+			}
+			if pressed[[2]int{x, y}] {
+				s = s.Inherit(crosshairStyle)
+			}
+			if x == m.cursorX && y == m.cursorY {
+				s = highlightStyle
+			}
+			cells = append(cells, s.Width(e.cellWidth()).AlignHorizontal(lipgloss.Center).Render(glyphs[value]))
+		}
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, cells...))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, rows...)
+}
+
+func (e *LightsOutEngine) helpView(m model) string {
+	help := "\nz: Press\n"
+	help += e.statusView()
+	help += "arrow keys or hjkl to move\n"
+	help += "Press 'esc' to return to the menu.\n"
+	if e.viewport.clipped(e.GetWidth(), e.GetHeight()) {
+		v := e.viewport
+		help += fmt.Sprintf("Showing columns %d-%d of %d, rows %d-%d of %d\n",
+			v.offsetX+1, v.offsetX+v.cols, e.GetWidth(),
+			v.offsetY+1, v.offsetY+v.rows, e.GetHeight())
+	}
+	if e.Save.Solved {
+		help += "Congrats!\n"
+	}
+	return help
+}
+
+// statusView compares the presses made so far with the level's par.
+func (e *LightsOutEngine) statusView() string {
+	switch {
+	case !e.solvable:
+		return fmt.Sprintf("Moves: %d\tPar: none, this board can't be solved\n", e.moves)
+	case !e.parExact:
+		return fmt.Sprintf("Moves: %d\tPar: %d or less\n", e.moves, e.par)
+	}
+	return fmt.Sprintf("Moves: %d\tPar: %d\n", e.moves, e.par)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// lightsOutMaxNullity caps how many free presses lightsOutPar tries every
+// combination of. Boards with more than this many ways to press without
+// changing anything get a par that may not be the lowest.
+const lightsOutMaxNullity = 20
+
+// gf2Vector is a vector over GF(2), packed 64 entries to a word.
+type gf2Vector []uint64
+
+func newGF2Vector(n int) gf2Vector {
+	return make(gf2Vector, (n+63)/64)
+}
+
+func (v gf2Vector) get(i int) bool {
+	return v[i/64]&(1<<(i%64)) != 0
+}
+
+func (v gf2Vector) flip(i int) {
+	v[i/64] ^= 1 << (i % 64)
+}
+
+func (v gf2Vector) xor(o gf2Vector) {
+	for i := range v {
+		v[i] ^= o[i]
+	}
+}
+
+func (v gf2Vector) count() int {
+	n := 0
+	for _, w := range v {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// lightsOutPar works out the fewest presses that turn every light off.
+// Pressing a cell twice undoes it and the order of presses doesn't matter,
+// so a solution is a set of cells to press: a vector x with A x = b over
+// GF(2), where column j of A is the lights press j toggles and b is the
+// lights that start on. Gaussian elimination finds one solution and a basis
+// of the presses that change nothing, and the lightest combination of them
+// is the par. exact is false when there were too many to try them all.
+func lightsOutPar(initial string, r lightsOutRules) (par int, exact bool, solvable bool) {
+	n := r.w * r.h
+	rows := make([]gf2Vector, n)
+	for i := range rows {
+		rows[i] = newGF2Vector(n + 1)
+	}
+	for y := 0; y < r.h; y++ {
+		for x := 0; x < r.w; x++ {
+			for _, t := range r.targets(x, y) {
+				rows[t[1]*r.w+t[0]].flip(y*r.w + x)
+			}
+		}
+	}
+	for y, row := range strings.Split(initial, "\n") {
+		for x, c := range row {
+			if c == LightOnTile && x < r.w && y < r.h {
+				rows[y*r.w+x].flip(n)
+			}
+		}
+	}
+
+	// Reduce to row echelon form, remembering each pivot's row.
+	pivotRow := make(map[int]int)
+	var free []int
+	rank := 0
+	for col := 0; col < n; col++ {
+		p := -1
+		for i := rank; i < n; i++ {
+			if rows[i].get(col) {
+				p = i
+				break
+			}
+		}
+		if p < 0 {
+			free = append(free, col)
+			continue
+		}
+		rows[rank], rows[p] = rows[p], rows[rank]
+		for i := range rows {
+			if i != rank && rows[i].get(col) {
+				rows[i].xor(rows[rank])
+			}
+		}
+		pivotRow[col] = rank
+		rank++
+	}
+	for i := rank; i < n; i++ {
+		if rows[i].get(n) {
+			return 0, true, false
+		}
+	}
+
+	// One solution leaves every free press unpressed.
+	x := newGF2Vector(n)
+	for col, i := range pivotRow {
+		if rows[i].get(n) {
+			x.flip(col)
+		}
+	}
+	if len(free) > lightsOutMaxNullity {
+		return x.count(), false, true
+	}
+
+	// Each free press, with the pivot presses that cancel it, changes
+	// nothing. Walk every combination of them in Gray code order.
+	basis := make([]gf2Vector, len(free))
+	for k, f := range free {
+		basis[k] = newGF2Vector(n)
+		basis[k].flip(f)
+		for col, i := range pivotRow {
+			if rows[i].get(f) {
+				basis[k].flip(col)
+			}
+		}
+	}
+	par = x.count()
+	for i := 1; i < 1<<len(free); i++ {
+		x.xor(basis[bits.TrailingZeros(uint(i))])
+		par = min(par, x.count())
+	}
+	return par, true, true
+}
+
+// countLightsOutSolutions reports a level as having one solution when its
+// lights can all be turned off, since the goal is always the same dark
+// board, and none otherwise.
+func countLightsOutSolutions(l Level, limit int) (int, bool) {
+	rules, err := lightsOutRulesFor(l)
+	if err != nil {
+		return 0, true
+	}
+	if _, _, solvable := lightsOutPar(l.Initial, rules); !solvable {
+		return 0, true
+	}
+	return min(1, limit), true
+}
+
+// validateLightsOut refuses levels whose rules are invalid or whose lights
+// can't all be turned off, and reports the par of the rest.
+func validateLightsOut(l Level) (string, error) {
+	rules, err := lightsOutRulesFor(l)
+	if err != nil {
+		return "", err
+	}
+	par, exact, solvable := lightsOutPar(l.Initial, rules)
+	switch {
+	case !solvable:
+		return "", errors.New("the lights can't all be turned off")
+	case !exact:
+		return fmt.Sprintf("par %d or less", par), nil
+	}
+	return fmt.Sprintf("par %d", par), nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// lightsOutLevel is a lights out level with the given board, to be turned
+// all the way off.
+func lightsOutLevel(initial string, options LevelOptions) Level {
+	return Level{
+		Engine:  "lightsout",
+		Initial: initial,
+		Solution: strings.Map(func(r rune) rune {
+			if r == '\n' {
+				return r
+			}
+			return LightOffTile
+		}, initial),
+		Options: options,
+	}
+}
+
+func TestLightsOutPress(t *testing.T) {
+	testCases := []struct {
+		name    string
+		options LevelOptions
+		x, y    int
+		want    string
+	}{
+		{name: "centre", x: 1, y: 1, want: " 1 \n111\n 1 "},
+		{name: "corner", x: 0, y: 0, want: "11 \n1  \n   "},
+		{name: "wrapped corner", options: LevelOptions{"wrap": "true"}, x: 0, y: 0, want: "111\n1  \n1  "},
+		{name: "custom pattern", options: LevelOptions{"pattern": "1.1\n.1.\n1.1"}, x: 1, y: 1, want: "1 1\n 1 \n1 1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newTestEngine(t, lightsOutLevel("   \n   \n   ", tc.options)).(*LightsOutEngine)
+			e.PrimaryAction(tc.x, tc.y)
+			if want := tc.want + "\n1"; e.Save.State != want {
+				t.Errorf("expected %q after pressing, got %q", want, e.Save.State)
+			}
+			if e.moves != 1 {
+				t.Errorf("expected 1 move, got %d", e.moves)
+			}
+		})
+	}
+}
+
+func TestLightsOutSolve(t *testing.T) {
+	e := newTestEngine(t, lightsOutLevel(" 1 \n111\n 1 ", nil)).(*LightsOutEngine)
+	if e.Save.Solved || e.par != 1 || !e.parExact {
+		t.Fatalf("expected an unsolved board with par 1, got par %d", e.par)
+	}
+	e.PrimaryAction(1, 1)
+	if !e.Save.Solved {
+		t.Errorf("expected pressing the centre to turn every light off, got %q", e.Save.State)
+	}
+
+	// The presses carry on counting when a save is loaded.
+	e = newTestEngine(t, lightsOutLevel(" 1 \n111\n 1 ", nil)).(*LightsOutEngine)
+	e.PrimaryAction(0, 0)
+	e.PrimaryAction(0, 0)
+	e = loadTestEngine(t, lightsOutLevel(" 1 \n111\n 1 ", nil), e.GetSave()).(*LightsOutEngine)
+	if e.moves != 2 || e.Grid[1][1].value != LightOnTile {
+		t.Fatalf("expected the board and 2 moves back, got %d moves in %q", e.moves, e.Save.State)
+	}
+	e.PrimaryAction(1, 1)
+	if !e.Save.Solved || !strings.Contains(e.statusView(), "Moves: 3") {
+		t.Errorf("expected the third press to solve the board, got %q", e.statusView())
+	}
+
+	// Lights turned off in a save stay off when it is loaded.
+	e = loadTestEngine(t, lightsOutLevel("1  \n   \n   ", nil), &Save{State: "   \n   \n   "}).(*LightsOutEngine)
+	if !e.Save.Solved {
+		t.Errorf("expected the saved board to load solved, got %q", e.Save.State)
+	}
+}
+
+func TestLightsOutPar(t *testing.T) {
+	testCases := []struct {
+		name         string
+		initial      string
+		options      LevelOptions
+		wantPar      int
+		wantSolvable bool
+	}{
+		{name: "dark board", initial: "   \n   \n   ", wantPar: 0, wantSolvable: true},
+		{name: "all on", initial: "111\n111\n111", wantPar: 5, wantSolvable: true},
+		{name: "single light", initial: "1 \n  ", wantPar: 3, wantSolvable: true},
+		{name: "unsolvable corner", initial: "1    \n     \n     \n     \n     ", wantSolvable: false},
+		{name: "free presses", initial: "11111\n1 1 1\n11 11\n1 1 1\n 111 ", wantPar: 9, wantSolvable: true},
+		{name: "wrapped", initial: " 1 \n111\n 1 ", options: LevelOptions{"wrap": "true"}, wantPar: 1, wantSolvable: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rules, err := lightsOutRulesFor(Level{Initial: tc.initial, Options: tc.options})
+			if err != nil {
+				t.Fatalf("invalid rules: %v", err)
+			}
+			par, exact, solvable := lightsOutPar(tc.initial, rules)
+			if solvable != tc.wantSolvable {
+				t.Fatalf("expected solvable %v, got %v", tc.wantSolvable, solvable)
+			}
+			if solvable && (par != tc.wantPar || !exact) {
+				t.Errorf("expected exact par %d, got %d (exact %v)", tc.wantPar, par, exact)
+			}
+		})
+	}
+}
+
+func TestLightsOutRulesErrors(t *testing.T) {
+	for _, options := range []LevelOptions{
+		{"wrap": "sometimes"},
+		{"pattern": "11\n11"},
+		{"pattern": "...\n...\n..."},
+	} {
+		if _, err := lightsOutRulesFor(Level{Initial: "  \n  ", Options: options}); err == nil {
+			t.Errorf("expected options %v to be refused", options)
+		}
+	}
+}
+
+func TestLightsOutDragPressesOnce(t *testing.T) {
+	e := newTestEngine(t, lightsOutLevel("   \n   \n   ", nil)).(*LightsOutEngine)
+	m := model{state: gameView, engine: e}
+	m.updateGameMouse(tea.MouseMsg{Y: viewHeaderHeight, Action: tea.MouseActionPress, Button: tea.MouseButtonLeft})
+	m.updateGameMouse(tea.MouseMsg{X: 2 * e.cellWidth(), Y: viewHeaderHeight, Action: tea.MouseActionMotion, Button: tea.MouseButtonLeft})
+	if want := "11 \n1  \n   \n1"; e.Save.State != want {
+		t.Errorf("expected a drag to press only the first light, got %q", e.Save.State)
+	}
+}
@@ -1,14 +1,21 @@
 package main
 
 import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"slices"
 	"strings"
+	"text/tabwriter"
 
 	"gopkg.in/yaml.v3"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 	"github.com/spf13/cobra"
 )
 
@@ -25,7 +32,7 @@ It uses a plain text, YAML-based format for creating and sharing level packs, ma
 
 		m := NewModel(store)
 
-		p := tea.NewProgram(&m)
+		p := tea.NewProgram(&m, tea.WithMouseCellMotion())
 		if _, err := p.Run(); err != nil {
 			log.Fatalf("event=\"tui_failed\" err=\"%v\"", err)
 		}
@@ -34,15 +41,60 @@ It uses a plain text, YAML-based format for creating and sharing level packs, ma
 
 var exportCmd = &cobra.Command{
 	Use:   "export",
-	Short: "Export a level pack to a YAML file. This is useful for sharing level packs with others.",
-	Long: `Export a level pack to a YAML file. This is useful for sharing level packs with others.
-The exported file can be imported by other users using the import command.`,
+	Short: "Export a level pack to a YAML or JSON file. This is useful for sharing level packs with others.",
+	Long: `Export a level pack to a YAML or JSON file. This is useful for sharing level packs with others.
+The exported file can be imported by other users using the import command.
+Without --pack a level pack is picked interactively.
+With --bundle every installed pack, with its assets, is written to one .zip or .tar.gz archive.`,
+	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		store, err := NewStore("chronical.db")
 		if err != nil {
 			log.Fatalf("unable to init store: %v", err)
 		}
 
+		packRef, _ := cmd.Flags().GetString("pack")
+		bundle, _ := cmd.Flags().GetBool("bundle")
+		if bundle {
+			if packRef != "" {
+				log.Fatalf("--pack and --bundle can't be used together")
+			}
+			out, _ := cmd.Flags().GetString("out")
+			force, _ := cmd.Flags().GetBool("force")
+			if out == "" {
+				out = "levelpacks.zip"
+			}
+			if err := store.ExportBundle(out, force); err != nil {
+				log.Fatalf("unable to export bundle: %v", err)
+			}
+			fmt.Printf("Level packs exported to %s\n", out)
+			return
+		}
+		if packRef != "" {
+			out, _ := cmd.Flags().GetString("out")
+			format, _ := cmd.Flags().GetString("format")
+			force, _ := cmd.Flags().GetBool("force")
+			if format != "" && format != formatYAML && format != formatJSON {
+				log.Fatalf("unknown export format %q", format)
+			}
+
+			pack, err := store.FindLevelPack(packRef)
+			if err != nil {
+				log.Fatalf("unable to find level pack: %v", err)
+			}
+			if out == "" {
+				if format == "" {
+					format = formatYAML
+				}
+				out = safeFileName(pack.Name) + "." + format
+			}
+			if err := store.ExportLevelPackFile(pack.ID, out, ExportOptions{Format: format, Force: force}); err != nil {
+				log.Fatalf("unable to export level pack: %v", err)
+			}
+			fmt.Printf("Level pack %s exported to %s\n", pack.Name, out)
+			return
+		}
+
 		m := NewModel(store)
 		m.state = exportView
 
@@ -54,10 +106,16 @@ The exported file can be imported by other users using the import command.`,
 }
 
 var importCmd = &cobra.Command{
-	Use:   "import [path]",
-	Short: "Import a level pack from a YAML file. This is useful for playing level packs created by others.",
-	Long: `Import a level pack from a YAML file. This is useful for playing level packs created by others.
-The imported file will be added to your library of level packs.`,
+	Use:   "import [path or url]",
+	Short: "Import a level pack from a YAML or JSON file, or a bundle of packs. This is useful for playing level packs created by others.",
+	Long: `Import a level pack from a YAML or JSON file, or a bundle of packs. This is useful for playing level packs created by others.
+The format is taken from the file extension, or from the content when there isn't one.
+A sokoban collection in a .sok or .txt file is imported as a pack of sokoban levels.
+A bundle is a directory, .zip or .tar.gz archive holding several packs and their assets,
+listed in a manifest.yaml. Without a manifest every YAML and JSON file in it is imported.
+All packs in a bundle are imported together, or not at all.
+An http or https URL is downloaded first; pass --sha256 to check it against a checksum.
+The imported packs will be added to your library of level packs.`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		store, err := NewStore("chronical.db")
@@ -65,16 +123,377 @@ The imported file will be added to your library of level packs.`,
 			log.Fatalf("unable to init store: %v", err)
 		}
 
-		if err := store.ImportLevelPack(args[0]); err != nil {
+		var packs []*LevelPack
+		switch {
+		case isURL(args[0]):
+			sum, _ := cmd.Flags().GetString("sha256")
+			packs, err = store.ImportURL(args[0], sum)
+		case isBundle(args[0]):
+			packs, err = store.ImportBundle(args[0])
+		default:
+			var pack *LevelPack
+			pack, err = store.ImportLevelPackFile(args[0])
+			packs = append(packs, pack)
+		}
+		if err != nil {
 			log.Fatalf("unable to import level pack: %v", err)
 		}
 
-		fmt.Printf("Level pack imported from %s\n", args[0])
+		for _, pack := range packs {
+			fmt.Printf("Level pack %s imported from %s (%s)\n", pack.Name, args[0], packIntegrity(*pack))
+			duplicates, err := store.DuplicateLevels(pack.ID)
+			if err != nil {
+				log.Fatalf("unable to check for duplicate levels: %v", err)
+			}
+			for _, d := range duplicates {
+				fmt.Printf("  %s is the same level as %s in %s\n", d.Level, d.OtherLevel, d.OtherPack)
+			}
+			levels, err := store.GetLevelsByPack(pack.ID)
+			if err != nil {
+				log.Fatalf("unable to get levels: %v", err)
+			}
+			for _, level := range levels {
+				if note, _ := validateLevel(level); note != "" {
+					fmt.Printf("  %s: %s\n", level.Name, note)
+				}
+			}
+		}
 	},
 }
 
-var testImportCmd = &cobra.Command{
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema for level pack files.",
+	Long: `Print the JSON Schema for level pack files.
+Point your editor at it to validate YAML or JSON level packs as you write them.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		data, err := json.MarshalIndent(levelPackSchema(), "", "  ")
+		if err != nil {
+			log.Fatalf("unable to marshal schema: %v", err)
+		}
+		fmt.Println(string(data))
+	},
+}
+
+var keygenCmd = &cobra.Command{
+	Use:   "keygen [name]",
+	Short: "Generate an ed25519 key for signing level packs.",
+	Long: `Generate an ed25519 key for signing level packs.
+The key is saved as name.key in the keys directory next to the config file, and its
+public key is printed. Share the public key so others can add it to their trusted keys.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := "default"
+		if len(args) > 0 {
+			name = args[0]
+		}
+		path, err := keyPath(name)
+		if err != nil {
+			log.Fatalf("unable to find keys directory: %v", err)
+		}
+		pub, err := GenerateKey(path)
+		if err != nil {
+			log.Fatalf("unable to generate key: %v", err)
+		}
+		fmt.Printf("Key saved to %s\n", path)
+		fmt.Printf("Public key: %s\n", base64.StdEncoding.EncodeToString(pub))
+	},
+}
+
+var signCmd = &cobra.Command{
+	Use:   "sign [path]",
+	Short: "Sign a level pack file with one of your keys.",
+	Long: `Sign a level pack file with one of your keys.
+The pack's content hash, signature and public key are written back into the file.
+Sign a pack last, after every other change, since any change afterwards marks it as tampered.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		keyRef, _ := cmd.Flags().GetString("key")
+		path, err := keyPath(keyRef)
+		if err != nil {
+			log.Fatalf("unable to find keys directory: %v", err)
+		}
+		key, err := LoadKey(path)
+		if err != nil {
+			log.Fatalf("unable to load key %s: %v", path, err)
+		}
+
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			log.Fatalf("unable to read level pack: %v", err)
+		}
+		format := detectFormat(args[0], data)
+		levelPackYAML, err := DecodeLevelPack(data, format)
+		if err != nil {
+			log.Fatalf("unable to parse level pack: %v", err)
+		}
+		if err := SignLevelPack(levelPackYAML, key); err != nil {
+			log.Fatalf("unable to sign level pack: %v", err)
+		}
+		data, err = encodeLevelPackYAML(levelPackYAML, format)
+		if err != nil {
+			log.Fatalf("unable to encode level pack: %v", err)
+		}
+		if err := writeFileAtomic(args[0], data, true); err != nil {
+			log.Fatalf("unable to write level pack: %v", err)
+		}
+		fmt.Printf("Signed %s (%s)\n", args[0], levelPackYAML.Hash)
+	},
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [path]",
+	Short: "Check whether a level pack file is verified, unsigned or tampered with.",
+	Long: `Check whether a level pack file is verified, unsigned or tampered with.
+Exits with an error if the pack is tampered with or the trust policy would refuse it.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			log.Fatalf("unable to read level pack: %v", err)
+		}
+		levelPackYAML, err := DecodeLevelPackFile(args[0], data)
+		if err != nil {
+			log.Fatalf("unable to parse level pack: %v", err)
+		}
+		verification := VerifyLevelPack(levelPackYAML, activeTrust)
+		fmt.Printf("%s: %s\n", args[0], verification)
+		if err := activeTrust.allow(verification); err != nil {
+			fmt.Fprintf(os.Stderr, "refused: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var savesCmd = &cobra.Command{
+	Use:   "saves",
+	Short: "Move your progress between machines.",
+}
+
+var savesExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export your progress on every level to a JSON file.",
+	Long: `Export your progress on every level to a JSON file.
+Saves are keyed by pack and level name, so the file can be imported into another library
+that has the same packs installed.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		store, err := NewStore("chronical.db")
+		if err != nil {
+			log.Fatalf("unable to init store: %v", err)
+		}
+
+		out, _ := cmd.Flags().GetString("out")
+		force, _ := cmd.Flags().GetBool("force")
+		export, err := store.ExportSaves()
+		if err != nil {
+			log.Fatalf("unable to export saves: %v", err)
+		}
+		data, err := json.MarshalIndent(export, "", "  ")
+		if err != nil {
+			log.Fatalf("unable to marshal saves: %v", err)
+		}
+		if err := writeFileAtomic(out, append(data, '\n'), force); err != nil {
+			log.Fatalf("unable to write saves: %v", err)
+		}
+		fmt.Printf("%d save(s) exported to %s\n", len(export.Saves), out)
+	},
+}
+
+var savesImportCmd = &cobra.Command{
 	Use:   "import [path]",
+	Short: "Import progress exported from another machine.",
+	Long: `Import progress exported from another machine.
+A solved level stays solved whichever machine solved it. Otherwise the most recently
+updated save wins. The changes are shown before anything is written.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		store, err := NewStore("chronical.db")
+		if err != nil {
+			log.Fatalf("unable to init store: %v", err)
+		}
+
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			log.Fatalf("unable to read saves: %v", err)
+		}
+		export, err := DecodeSaveExport(data)
+		if err != nil {
+			log.Fatalf("unable to parse saves: %v", err)
+		}
+		changes, err := store.PlanSaveImport(export)
+		if err != nil {
+			log.Fatalf("unable to plan save import: %v", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ACTION\tPACK\tLEVEL\tSOLVED\tREASON")
+		pending := 0
+		for _, change := range changes {
+			if change.Action == syncAdd || change.Action == syncUpdate {
+				pending++
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\n", change.Action, change.Save.Pack, change.Save.Level, change.Save.Solved, change.Reason)
+		}
+		w.Flush()
+
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if dryRun || pending == 0 {
+			fmt.Printf("%d save(s) would change.\n", pending)
+			return
+		}
+		yes, _ := cmd.Flags().GetBool("yes")
+		if !yes {
+			fmt.Printf("Apply %d change(s)? [y/N] ", pending)
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+				fmt.Println("Aborted.")
+				return
+			}
+		}
+
+		if err := store.ApplySaveImport(changes); err != nil {
+			log.Fatalf("unable to import saves: %v", err)
+		}
+		fmt.Printf("%d save(s) imported from %s\n", pending, args[0])
+	},
+}
+
+var packCmd = &cobra.Command{
+	Use:   "pack",
+	Short: "Manage the level packs in your library.",
+}
+
+var packListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the level packs in your library.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		store, err := NewStore("chronical.db")
+		if err != nil {
+			log.Fatalf("unable to init store: %v", err)
+		}
+
+		packs, err := store.GetAllLevelPacks()
+		if err != nil {
+			log.Fatalf("unable to list level packs: %v", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tAUTHOR\tVERSION\tSOLVED")
+		for _, pack := range packs {
+			total, solved, err := store.CountLevelsByPack(pack.ID)
+			if err != nil {
+				log.Fatalf("unable to count levels: %v", err)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%d/%d\n", pack.ID, pack.Name, pack.Author, pack.Version, solved, total)
+		}
+		w.Flush()
+	},
+}
+
+var packShowCmd = &cobra.Command{
+	Use:   "show [pack]",
+	Short: "Show a level pack and its levels. The pack can be given by id or name.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		store, err := NewStore("chronical.db")
+		if err != nil {
+			log.Fatalf("unable to init store: %v", err)
+		}
+
+		pack, err := store.FindLevelPack(args[0])
+		if err != nil {
+			log.Fatalf("unable to find level pack: %v", err)
+		}
+		levels, err := store.GetLevelsByPack(pack.ID)
+		if err != nil {
+			log.Fatalf("unable to get levels: %v", err)
+		}
+		var levelIDs []int
+		for _, level := range levels {
+			levelIDs = append(levelIDs, level.ID)
+		}
+		indicators, err := store.GetSaveIndicators(levelIDs)
+		if err != nil {
+			log.Fatalf("unable to get saves: %v", err)
+		}
+
+		fmt.Printf("%s by %s (version %d, %s)\n", pack.Name, pack.Author, pack.Version, packIntegrity(*pack))
+		if pack.Description != "" {
+			fmt.Println(pack.Description)
+		}
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SAVE\tNAME\tENGINE\tSIZE")
+		for _, level := range levels {
+			level.SetDimensions()
+			fmt.Fprintf(w, "%s\t%s\t%s\t%dx%d\n", indicators[level.ID], level.Name, level.Engine, level.Width, level.Height)
+		}
+		w.Flush()
+	},
+}
+
+var packDeleteCmd = &cobra.Command{
+	Use:   "delete [pack]",
+	Short: "Delete a level pack along with its levels and saves. The pack can be given by id or name.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		store, err := NewStore("chronical.db")
+		if err != nil {
+			log.Fatalf("unable to init store: %v", err)
+		}
+
+		pack, err := store.FindLevelPack(args[0])
+		if err != nil {
+			log.Fatalf("unable to find level pack: %v", err)
+		}
+
+		yes, _ := cmd.Flags().GetBool("yes")
+		if !yes {
+			total, _, err := store.CountLevelsByPack(pack.ID)
+			if err != nil {
+				log.Fatalf("unable to count levels: %v", err)
+			}
+			fmt.Printf("Delete %s and its %d level(s) and saves? [y/N] ", pack.Name, total)
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+				fmt.Println("Aborted.")
+				return
+			}
+		}
+
+		if err := store.DeleteLevelPack(pack.ID); err != nil {
+			log.Fatalf("unable to delete level pack: %v", err)
+		}
+		fmt.Printf("Deleted level pack %s\n", pack.Name)
+	},
+}
+
+var packRenameCmd = &cobra.Command{
+	Use:   "rename [pack] [new-name]",
+	Short: "Rename a level pack. The pack can be given by id or name.",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		store, err := NewStore("chronical.db")
+		if err != nil {
+			log.Fatalf("unable to init store: %v", err)
+		}
+
+		pack, err := store.FindLevelPack(args[0])
+		if err != nil {
+			log.Fatalf("unable to find level pack: %v", err)
+		}
+		if err := store.RenameLevelPack(pack.ID, args[1]); err != nil {
+			log.Fatalf("unable to rename level pack: %v", err)
+		}
+		fmt.Printf("Renamed level pack %s to %s\n", pack.Name, args[1])
+	},
+}
+
+var testImportCmd = &cobra.Command{
+	Use:   "import [path or url]",
 	Short: "Test importing and exporting a level pack to ensure that the process is working correctly.",
 	Long: `Test importing and exporting a level pack to ensure that the process is working correctly.
 This command is useful for developers who want to test the import/export functionality.`,
@@ -86,6 +505,25 @@ This command is useful for developers who want to test the import/export functio
 		}
 
 		// Import the level pack
+		if isURL(args[0]) {
+			sum, _ := cmd.Flags().GetString("sha256")
+			packs, err := store.ImportURL(args[0], sum)
+			if err != nil {
+				log.Fatalf("unable to import from url: %v", err)
+			}
+			fmt.Printf("%d level packs imported from %s\n", len(packs), args[0])
+			return
+		}
+
+		if isBundle(args[0]) {
+			packs, err := store.ImportBundle(args[0])
+			if err != nil {
+				log.Fatalf("unable to import bundle: %v", err)
+			}
+			fmt.Printf("%d level packs imported from %s\n", len(packs), args[0])
+			return
+		}
+
 		if err := store.ImportLevelPack(args[0]); err != nil {
 			log.Fatalf("unable to import level pack: %v", err)
 		}
@@ -194,13 +632,11 @@ You can use this command to see how a level will look without having to play it.
 			State: initialState,
 		}
 
-		var engine GameEngine
-		switch engineName {
-		case "nonogram":
-			engine = &NonogramEngine{}
-		default:
+		info, ok := engines[engineName]
+		if !ok {
 			log.Fatalf("unknown engine: %s", engineName)
 		}
+		engine := info.New()
 
 		game, err := engine.New(level, save)
 		if err != nil {
@@ -216,7 +652,75 @@ You can use this command to see how a level will look without having to play it.
 	},
 }
 
+// userConfig holds the settings read from the config file by initConfig.
+var userConfig = &Config{}
+
+// initConfig loads the config file. A config that can't be read is logged
+// and the defaults are used instead.
+func initConfig() {
+	path, err := configPath()
+	if err != nil {
+		log.Printf("event=\"config_path_failed\" err=\"%v\"", err)
+		return
+	}
+	config, err := LoadConfig(path)
+	if err != nil {
+		log.Printf("event=\"config_load_failed\" path=\"%s\" err=\"%v\"", path, err)
+		return
+	}
+	userConfig = config
+}
+
+// initTheme applies the theme chosen by flag or config file. NO_COLOR turns
+// colour off entirely, leaving the text attribute fallbacks.
+func initTheme() {
+	if os.Getenv("NO_COLOR") != "" {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+
+	config := userConfig
+	name, _ := rootCmd.PersistentFlags().GetString("theme")
+	if name == "" {
+		name = config.Theme
+	}
+	if name == "" {
+		name = defaultThemeName
+	}
+	theme, err := findTheme(name, config.Themes)
+	if err != nil {
+		log.Printf("event=\"theme_not_found\" theme=\"%s\" err=\"%v\"", name, err)
+		theme = builtinThemes[defaultThemeName]
+	}
+	applyTheme(theme)
+}
+
+// initTrust applies the pack signing policy from the config file.
+func initTrust() {
+	activeTrust = userConfig.Trust
+}
+
+// initGlyphs picks the glyph set chosen by flag or config file, or detects
+// one from the locale.
+func initGlyphs() {
+	name, _ := rootCmd.PersistentFlags().GetString("glyphs")
+	if name == "" {
+		name = userConfig.Glyphs
+	}
+	if name == "" {
+		name = envGlyphSet()
+	}
+	if !slices.Contains(glyphSetNames(), name) {
+		log.Printf("event=\"glyph_set_not_found\" glyphs=\"%s\"", name)
+		name = envGlyphSet()
+	}
+	activeGlyphSet = name
+}
+
 func init() {
+	cobra.OnInitialize(initConfig, initTheme, initGlyphs, initTrust)
+	rootCmd.PersistentFlags().String("glyphs", "", fmt.Sprintf("Glyph set to draw with (%s). Detected from the locale by default.", strings.Join(glyphSetNames(), ", ")))
+	rootCmd.PersistentFlags().String("theme", "", fmt.Sprintf("Colour theme to use (%s, or one defined in the config file).", strings.Join(themeNames(), ", ")))
+
 	renderCmd.Flags().String("engine", "nonogram", "The engine to use for rendering.")
 	renderCmd.Flags().String("initial", "", "The initial state of the grid.")
 	renderCmd.Flags().String("save", "", "The save state of the grid.")
@@ -224,10 +728,36 @@ func init() {
 	testCmd.AddCommand(renderCmd)
 	testCmd.AddCommand(testImportCmd)
 	testImportCmd.Flags().BoolP("help", "h", false, "Help message for the test import command")
+	testImportCmd.Flags().String("sha256", "", "Expected SHA-256 checksum of a downloaded pack, in hex.")
 	testCmd.PersistentFlags().Bool("log-stdout", false, "Write logs to stdout instead of a file.")
 
+	packDeleteCmd.Flags().BoolP("yes", "y", false, "Delete without asking for confirmation.")
+	packCmd.AddCommand(packListCmd)
+	packCmd.AddCommand(packShowCmd)
+	packCmd.AddCommand(packDeleteCmd)
+	packCmd.AddCommand(packRenameCmd)
+
+	exportCmd.Flags().String("pack", "", "Id or name of the level pack to export. Exports without opening the TUI.")
+	exportCmd.Flags().String("out", "", "Path to write to. Defaults to the pack name, or levelpacks.zip with --bundle, in the current directory.")
+	exportCmd.Flags().Bool("bundle", false, "Export every installed pack to one .zip or .tar.gz archive.")
+	exportCmd.Flags().String("format", "", "Format to write, yaml or json. Defaults to the --out extension, or yaml.")
+	exportCmd.Flags().Bool("force", false, "Overwrite the output file if it exists.")
 	rootCmd.AddCommand(exportCmd)
+	importCmd.Flags().String("sha256", "", "Expected SHA-256 checksum of a downloaded pack, in hex.")
 	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(packCmd)
+	savesExportCmd.Flags().String("out", "chronical-saves.json", "Path to write to.")
+	savesExportCmd.Flags().Bool("force", false, "Overwrite the output file if it exists.")
+	savesImportCmd.Flags().Bool("dry-run", false, "Only show what would change.")
+	savesImportCmd.Flags().BoolP("yes", "y", false, "Apply the changes without asking.")
+	savesCmd.AddCommand(savesExportCmd)
+	savesCmd.AddCommand(savesImportCmd)
+	rootCmd.AddCommand(savesCmd)
+	rootCmd.AddCommand(schemaCmd)
+	signCmd.Flags().String("key", "default", "Name of the key in the keys directory, or a path to a key file.")
+	rootCmd.AddCommand(keygenCmd)
+	rootCmd.AddCommand(signCmd)
+	rootCmd.AddCommand(verifyCmd)
 	rootCmd.AddCommand(testCmd)
 }
 
@@ -0,0 +1,409 @@
+// This file implements the Minesweeper game logic.
+//
+// The level's solution is the mine layout, with MineTile marking a mine and
+// anything else a safe cell. The grid tracks what the player has uncovered:
+// hidden cells, revealed cells (RevealedTile) and flags (FlagTile). The
+// numbers on revealed cells are worked out from the solution.
+//
+// The primary action reveals a hidden cell, flooding outwards from cells with
+// no neighbouring mines. On a revealed number whose mines are all flagged it
+// chords, revealing the rest of its neighbours. The secondary action toggles
+// a flag. Revealing a mine marks it with ExplodedTile and loses the game,
+// which can then be restarted.
+//
+// Cells revealed in the initial state are where the player starts. A level
+// that reveals nothing starts on the first cell with no neighbouring mines,
+// opened for the player when the level is loaded.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	MineTile     = rune('*')
+	RevealedTile = rune('o')
+	FlagTile     = rune('F')
+	ExplodedTile = rune('!')
+	HiddenTile   = rune(' ')
+)
+
+var minesweeperGlyphs = map[string]GlyphSet{
+	unicodeGlyphs: mineCountGlyphs(GlyphSet{
+		HiddenTile:   "■",
+		FlagTile:     "⚑",
+		MineTile:     "✱",
+		ExplodedTile: "✹",
+	}, " "),
+	asciiGlyphs: mineCountGlyphs(GlyphSet{
+		HiddenTile:   "#",
+		FlagTile:     "F",
+		MineTile:     "*",
+		ExplodedTile: "X",
+	}, "."),
+	emojiGlyphs: mineCountGlyphs(GlyphSet{
+		HiddenTile:   "🟦",
+		FlagTile:     "🚩",
+		MineTile:     "💣",
+		ExplodedTile: "💥",
+	}, " "),
+}
+
+// mineCountGlyphs adds the glyphs revealed cells are drawn with, showing
+// their mine count, to a glyph set.
+func mineCountGlyphs(set GlyphSet, zero string) GlyphSet {
+	set['0'] = zero
+	for n := 1; n <= 8; n++ {
+		set[rune('0'+n)] = fmt.Sprint(n)
+	}
+	return set
+}
+
+type MinesweeperEngine struct {
+	Engine
+	mines    [][]bool
+	counts   [][]int
+	zoom     int
+	viewport gridViewport
+}
+
+func (e *MinesweeperEngine) New(l Level, s *Save) (GameEngine, error) {
+	fresh := s == nil
+	_, err := e.Engine.New(l, s)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mines, e.counts = mineLayout(l.Solution, e.GetWidth(), e.GetHeight())
+	if fresh && !e.anyRevealed() {
+		if x, y, ok := minesweeperStart(e.mines, e.counts); ok {
+			e.reveal(x, y)
+		}
+	}
+	e.updateSolved()
+	e.viewport.fit(0, 0, e.cellWidth(), e.GetWidth(), e.GetHeight(), 0, 0)
+
+	return e, nil
+}
+
+// PrimaryAction reveals a hidden cell, or chords on a revealed one.
+func (e *MinesweeperEngine) PrimaryAction(x, y int) error {
+	if !e.HasCell(x, y) {
+		return errors.New("coordinates out of bounds")
+	}
+	if e.Outcome() == outcomeLost {
+		return nil
+	}
+	switch e.Grid[y][x].value {
+	case HiddenTile:
+		e.reveal(x, y)
+	case RevealedTile:
+		e.chord(x, y)
+	}
+	e.updateSolved()
+	return nil
+}
+
+// SecondaryAction toggles a flag on a hidden cell.
+func (e *MinesweeperEngine) SecondaryAction(x, y int) error {
+	if !e.HasCell(x, y) {
+		return errors.New("coordinates out of bounds")
+	}
+	if e.Outcome() == outcomeLost {
+		return nil
+	}
+	var err error
+	switch e.Grid[y][x].value {
+	case HiddenTile:
+		err = e.setCellValue(x, y, FlagTile)
+	case FlagTile:
+		err = e.Engine.ClearCell(x, y)
+	}
+	e.updateSolved()
+	return err
+}
+
+// ClearCell removes a flag. Revealed cells can't be hidden again.
+func (e *MinesweeperEngine) ClearCell(x, y int) error {
+	if !e.HasCell(x, y) {
+		return errors.New("coordinates out of bounds")
+	}
+	if e.Grid[y][x].value != FlagTile {
+		return nil
+	}
+	err := e.Engine.ClearCell(x, y)
+	e.updateSolved()
+	return err
+}
+
+// Evaluate reports whether every safe cell has been revealed without setting
+// off a mine. Flags don't matter.
+func (e *MinesweeperEngine) Evaluate() (bool, error) {
+	for y, row := range e.Grid {
+		for x, cell := range row {
+			if cell.value == ExplodedTile {
+				return false, nil
+			}
+			if !e.mines[y][x] && cell.value != RevealedTile {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// Outcome reports a loss once a mine has been revealed.
+func (e *MinesweeperEngine) Outcome() Outcome {
+	for _, row := range e.Grid {
+		for _, cell := range row {
+			if cell.value == ExplodedTile {
+				return outcomeLost
+			}
+		}
+	}
+	return e.Engine.Outcome()
+}
+
+func (e *MinesweeperEngine) View(m model) string {
+	h := e.helpView(m)
+	e.viewport.fitTo(m, 0, lipgloss.Height(h), e.cellWidth(), e.GetWidth(), e.GetHeight())
+	return lipgloss.JoinVertical(lipgloss.Left, e.gridView(m), h)
+}
+
+// CellAt maps a position within the rendered view to grid coordinates.
+func (e *MinesweeperEngine) CellAt(col, row int) (int, int, bool) {
+	return e.viewport.cellAt(col, row, e.cellWidth())
+}
+
+// GlyphSets returns the glyphs minesweeper tiles can be drawn with.
+func (e *MinesweeperEngine) GlyphSets() map[string]GlyphSet {
+	return minesweeperGlyphs
+}
+
+// Zoom steps through the cell widths, as for nonograms.
+func (e *MinesweeperEngine) Zoom(step int) {
+	e.zoom = zoomBy(e.zoom, step)
+}
+
+// --- Private Functions ---
+
+// mineLayout reads the mines from a solution and counts the mines around
+// every cell.
+func mineLayout(solution string, w, h int) ([][]bool, [][]int) {
+	rows := strings.Split(solution, "\n")
+	mines := make([][]bool, h)
+	for y := range mines {
+		mines[y] = make([]bool, w)
+		for x := range mines[y] {
+			mines[y][x] = y < len(rows) && x < len(rows[y]) && rune(rows[y][x]) == MineTile
+		}
+	}
+	counts := make([][]int, h)
+	for y := range counts {
+		counts[y] = make([]int, w)
+		for x := range counts[y] {
+			forNeighbours(x, y, w, h, func(nx, ny int) {
+				if mines[ny][nx] {
+					counts[y][x]++
+				}
+			})
+		}
+	}
+	return mines, counts
+}
+
+// forNeighbours calls fn for each of the up to eight cells around (x, y).
+func forNeighbours(x, y, w, h int, fn func(nx, ny int)) {
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			nx, ny := x+dx, y+dy
+			if (dx != 0 || dy != 0) && nx >= 0 && nx < w && ny >= 0 && ny < h {
+				fn(nx, ny)
+			}
+		}
+	}
+}
+
+// minesweeperStart picks the cell a level with nothing revealed starts on:
+// the first safe cell with no neighbouring mines, or failing that the first
+// safe cell.
+func minesweeperStart(mines [][]bool, counts [][]int) (int, int, bool) {
+	fx, fy, found := 0, 0, false
+	for y, row := range mines {
+		for x, mine := range row {
+			if mine {
+				continue
+			}
+			if counts[y][x] == 0 {
+				return x, y, true
+			}
+			if !found {
+				fx, fy, found = x, y, true
+			}
+		}
+	}
+	return fx, fy, found
+}
+
+// anyRevealed reports whether any cell has been uncovered.
+func (e *MinesweeperEngine) anyRevealed() bool {
+	for _, row := range e.Grid {
+		for _, cell := range row {
+			if cell.value != HiddenTile && cell.value != FlagTile {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reveal uncovers a hidden cell. A mine explodes, and a cell with no
+// neighbouring mines floods outwards until it reaches numbered cells.
+func (e *MinesweeperEngine) reveal(x, y int) {
+	if e.mines[y][x] {
+		e.setCellValue(x, y, ExplodedTile)
+		return
+	}
+	queue := [][2]int{{x, y}}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		cell := &e.Grid[p[1]][p[0]]
+		if cell.value != HiddenTile {
+			continue
+		}
+		cell.EnterValue(RevealedTile)
+		if e.counts[p[1]][p[0]] > 0 {
+			continue
+		}
+		forNeighbours(p[0], p[1], e.GetWidth(), e.GetHeight(), func(nx, ny int) {
+			if !e.mines[ny][nx] && e.Grid[ny][nx].value == HiddenTile {
+				queue = append(queue, [2]int{nx, ny})
+			}
+		})
+	}
+	e.updateSaveState()
+}
+
+// chord reveals the hidden neighbours of a revealed number once as many of
+// its neighbours are flagged as it has mines. A wrong flag sets off a mine.
+func (e *MinesweeperEngine) chord(x, y int) {
+	w, h := e.GetWidth(), e.GetHeight()
+	flags := 0
+	forNeighbours(x, y, w, h, func(nx, ny int) {
+		if e.Grid[ny][nx].value == FlagTile {
+			flags++
+		}
+	})
+	if flags != e.counts[y][x] {
+		return
+	}
+	forNeighbours(x, y, w, h, func(nx, ny int) {
+		if e.Grid[ny][nx].value == HiddenTile {
+			e.reveal(nx, ny)
+		}
+	})
+}
+
+// updateSolved refreshes the solved flag on the save.
+func (e *MinesweeperEngine) updateSolved() {
+	solved, err := e.Evaluate()
+	e.Save.Solved = err == nil && solved
+}
+
+// cellWidth is the width of the current zoom level, widened when the glyph
+// set draws anything wider than that.
+func (e *MinesweeperEngine) cellWidth() int {
+	return max(cellWidths[e.zoom], widestGlyph(glyphSetFor(minesweeperGlyphs)))
+}
+
+// tile returns the glyph key a cell is drawn with. Revealed cells show their
+// count, and once the game is lost every mine is shown.
+func (e *MinesweeperEngine) tile(x, y int, lost bool) rune {
+	switch v := e.Grid[y][x].value; {
+	case v == RevealedTile:
+		return rune('0' + e.counts[y][x])
+	case v == ExplodedTile:
+		return ExplodedTile
+	case lost && e.mines[y][x]:
+		return MineTile
+	default:
+		return v
+	}
+}
+
+func (e *MinesweeperEngine) gridView(m model) string {
+	v := e.viewport
+	glyphs := glyphSetFor(minesweeperGlyphs)
+	lost := e.Outcome() == outcomeLost
+	var rows []string
+	for y := v.offsetY; y < v.offsetY+v.rows; y++ {
+		var cells []string
+		for x := v.offsetX; x < v.offsetX+v.cols; x++ {
+			t := e.tile(x, y, lost)
+			s, ok := mineStyles[t]
+			if !ok {
+				s = mineNumberStyle
+			}
+			if x == m.cursorX && y == m.cursorY {
+				s = highlightStyle
+			}
+			g, ok := glyphs[t]
+			if !ok {
+				g = glyphs[HiddenTile]
+			}
+			cells = append(cells, s.Width(e.cellWidth()).AlignHorizontal(lipgloss.Center).Render(g))
+		}
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, cells...))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, rows...)
+}
+
+func (e *MinesweeperEngine) helpView(m model) string {
+	help := "\n"
+	switch e.Grid[m.cursorY][m.cursorX].value {
+	case RevealedTile:
+		help += "z: Chord\n"
+	case HiddenTile, FlagTile:
+		help += "z: Reveal\tx: Flag\tbackspace: clear flag\n"
+	default:
+		help += "\n"
+	}
+	help += e.statusView()
+	help += "arrow keys or hjkl to move\t+/-: zoom\n"
+	help += "Press 'esc' to return to the menu.\n"
+	if e.viewport.clipped(e.GetWidth(), e.GetHeight()) {
+		v := e.viewport
+		help += fmt.Sprintf("Showing columns %d-%d of %d, rows %d-%d of %d\n",
+			v.offsetX+1, v.offsetX+v.cols, e.GetWidth(),
+			v.offsetY+1, v.offsetY+v.rows, e.GetHeight())
+	}
+	switch e.Outcome() {
+	case outcomeLost:
+		help += "Boom! Press 'r' to restart.\n"
+	case outcomeWon:
+		help += "Congrats!\n"
+	}
+	return help
+}
+
+// statusView reports how many mines are left to flag.
+func (e *MinesweeperEngine) statusView() string {
+	mines, flags := 0, 0
+	for y, row := range e.Grid {
+		for x, cell := range row {
+			if e.mines[y][x] {
+				mines++
+			}
+			if cell.value == FlagTile {
+				flags++
+			}
+		}
+	}
+	return fmt.Sprintf("Mines: %d\tFlags: %d\n", mines, flags)
+}
@@ -0,0 +1,340 @@
+package main
+
+import "strings"
+
+// minesweeperSearchBudget caps how many assignments the solver tries while
+// looking for forced cells, so checking a large board can't hang the editor.
+const minesweeperSearchBudget = 200000
+
+// The solver's knowledge of a cell.
+const (
+	mineUnknown int8 = iota
+	mineSafe
+	mineFlagged
+)
+
+// countMinesweeperSolutions checks whether a board can be cleared without
+// guessing, playing it from its start the way a careful player would. A
+// board that needs a guess has more than one mine layout that fits what can
+// be seen at that point, so it counts as two solutions; one that can be
+// cleared by deduction counts as one. A board that starts on a mine has
+// none. ok is false when the search ran out of budget.
+//
+// Cells are first settled with the single number rules. When those stall,
+// every mine layout of the cells next to revealed numbers is searched, and
+// any cell that is a mine in all of them, or in none, is settled.
+func countMinesweeperSolutions(l Level, limit int) (int, bool) {
+	rows := strings.Split(l.Solution, "\n")
+	h, w := len(rows), 0
+	for _, row := range rows {
+		w = max(w, len(row))
+	}
+	mines, counts := mineLayout(l.Solution, w, h)
+
+	s := &mineSolver{w: w, h: h, mines: mines, counts: counts, budget: minesweeperSearchBudget}
+	s.known = make([][]int8, h)
+	for y := range s.known {
+		s.known[y] = make([]int8, w)
+	}
+
+	started := false
+	for y, row := range strings.Split(l.Initial, "\n") {
+		for x, r := range row {
+			if r == '.' || r == ' ' || y >= h || x >= w {
+				continue
+			}
+			if mines[y][x] {
+				return 0, true
+			}
+			s.open(x, y)
+			started = true
+		}
+	}
+	if !started {
+		x, y, ok := minesweeperStart(mines, counts)
+		if !ok {
+			return 1, true
+		}
+		s.open(x, y)
+	}
+
+	for !s.cleared() {
+		if s.deduce() {
+			continue
+		}
+		progress, ok := s.search()
+		if !ok {
+			return 0, false
+		}
+		if !progress {
+			return min(2, limit), true
+		}
+	}
+	return 1, true
+}
+
+type mineSolver struct {
+	w, h   int
+	mines  [][]bool
+	counts [][]int
+	known  [][]int8
+	budget int
+}
+
+// open reveals a safe cell, flooding outwards from cells with no
+// neighbouring mines as the game does.
+func (s *mineSolver) open(x, y int) {
+	if s.known[y][x] == mineSafe {
+		return
+	}
+	s.known[y][x] = mineSafe
+	if s.counts[y][x] == 0 {
+		forNeighbours(x, y, s.w, s.h, func(nx, ny int) {
+			s.open(nx, ny)
+		})
+	}
+}
+
+// cleared reports whether every safe cell has been revealed.
+func (s *mineSolver) cleared() bool {
+	for y, row := range s.known {
+		for x, k := range row {
+			if !s.mines[y][x] && k != mineSafe {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// unknownAround returns the unsettled neighbours of a cell and how many of
+// its neighbours are flagged.
+func (s *mineSolver) unknownAround(x, y int) ([][2]int, int) {
+	var unknown [][2]int
+	flags := 0
+	forNeighbours(x, y, s.w, s.h, func(nx, ny int) {
+		switch s.known[ny][nx] {
+		case mineUnknown:
+			unknown = append(unknown, [2]int{nx, ny})
+		case mineFlagged:
+			flags++
+		}
+	})
+	return unknown, flags
+}
+
+// deduce applies the single number rules: a number whose mines are all
+// flagged clears its other neighbours, and one with exactly as many unknown
+// neighbours as missing mines flags them all.
+func (s *mineSolver) deduce() bool {
+	progress := false
+	for y, row := range s.known {
+		for x, k := range row {
+			if k != mineSafe {
+				continue
+			}
+			unknown, flags := s.unknownAround(x, y)
+			if len(unknown) == 0 {
+				continue
+			}
+			need := s.counts[y][x] - flags
+			for _, p := range unknown {
+				switch need {
+				case 0:
+					s.open(p[0], p[1])
+				case len(unknown):
+					s.known[p[1]][p[0]] = mineFlagged
+				default:
+					continue
+				}
+				progress = true
+			}
+		}
+	}
+	return progress
+}
+
+// search enumerates the mine layouts that fit the revealed numbers and the
+// number of mines left, and settles every cell that takes the same value in
+// all of them. ok is false when the budget ran out.
+func (s *mineSolver) search() (progress bool, ok bool) {
+	m := &mineSearch{budget: &s.budget}
+	index := make(map[[2]int]int)
+	unknowns, flags, total := 0, 0, 0
+	for y, row := range s.known {
+		for x, k := range row {
+			if s.mines[y][x] {
+				total++
+			}
+			switch k {
+			case mineUnknown:
+				unknowns++
+			case mineFlagged:
+				flags++
+			case mineSafe:
+				unknown, f := s.unknownAround(x, y)
+				if len(unknown) == 0 {
+					continue
+				}
+				c := mineConstraint{need: s.counts[y][x] - f}
+				for _, p := range unknown {
+					i, seen := index[p]
+					if !seen {
+						i = len(m.vars)
+						index[p] = i
+						m.vars = append(m.vars, p)
+					}
+					c.vars = append(c.vars, i)
+				}
+				m.cons = append(m.cons, c)
+			}
+		}
+	}
+	m.remaining = total - flags
+	m.others = unknowns - len(m.vars)
+	m.prepare()
+	m.dfs(0, 0)
+	if *m.budget < 0 {
+		return false, false
+	}
+
+	for i, p := range m.vars {
+		switch {
+		case m.seenMine[i] && !m.seenSafe[i]:
+			s.known[p[1]][p[0]] = mineFlagged
+			progress = true
+		case m.seenSafe[i] && !m.seenMine[i]:
+			s.open(p[0], p[1])
+			progress = true
+		}
+	}
+	if m.others > 0 && m.othersMine != m.othersSafe {
+		for y, row := range s.known {
+			for x, k := range row {
+				if _, frontier := index[[2]int{x, y}]; k != mineUnknown || frontier {
+					continue
+				}
+				if m.othersMine {
+					s.known[y][x] = mineFlagged
+				} else {
+					s.open(x, y)
+				}
+			}
+		}
+		progress = true
+	}
+	return progress, true
+}
+
+// mineConstraint says exactly need of vars are mines.
+type mineConstraint struct {
+	need int
+	vars []int
+}
+
+// mineSearch enumerates mine layouts of the cells next to revealed numbers
+// and records which values each cell takes. Cells away from the numbers are
+// interchangeable, so only whether any of them can be a mine, or safe, is
+// recorded.
+type mineSearch struct {
+	vars      [][2]int
+	cons      []mineConstraint
+	varCons   [][]int
+	values    []bool
+	assigned  []int
+	open      []int
+	remaining int
+	others    int
+	budget    *int
+
+	seenMine   []bool
+	seenSafe   []bool
+	othersMine bool
+	othersSafe bool
+}
+
+func (m *mineSearch) prepare() {
+	m.varCons = make([][]int, len(m.vars))
+	m.assigned = make([]int, len(m.cons))
+	m.open = make([]int, len(m.cons))
+	for c, con := range m.cons {
+		m.open[c] = len(con.vars)
+		for _, v := range con.vars {
+			m.varCons[v] = append(m.varCons[v], c)
+		}
+	}
+	m.seenMine = make([]bool, len(m.vars))
+	m.seenSafe = make([]bool, len(m.vars))
+}
+
+// done reports whether every cell has been seen both ways, so nothing more
+// can be learned.
+func (m *mineSearch) done() bool {
+	for i := range m.vars {
+		if !m.seenMine[i] || !m.seenSafe[i] {
+			return false
+		}
+	}
+	return m.others == 0 || (m.othersMine && m.othersSafe)
+}
+
+// dfs assigns vars from i onwards, given the mines placed so far, and
+// reports whether the search should stop.
+func (m *mineSearch) dfs(i, mines int) bool {
+	*m.budget--
+	if *m.budget < 0 {
+		return true
+	}
+	if i == len(m.vars) {
+		rest := m.remaining - mines
+		if rest < 0 || rest > m.others {
+			return false
+		}
+		for v := range m.vars {
+			m.seenMine[v] = m.seenMine[v] || m.values[v]
+			m.seenSafe[v] = m.seenSafe[v] || !m.values[v]
+		}
+		m.othersMine = m.othersMine || rest > 0
+		m.othersSafe = m.othersSafe || rest < m.others
+		return m.done()
+	}
+
+	for _, mine := range []int{0, 1} {
+		if mines+mine > m.remaining || !m.fits(i, mine) {
+			continue
+		}
+		m.set(i, mine, 1)
+		stop := m.dfs(i+1, mines+mine)
+		m.set(i, mine, -1)
+		if stop {
+			return true
+		}
+	}
+	return false
+}
+
+// fits reports whether var i can take the value without breaking any of its
+// constraints.
+func (m *mineSearch) fits(i, mine int) bool {
+	for _, c := range m.varCons[i] {
+		assigned := m.assigned[c] + mine
+		if assigned > m.cons[c].need || assigned+m.open[c]-1 < m.cons[c].need {
+			return false
+		}
+	}
+	return true
+}
+
+// set applies (dir 1) or undoes (dir -1) giving var i the value, tracking
+// it in the bookkeeping of its constraints.
+func (m *mineSearch) set(i, mine, dir int) {
+	for _, c := range m.varCons[i] {
+		m.assigned[c] += dir * mine
+		m.open[c] -= dir
+	}
+	if dir > 0 {
+		m.values = append(m.values, mine == 1)
+	} else {
+		m.values = m.values[:len(m.values)-1]
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import "testing"
+
+// testMinesweeperLevel is a 4x3 board with mines in opposite corners.
+var testMinesweeperLevel = Level{
+	Engine:   "minesweeper",
+	Initial:  "    \n    \n    ",
+	Solution: "*   \n    \n   *",
+}
+
+func TestMinesweeperFloodFill(t *testing.T) {
+	e := newTestEngine(t, testMinesweeperLevel).(*MinesweeperEngine)
+	if want := " ooo\n ooo\n    "; e.Save.State != want {
+		t.Fatalf("expected the start to flood open, got %q", e.Save.State)
+	}
+	if e.Outcome() != outcomePlaying {
+		t.Errorf("expected the game to be in play")
+	}
+
+	e.PrimaryAction(0, 2)
+	if want := " ooo\noooo\nooo "; e.Save.State != want {
+		t.Errorf("expected the corner to flood open, got %q", e.Save.State)
+	}
+	if !e.Save.Solved || e.Outcome() != outcomeWon {
+		t.Errorf("expected the board to be cleared")
+	}
+}
+
+func TestMinesweeperChord(t *testing.T) {
+	e := newTestEngine(t, testMinesweeperLevel).(*MinesweeperEngine)
+	e.SecondaryAction(0, 0)
+	e.PrimaryAction(1, 0)
+	if want := "Fooo\noooo\n    "; e.Save.State != want {
+		t.Errorf("expected chording to reveal the unflagged neighbour, got %q", e.Save.State)
+	}
+
+	e = newTestEngine(t, testMinesweeperLevel).(*MinesweeperEngine)
+	e.SecondaryAction(1, 2)
+	e.PrimaryAction(2, 1)
+	if e.Outcome() != outcomeLost {
+		t.Errorf("expected chording around a wrong flag to set off a mine, got %q", e.Save.State)
+	}
+}
+
+func TestMinesweeperFlags(t *testing.T) {
+	e := newTestEngine(t, testMinesweeperLevel).(*MinesweeperEngine)
+	e.SecondaryAction(0, 0)
+	if e.Grid[0][0].value != FlagTile {
+		t.Fatalf("expected a flag, got %q", e.Grid[0][0].value)
+	}
+	e.PrimaryAction(0, 0)
+	if e.Outcome() != outcomePlaying {
+		t.Errorf("expected revealing a flag to do nothing")
+	}
+	e.SecondaryAction(0, 0)
+	if e.Grid[0][0].value != HiddenTile {
+		t.Errorf("expected a second flag to clear it, got %q", e.Grid[0][0].value)
+	}
+
+	e.SecondaryAction(0, 0)
+	e.ClearCell(0, 0)
+	e.ClearCell(1, 0)
+	if want := " ooo\n ooo\n    "; e.Save.State != want {
+		t.Errorf("expected clearing to remove only the flag, got %q", e.Save.State)
+	}
+}
+
+func TestMinesweeperFlagAfterWin(t *testing.T) {
+	e := newTestEngine(t, testMinesweeperLevel).(*MinesweeperEngine)
+	e.PrimaryAction(0, 2)
+	if !e.Save.Solved {
+		t.Fatalf("expected the board to be cleared, got %q", e.Save.State)
+	}
+
+	// Flagging the mines left hidden, and taking the flags away again,
+	// leaves the board cleared.
+	e.SecondaryAction(0, 0)
+	if !e.Save.Solved || e.Outcome() != outcomeWon {
+		t.Errorf("expected a flag to keep the board cleared, got %q", e.Save.State)
+	}
+	e.SecondaryAction(0, 0)
+	e.SecondaryAction(3, 2)
+	e.ClearCell(3, 2)
+	if !e.Save.Solved || e.Outcome() != outcomeWon {
+		t.Errorf("expected taking the flags away to keep the board cleared, got %q", e.Save.State)
+	}
+}
+
+func TestMinesweeperLoss(t *testing.T) {
+	e := newTestEngine(t, testMinesweeperLevel).(*MinesweeperEngine)
+	e.PrimaryAction(0, 0)
+	if e.Outcome() != outcomeLost || e.Save.Solved {
+		t.Fatalf("expected revealing a mine to lose, got %q", e.Save.State)
+	}
+	state := e.Save.State
+	e.PrimaryAction(0, 2)
+	e.SecondaryAction(3, 2)
+	if e.Save.State != state {
+		t.Errorf("expected a lost game to ignore moves, got %q", e.Save.State)
+	}
+
+	// A lost save loads lost, and restarting starts over.
+	game, err := newEngine(e.GetLevel(), e.GetSave())
+	if err != nil || game.Outcome() != outcomeLost {
+		t.Fatalf("expected the lost save to load lost, got %v", err)
+	}
+	game, err = newEngine(e.GetLevel(), nil)
+	if err != nil || game.Outcome() != outcomePlaying {
+		t.Errorf("expected a restart to be in play, got %v", err)
+	}
+}
+
+func TestCountMinesweeperSolutions(t *testing.T) {
+	testCases := []struct {
+		name      string
+		initial   string
+		solution  string
+		wantCount int
+	}{
+		{
+			name:      "opens in one click",
+			initial:   "   \n   \n   ",
+			solution:  "*  \n   \n   ",
+			wantCount: 1,
+		},
+		{
+			name:      "settled by the mine count",
+			initial:   "   \n   \n   ",
+			solution:  "* *\n   \n   ",
+			wantCount: 1,
+		},
+		{
+			name:      "fifty fifty",
+			initial:   "  \n  \n  ",
+			solution:  "* \n  \n  ",
+			wantCount: 2,
+		},
+		{
+			name:      "given start",
+			initial:   "  \n  \n o",
+			solution:  "* \n  \n  ",
+			wantCount: 2,
+		},
+		{
+			name:      "starts on a mine",
+			initial:   "o \n  ",
+			solution:  "* \n  ",
+			wantCount: 0,
+		},
+		{
+			name:      "settled by a single number",
+			initial:   "    \n    \n    ",
+			solution:  " *  \n    \n    ",
+			wantCount: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			count, ok := countMinesweeperSolutions(Level{Initial: tc.initial, Solution: tc.solution}, 2)
+			if !ok {
+				t.Fatalf("solver gave up")
+			}
+			if count != tc.wantCount {
+				t.Errorf("countMinesweeperSolutions() = %d, want %d", count, tc.wantCount)
+			}
+		})
+	}
+}
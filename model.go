@@ -5,7 +5,6 @@ import (
 	"log"
 
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
 )
 
 const (
@@ -13,8 +12,14 @@ const (
 	browseView
 	gameView
 	exportView
+	editorView
+	indexView
 )
 
+// viewHeaderHeight is the number of lines drawn above every non-menu view:
+// the title bar and a blank line.
+const viewHeaderHeight = 2
+
 type errMsg struct{ err error }
 
 func (e errMsg) Error() string { return e.err.Error() }
@@ -34,6 +39,13 @@ type model struct {
 	totalLevels    int
 	solvedLevels   int
 	saveIndicators map[int]string
+	drag           dragState
+	width          int
+	height         int
+	editor         editorState
+	prompt         prompt
+	message        string
+	index          indexState
 }
 
 func NewModel(store *Store) model {
@@ -61,6 +73,30 @@ func NewModel(store *Store) model {
 		totalLevels:    totalLevels,
 		solvedLevels:   solvedLevels,
 		saveIndicators: make(map[int]string),
+		editor:         newEditorState(),
+		index:          indexState{url: userConfig.PackIndex},
+	}
+}
+
+// reloadLevelPacks refreshes the pack list and library stats after the store
+// has changed.
+func (m *model) reloadLevelPacks() error {
+	levelpacks, err := m.store.GetAllLevelPacks()
+	if err != nil {
+		return err
+	}
+	m.levelpacks = levelpacks
+	m.loadedPacks = len(levelpacks)
+	if total, err := m.store.CountLevels(); err == nil {
+		m.totalLevels = total
+	}
+	return nil
+}
+
+// reloadSolvedLevels refreshes the solved count after saves have changed.
+func (m *model) reloadSolvedLevels() {
+	if solved, err := m.store.CountSolvedLevels(); err == nil {
+		m.solvedLevels = solved
 	}
 }
 
@@ -71,14 +107,26 @@ func (m model) Init() tea.Cmd {
 func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
-		if m.state == gameView {
-			m.engine.View(*m)
-		}
+		m.width = msg.Width
+		m.height = msg.Height
 		return m, nil
 	case errMsg:
 		log.Printf("error: %v", msg)
 		return m, tea.Quit
+	case packIndexMsg:
+		return m.updatePackIndex(msg)
+	case packInstalledMsg:
+		return m.updatePackInstalled(msg)
+	case editorCheckMsg:
+		return m.updateEditorCheck(msg)
+	case tea.MouseMsg:
+		if m.state == gameView {
+			return m.updateGameMouse(msg)
+		}
 	case tea.KeyMsg:
+		if m.prompt.active {
+			return m.updatePrompt(msg)
+		}
 		switch m.state {
 		case menuView:
 			return m.updateMenuView(msg)
@@ -88,6 +136,10 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.updateGameView(msg)
 		case exportView:
 			return m.updateExportView(msg)
+		case editorView:
+			return m.updateEditorView(msg)
+		case indexView:
+			return m.updateIndexView(msg)
 		}
 	}
 	return m, nil
@@ -102,7 +154,7 @@ func (m model) View() string {
 		} else {
 			title = "chronical"
 		}
-		s = lipgloss.NewStyle().Background(lipgloss.Color("130")).Padding(0, 1).Render(title)
+		s = titleStyle.Render(title)
 		s += "\n\n"
 	}
 
@@ -115,6 +167,16 @@ func (m model) View() string {
 		s += m.engine.View(m)
 	case exportView:
 		s += m.viewExportView()
+	case editorView:
+		s += m.viewEditorView()
+	case indexView:
+		s += m.viewIndexView()
+	}
+
+	if m.prompt.active {
+		s += "\n" + m.viewPrompt()
+	} else if m.message != "" {
+		s += "\n" + m.message + "\n"
 	}
 
 	return s
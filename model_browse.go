@@ -5,10 +5,10 @@ import (
 	"log"
 
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
 )
 
 func (m *model) updateBrowseView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.message = ""
 	switch msg.String() {
 	case "ctrl+c":
 		return m, tea.Quit
@@ -39,8 +39,43 @@ func (m *model) updateBrowseView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				m.levelIndex++
 			}
 		}
+	case "d":
+		if m.levels == nil && len(m.levelpacks) > 0 {
+			pack := m.levelpacks[m.levelPackIndex]
+			total, _, err := m.store.CountLevelsByPack(pack.ID)
+			if err != nil {
+				return m, func() tea.Msg { return errMsg{err} }
+			}
+			label := fmt.Sprintf("Delete %s and its %d level(s) and saves?", pack.Name, total)
+			m.askConfirm(label, func(m *model) error {
+				if err := m.store.DeleteLevelPack(pack.ID); err != nil {
+					return err
+				}
+				if err := m.reloadLevelPacks(); err != nil {
+					return err
+				}
+				m.reloadSolvedLevels()
+				m.levelPackIndex = max(0, min(m.levelPackIndex, len(m.levelpacks)-1))
+				m.message = fmt.Sprintf("Deleted %s", pack.Name)
+				return nil
+			})
+		}
+	case "r":
+		if m.levels == nil && len(m.levelpacks) > 0 {
+			pack := m.levelpacks[m.levelPackIndex]
+			m.askText("Rename "+pack.Name+" to", pack.Name, func(m *model, name string) error {
+				if err := m.store.RenameLevelPack(pack.ID, name); err != nil {
+					return err
+				}
+				m.message = fmt.Sprintf("Renamed %s to %s", pack.Name, name)
+				return m.reloadLevelPacks()
+			})
+		}
 	case "enter":
 		if m.levels == nil {
+			if len(m.levelpacks) == 0 {
+				return m, nil
+			}
 			selectedPack := m.levelpacks[m.levelPackIndex]
 			levels, err := m.store.GetLevelsByPack(selectedPack.ID)
 			if err != nil {
@@ -66,15 +101,7 @@ func (m *model) updateBrowseView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				log.Printf("event=\"no_save_file_found\" level_id=%d", selectedLevel.ID)
 			}
 
-			var engine GameEngine
-			switch selectedLevel.Engine {
-			case "nonogram":
-				engine, err = new(NonogramEngine).New(selectedLevel, save)
-			default:
-				log.Printf("event=\"engine_not_found\" level_engine=\"%v\"", selectedLevel.Engine)
-				selectedLevel.Engine = "fallback"
-				engine, err = new(DebugEngine).New(selectedLevel, save)
-			}
+			engine, err := newEngine(selectedLevel, save)
 			if err != nil {
 				return m, func() tea.Msg { return errMsg{err} }
 			}
@@ -96,27 +123,31 @@ func (m model) viewBrowseView() string {
 		s += "Select a level pack:\n\n"
 		for i, lp := range m.levelpacks {
 			if i == m.levelPackIndex {
-				s += lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Render(fmt.Sprintf("> %s by %s", lp.Name, lp.Author)) + "\n"
+				s += focusedStyle.Render(fmt.Sprintf("> %s by %s", lp.Name, lp.Author)) + "\n"
 			} else {
-				s += lipgloss.NewStyle().Foreground(lipgloss.Color("255")).Render(fmt.Sprintf("  %s by %s", lp.Name, lp.Author)) + "\n"
+				s += blurredStyle.Render(fmt.Sprintf("  %s by %s", lp.Name, lp.Author)) + "\n"
 			}
 		}
 	} else {
 		s += fmt.Sprintf("Select a level in %s:\n\n", m.levelpacks[m.levelPackIndex].Name)
-		s += lipgloss.NewStyle().Foreground(lipgloss.Color("145")).Render(fmt.Sprintf("  %-24s\t(%s)\t%s", "Level Name", "Game Mode", "Save")) + "\n"
+		s += tableTitleStyle.Render(fmt.Sprintf("  %-24s\t(%s)\t%s", "Level Name", "Game Mode", "Save")) + "\n"
 		for i, l := range m.levels {
 			saveIndicator := m.saveIndicators[l.ID]
 
 			line := fmt.Sprintf("  %-24s\t(%s)\t%s", l.Name, l.Engine, saveIndicator)
 			if i == m.levelIndex {
 				line = ">" + line[1:]
-				s += lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Render(line) + "\n"
+				s += focusedStyle.Render(line) + "\n"
 			} else {
-				s += lipgloss.NewStyle().Foreground(lipgloss.Color("255")).Render(line) + "\n"
+				s += blurredStyle.Render(line) + "\n"
 			}
 		}
 	}
 
-	s += "\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render("Press 'esc' to return to the menu.") + "\n"
+	s += "\n"
+	if m.levels == nil {
+		s += subtleStyle.Render("d: delete pack\tr: rename pack") + "\n"
+	}
+	s += subtleStyle.Render("Press 'esc' to return to the menu.") + "\n"
 	return s
 }
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	editorSetup uint = iota
+	editorPaint
+)
+
+const (
+	editorFieldEngine = iota
+	editorFieldWidth
+	editorFieldHeight
+	editorFieldPack
+	editorFieldPackName
+	editorFieldLevelName
+	editorFieldAuthor
+	editorFieldCount
+)
+
+const (
+	editorMinSize = 1
+	editorMaxSize = 50
+)
+
+// editorState holds the level editor's form and the draft being painted.
+type editorState struct {
+	step        uint
+	field       int
+	engineIndex int
+	width       int
+	height      int
+	packIndex   int
+	packName    string
+	levelName   string
+	author      string
+	draft       *levelDraft
+	layer       uint
+	check       string
+	checkSeq    int
+	message     string
+}
+
+// editorCheckMsg carries the result of checking a draft back to the model.
+// seq tells a result apart from those of drafts painted over since.
+type editorCheckMsg struct {
+	seq    int
+	result string
+}
+
+func newEditorState() editorState {
+	return editorState{width: 5, height: 5}
+}
+
+// editorNewPack reports whether the level will be saved into a new pack.
+func (m *model) editorNewPack() bool {
+	return m.editor.packIndex >= len(m.levelpacks)
+}
+
+func (m *model) updateEditorView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+	if m.editor.step == editorPaint {
+		return m.updateEditorPaint(msg)
+	}
+	return m.updateEditorSetup(msg)
+}
+
+func (m *model) updateEditorSetup(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	ed := &m.editor
+	ed.message = ""
+	names := engineNames()
+
+	switch msg.String() {
+	case "esc":
+		m.state = menuView
+		return m, nil
+	case "up", "shift+tab":
+		ed.field = m.nextEditorField(-1)
+	case "down", "tab":
+		ed.field = m.nextEditorField(1)
+	case "left", "right":
+		step := 1
+		if msg.String() == "left" {
+			step = -1
+		}
+		switch ed.field {
+		case editorFieldEngine:
+			ed.engineIndex = (ed.engineIndex + step + len(names)) % len(names)
+		case editorFieldWidth:
+			ed.width = max(editorMinSize, min(editorMaxSize, ed.width+step))
+		case editorFieldHeight:
+			ed.height = max(editorMinSize, min(editorMaxSize, ed.height+step))
+		case editorFieldPack:
+			n := len(m.levelpacks) + 1
+			ed.packIndex = (ed.packIndex + step + n) % n
+		}
+	case "backspace":
+		if f := m.editorTextField(); f != nil && len(*f) > 0 {
+			r := []rune(*f)
+			*f = string(r[:len(r)-1])
+		}
+	case "enter":
+		if err := m.startEditorPaint(); err != nil {
+			ed.message = err.Error()
+		} else {
+			return m, m.checkDraft()
+		}
+	default:
+		if f := m.editorTextField(); f != nil && msg.Type == tea.KeyRunes {
+			*f += string(msg.Runes)
+		} else if msg.Type == tea.KeySpace && f != nil {
+			*f += " "
+		}
+	}
+	return m, nil
+}
+
+// nextEditorField moves focus through the setup form, skipping the new pack
+// name when an existing pack is selected.
+func (m *model) nextEditorField(step int) int {
+	f := m.editor.field
+	for {
+		f = (f + step + editorFieldCount) % editorFieldCount
+		if f != editorFieldPackName || m.editorNewPack() {
+			return f
+		}
+	}
+}
+
+// editorTextField returns the text the focused setup field edits, if any.
+func (m *model) editorTextField() *string {
+	switch m.editor.field {
+	case editorFieldPackName:
+		return &m.editor.packName
+	case editorFieldLevelName:
+		return &m.editor.levelName
+	case editorFieldAuthor:
+		return &m.editor.author
+	}
+	return nil
+}
+
+// startEditorPaint checks the setup form and moves on to painting. The draft
+// is kept when the engine and size haven't changed, so going back to fix a
+// name doesn't lose work.
+func (m *model) startEditorPaint() error {
+	ed := &m.editor
+	if strings.TrimSpace(ed.levelName) == "" {
+		return fmt.Errorf("the level needs a name")
+	}
+	if m.editorNewPack() && strings.TrimSpace(ed.packName) == "" {
+		return fmt.Errorf("the new pack needs a name")
+	}
+
+	engine := engineNames()[ed.engineIndex]
+	d := ed.draft
+	if d == nil || d.engine != engine || d.GetWidth() != ed.width || d.GetHeight() != ed.height {
+		ed.draft = newLevelDraft(engine, ed.width, ed.height)
+		m.cursorX, m.cursorY = 0, 0
+	}
+	ed.layer = solutionLayer
+	ed.step = editorPaint
+	return nil
+}
+
+// checkDraft starts checking the draft with its engine's solver, which can
+// take a while on a large grid, so the editor stays responsive meanwhile.
+func (m *model) checkDraft() tea.Cmd {
+	ed := &m.editor
+	ed.checkSeq++
+	ed.check = "checking..."
+	seq, level := ed.checkSeq, ed.draft.Level("", "")
+	return func() tea.Msg {
+		return editorCheckMsg{seq: seq, result: checkLevel(level)}
+	}
+}
+
+// updateEditorCheck shows the result of a check, unless the draft has been
+// painted since it started.
+func (m *model) updateEditorCheck(msg editorCheckMsg) (tea.Model, tea.Cmd) {
+	if msg.seq == m.editor.checkSeq {
+		m.editor.check = msg.result
+	}
+	return m, nil
+}
+
+func (m *model) updateEditorPaint(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	ed := &m.editor
+	d := ed.draft
+	paint := engines[d.engine].Paint
+	before := d.Level("", "")
+	ed.message = ""
+
+	switch msg.String() {
+	case "esc":
+		ed.step = editorSetup
+		return m, nil
+	case "up", "k":
+		if d.HasCell(m.cursorX, m.cursorY-1) {
+			m.cursorY--
+		}
+	case "down", "j":
+		if d.HasCell(m.cursorX, m.cursorY+1) {
+			m.cursorY++
+		}
+	case "left", "h":
+		if d.HasCell(m.cursorX-1, m.cursorY) {
+			m.cursorX--
+		}
+	case "right", "l":
+		if d.HasCell(m.cursorX+1, m.cursorY) {
+			m.cursorX++
+		}
+	case "tab":
+		if ed.layer == solutionLayer {
+			ed.layer = givensLayer
+		} else {
+			ed.layer = solutionLayer
+		}
+	case "z":
+		if ed.layer == givensLayer {
+			d.setGiven(m.cursorX, m.cursorY, true)
+		} else {
+			d.paint(m.cursorX, m.cursorY, paint[0])
+		}
+	case "x":
+		if ed.layer == givensLayer {
+			d.setGiven(m.cursorX, m.cursorY, false)
+		} else if len(paint) > 1 {
+			d.paint(m.cursorX, m.cursorY, paint[1])
+		} else {
+			d.paint(m.cursorX, m.cursorY, EmptyTile)
+		}
+	case "backspace":
+		if ed.layer == givensLayer {
+			d.setGiven(m.cursorX, m.cursorY, false)
+		} else {
+			d.paint(m.cursorX, m.cursorY, EmptyTile)
+		}
+	case "enter":
+		if err := m.saveDraft(); err != nil {
+			log.Printf("event=\"editor_save_failed\" err=\"%v\"", err)
+			ed.message = fmt.Sprintf("Could not save: %v", err)
+		}
+		return m, nil
+	default:
+		// Engines with more than two values, such as digits, paint them by
+		// typing the value itself.
+		if msg.Type == tea.KeyRunes && len(msg.Runes) == 1 && ed.layer == solutionLayer {
+			for _, v := range paint {
+				if v == msg.Runes[0] {
+					d.paint(m.cursorX, m.cursorY, v)
+				}
+			}
+		}
+	}
+	if after := d.Level("", ""); after.Initial != before.Initial || after.Solution != before.Solution {
+		return m, m.checkDraft()
+	}
+	return m, nil
+}
+
+// saveDraft stores the draft as a level in the selected pack, creating the
+// pack first when it is new.
+func (m *model) saveDraft() error {
+	ed := &m.editor
+	level := ed.draft.Level(strings.TrimSpace(ed.levelName), strings.TrimSpace(ed.author))
+	if err := level.Validate(); err != nil {
+		return err
+	}
+
+	var pack LevelPack
+	if m.editorNewPack() {
+		pack = LevelPack{Name: strings.TrimSpace(ed.packName), Author: level.Author, Version: 1}
+		if err := m.store.UpsertLevelPack(&pack); err != nil {
+			return err
+		}
+	} else {
+		pack = m.levelpacks[ed.packIndex]
+	}
+	if err := m.store.UpsertLevel(&level, pack.ID); err != nil {
+		return err
+	}
+	log.Printf("event=\"editor_saved_level\" level=\"%s\" level_pack_id=%d", level.Name, pack.ID)
+
+	if err := m.reloadLevelPacks(); err != nil {
+		return err
+	}
+	for i, lp := range m.levelpacks {
+		if lp.ID == pack.ID {
+			ed.packIndex = i
+		}
+	}
+	ed.message = fmt.Sprintf("Saved %s to %s", level.Name, pack.Name)
+	return nil
+}
+
+func (m model) viewEditorView() string {
+	if m.editor.step == editorPaint {
+		return m.viewEditorPaint()
+	}
+	return m.viewEditorSetup()
+}
+
+func (m model) viewEditorSetup() string {
+	ed := m.editor
+	pack := "New pack"
+	if !m.editorNewPack() {
+		pack = m.levelpacks[ed.packIndex].Name
+	}
+
+	fields := []struct {
+		index int
+		label string
+		value string
+	}{
+		{editorFieldEngine, "Engine", "< " + engineNames()[ed.engineIndex] + " >"},
+		{editorFieldWidth, "Width", fmt.Sprintf("< %d >", ed.width)},
+		{editorFieldHeight, "Height", fmt.Sprintf("< %d >", ed.height)},
+		{editorFieldPack, "Pack", "< " + pack + " >"},
+		{editorFieldPackName, "New pack name", ed.packName},
+		{editorFieldLevelName, "Level name", ed.levelName},
+		{editorFieldAuthor, "Author", ed.author},
+	}
+
+	s := "Create a level:\n\n"
+	for _, f := range fields {
+		if f.index == editorFieldPackName && !m.editorNewPack() {
+			continue
+		}
+		line := fmt.Sprintf("  %-14s %s", f.label, f.value)
+		if f.index == ed.field {
+			s += focusedStyle.Render(">"+line[1:]) + "\n"
+		} else {
+			s += blurredStyle.Render(line) + "\n"
+		}
+	}
+	if ed.message != "" {
+		s += "\n" + hintErrorStyle.Render(ed.message) + "\n"
+	}
+	s += "\n" + subtleStyle.Render("up/down: select field\tleft/right: change\tenter: paint") + "\n"
+	s += subtleStyle.Render("Press 'esc' to return to the menu.") + "\n"
+	return s
+}
+
+func (m model) viewEditorPaint() string {
+	ed := m.editor
+	d := ed.draft
+
+	var glyphs GlyphSet
+	if info, ok := engines[d.engine]; ok {
+		glyphs = glyphSetFor(info.New().GlyphSets())
+	}
+	width := max(3, widestGlyph(glyphs))
+
+	var rows []string
+	for y, row := range d.solution {
+		var cells []string
+		for x, r := range row {
+			g, ok := glyphs[r]
+			if !ok {
+				g = string(r)
+			}
+			style := blurredStyle
+			switch {
+			case x == m.cursorX && y == m.cursorY:
+				style = highlightStyle
+			case d.givens[y][x]:
+				style = editorGivenStyle
+			case ed.layer == givensLayer:
+				style = subtleStyle
+			}
+			cells = append(cells, style.Width(width).Align(lipgloss.Center).Render(g))
+		}
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, cells...))
+	}
+
+	layer := "solution"
+	help := "z: paint\tx: secondary\tbackspace: clear"
+	if ed.layer == givensLayer {
+		layer = "givens"
+		help = "z: reveal as given\tx/backspace: hide"
+	}
+
+	s := fmt.Sprintf("Editing %s (%s %dx%d), painting the %s\n\n", ed.levelName, d.engine, d.GetWidth(), d.GetHeight(), layer)
+	s += lipgloss.JoinVertical(lipgloss.Left, rows...) + "\n\n"
+	s += "Check: " + ed.check + "\n"
+	if ed.message != "" {
+		s += ed.message + "\n"
+	}
+	s += "\n" + subtleStyle.Render(help) + "\n"
+	s += subtleStyle.Render("tab: switch layer\tenter: save\tesc: back to setup") + "\n"
+	return s
+}
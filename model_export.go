@@ -1,10 +1,11 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
 )
 
 func (m *model) updateExportView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
@@ -20,26 +21,44 @@ func (m *model) updateExportView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.levelPackIndex++
 		}
 	case "enter":
-		selectedPack := m.levelpacks[m.levelPackIndex]
-		err := m.store.ExportLevelPack(selectedPack.ID, selectedPack.Name+".yaml")
-		if err != nil {
-			return m, func() tea.Msg { return errMsg{err} }
+		if len(m.levelpacks) == 0 {
+			return m, nil
 		}
-		return m, tea.Quit
+		pack := m.levelpacks[m.levelPackIndex]
+		m.askText("Export to", safeFileName(pack.Name)+".yaml", func(m *model, path string) error {
+			return m.exportPack(pack, path, false)
+		})
 	}
 	return m, nil
 }
 
+// exportPack writes a pack to path, asking before overwriting a file that is
+// already there.
+func (m *model) exportPack(pack LevelPack, path string, force bool) error {
+	err := m.store.ExportLevelPackFile(pack.ID, path, ExportOptions{Force: force})
+	if errors.Is(err, fs.ErrExist) {
+		m.askConfirm(path+" already exists. Overwrite it?", func(m *model) error {
+			return m.exportPack(pack, path, true)
+		})
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	m.message = fmt.Sprintf("Exported %s to %s", pack.Name, path)
+	return nil
+}
+
 func (m model) viewExportView() string {
 	s := "Select a level pack to export:\n\n"
 	for i, lp := range m.levelpacks {
 		if i == m.levelPackIndex {
-			s += lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Render(fmt.Sprintf("> %s by %s", lp.Name, lp.Author)) + "\n"
+			s += focusedStyle.Render(fmt.Sprintf("> %s by %s", lp.Name, lp.Author)) + "\n"
 		} else {
-			s += lipgloss.NewStyle().Foreground(lipgloss.Color("255")).Render(fmt.Sprintf("  %s by %s", lp.Name, lp.Author)) + "\n"
+			s += blurredStyle.Render(fmt.Sprintf("  %s by %s", lp.Name, lp.Author)) + "\n"
 		}
 	}
-	s += "\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render("Press 'enter' to export the selected level pack.") + "\n"
-	s += lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render("Press 'esc' to return to the menu.") + "\n"
+	s += "\n" + subtleStyle.Render("Press 'enter' to export the selected level pack. Use a .json path for JSON.") + "\n"
+	s += subtleStyle.Render("Press 'esc' to return to the menu.") + "\n"
 	return s
 }
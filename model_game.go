@@ -6,6 +6,24 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+const (
+	dragFree uint = iota
+	dragRow
+	dragCol
+)
+
+// dragState tracks a click-and-drag paint in the game view. Once the pointer
+// leaves the starting cell the drag is locked to that row or column.
+type dragState struct {
+	active  bool
+	primary bool
+	axis    uint
+	startX  int
+	startY  int
+	lastX   int
+	lastY   int
+}
+
 func (m *model) updateGameView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
@@ -26,31 +44,176 @@ func (m *model) updateGameView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.engine = nil
 		return m, nil
 	case "up", "k":
-		if m.engine.HasCell(m.cursorX, m.cursorY-1) {
-			m.cursorY--
-		}
+		m.moveCursor(0, -1)
 	case "down", "j":
-		if m.engine.HasCell(m.cursorX, m.cursorY+1) {
-			m.cursorY++
-		}
+		m.moveCursor(0, 1)
 	case "left", "h":
-		if m.engine.HasCell(m.cursorX-1, m.cursorY) {
-			m.cursorX--
-		}
+		m.moveCursor(-1, 0)
 	case "right", "l":
-		if m.engine.HasCell(m.cursorX+1, m.cursorY) {
-			m.cursorX++
+		m.moveCursor(1, 0)
+	case "+", "=":
+		m.engine.Zoom(1)
+	case "-":
+		m.engine.Zoom(-1)
+	case "r":
+		if m.engine.Outcome() == outcomeLost {
+			m.restartLevel()
 		}
+	default:
+		// A lost game only takes moves again once it is restarted.
+		if m.engine.Outcome() != outcomeLost {
+			m.applyGameKey(msg)
+		}
+	}
+
+	return m, cmd
+}
+
+// moveCursor steps the cursor to the next cell in a direction, or wherever
+// the engine puts it when it moves its own cursor.
+func (m *model) moveCursor(dx, dy int) {
+	if c, ok := m.engine.(cursorMover); ok {
+		if x, y, ok := c.MoveCursor(m.cursorX, m.cursorY, dx, dy); ok {
+			m.cursorX, m.cursorY = x, y
+		}
+		return
+	}
+	if m.engine.HasCell(m.cursorX+dx, m.cursorY+dy) {
+		m.cursorX += dx
+		m.cursorY += dy
+	}
+}
+
+// applyGameKey makes the move a key stands for at the cursor.
+func (m *model) applyGameKey(msg tea.KeyMsg) {
+	switch msg.String() {
 	case "z":
 		m.engine.PrimaryAction(m.cursorX, m.cursorY)
-		m.engine.Evaluate()
 	case "x":
 		m.engine.SecondaryAction(m.cursorX, m.cursorY)
-		m.engine.Evaluate()
 	case "backspace":
 		m.engine.ClearCell(m.cursorX, m.cursorY)
-		m.engine.Evaluate()
+	default:
+		e, ok := m.engine.(valueEntry)
+		if !ok || msg.Type != tea.KeyRunes || len(msg.Runes) != 1 {
+			return
+		}
+		e.EnterValue(m.cursorX, m.cursorY, msg.Runes[0])
 	}
+	m.engine.Evaluate()
+}
 
-	return m, cmd
+func (m *model) updateGameMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	x, y, ok := m.engine.CellAt(msg.X, msg.Y-viewHeaderHeight)
+
+	switch msg.Action {
+	case tea.MouseActionPress:
+		if !ok || m.engine.Outcome() == outcomeLost {
+			return m, nil
+		}
+		switch msg.Button {
+		case tea.MouseButtonLeft:
+			m.drag = dragState{active: true, primary: true}
+		case tea.MouseButtonRight:
+			m.drag = dragState{active: true, primary: false}
+		default:
+			return m, nil
+		}
+		m.drag.startX, m.drag.startY = x, y
+		m.drag.lastX, m.drag.lastY = x, y
+		m.cursorX, m.cursorY = x, y
+		m.applyDragAction(x, y)
+		if _, ok := m.engine.(dragPainter); !ok {
+			m.drag = dragState{}
+		}
+	case tea.MouseActionMotion:
+		if !m.drag.active || !ok {
+			return m, nil
+		}
+		x, y = m.drag.lock(x, y)
+		if !m.engine.HasCell(x, y) {
+			return m, nil
+		}
+		// Paint every cell between the last painted one and the pointer so
+		// fast drags don't leave gaps.
+		for m.drag.lastX != x || m.drag.lastY != y {
+			m.drag.lastX += sign(x - m.drag.lastX)
+			m.drag.lastY += sign(y - m.drag.lastY)
+			m.applyDragAction(m.drag.lastX, m.drag.lastY)
+		}
+		m.cursorX, m.cursorY = x, y
+	case tea.MouseActionRelease:
+		m.drag = dragState{}
+	}
+
+	return m, nil
+}
+
+// restartLevel reloads the level from its initial state, throwing away the
+// moves that lost it. The lost save is replaced straight away so it can't
+// come back if the level is left untouched.
+func (m *model) restartLevel() {
+	level := m.engine.GetLevel()
+	engine, err := newEngine(level, nil)
+	if err != nil {
+		log.Printf("event=\"restart_level_failed\" level_id=%d err=\"%v\"", level.ID, err)
+		return
+	}
+	log.Printf("event=\"restart_level\" level_id=%d", level.ID)
+	if m.store != nil {
+		if err := m.store.UpsertSave(engine.GetSave()); err != nil {
+			log.Printf("event=\"save_progress_failed\" level_id=%d err=\"%v\"", level.ID, err)
+		}
+	}
+	m.engine = engine
+	m.drag = dragState{}
+}
+
+// lock fixes the drag to a row or column once the pointer leaves the
+// starting cell, and projects the pointer onto that line.
+func (d *dragState) lock(x, y int) (int, int) {
+	if d.axis == dragFree {
+		switch {
+		case x == d.startX && y == d.startY:
+			return x, y
+		case y == d.startY:
+			d.axis = dragRow
+		case x == d.startX:
+			d.axis = dragCol
+		case abs(x-d.startX) >= abs(y-d.startY):
+			d.axis = dragRow
+		default:
+			d.axis = dragCol
+		}
+	}
+	if d.axis == dragRow {
+		return x, d.startY
+	}
+	return d.startX, y
+}
+
+func (m *model) applyDragAction(x, y int) {
+	if m.drag.primary {
+		m.engine.PrimaryAction(x, y)
+	} else {
+		m.engine.SecondaryAction(x, y)
+	}
+	m.engine.Evaluate()
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
 }
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// indexState holds the pack index being browsed in the install view.
+type indexState struct {
+	url        string
+	loading    bool
+	packs      []PackIndexEntry
+	cursor     int
+	err        error
+	installing string
+}
+
+// packIndexMsg carries a downloaded pack index back to the model.
+type packIndexMsg struct {
+	index *PackIndex
+	err   error
+}
+
+// packInstalledMsg reports the result of installing a pack from the index.
+type packInstalledMsg struct {
+	name string
+	err  error
+}
+
+// loadPackIndex starts downloading the configured pack index.
+func (m *model) loadPackIndex() tea.Cmd {
+	if m.index.url == "" {
+		m.index.err = errors.New("no pack index configured, set pack_index in the config file")
+		return nil
+	}
+	m.index.loading = true
+	m.index.err = nil
+	url := m.index.url
+	return func() tea.Msg {
+		index, err := FetchPackIndex(url)
+		return packIndexMsg{index: index, err: err}
+	}
+}
+
+func (m *model) updatePackIndex(msg packIndexMsg) (tea.Model, tea.Cmd) {
+	m.index.loading = false
+	m.index.err = msg.err
+	if msg.err == nil {
+		m.index.packs = msg.index.Packs
+		m.index.cursor = 0
+	}
+	return m, nil
+}
+
+func (m *model) updatePackInstalled(msg packInstalledMsg) (tea.Model, tea.Cmd) {
+	m.index.installing = ""
+	if msg.err != nil {
+		log.Printf("event=\"install_pack_failed\" name=\"%s\" err=\"%v\"", msg.name, msg.err)
+		m.message = fmt.Sprintf("Error: could not install %s: %v", msg.name, msg.err)
+		return m, nil
+	}
+	if err := m.reloadLevelPacks(); err != nil {
+		m.message = "Error: " + err.Error()
+		return m, nil
+	}
+	m.message = fmt.Sprintf("Installed %s", msg.name)
+	return m, nil
+}
+
+// installedVersion returns the version of the installed pack with the given
+// name, or 0 if it isn't installed.
+func (m model) installedVersion(name string) int {
+	for _, lp := range m.levelpacks {
+		if lp.Name == name {
+			return lp.Version
+		}
+	}
+	return 0
+}
+
+func (m *model) updateIndexView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c", "esc":
+		m.state = menuView
+	case "up", "k":
+		if m.index.cursor > 0 {
+			m.index.cursor--
+		}
+	case "down", "j":
+		if m.index.cursor < len(m.index.packs)-1 {
+			m.index.cursor++
+		}
+	case "r":
+		return m, m.loadPackIndex()
+	case "enter":
+		if m.index.installing != "" || len(m.index.packs) == 0 {
+			return m, nil
+		}
+		entry := m.index.packs[m.index.cursor]
+		m.index.installing = entry.Name
+		m.message = ""
+		store := m.store
+		return m, func() tea.Msg {
+			_, err := store.ImportURL(entry.URL, entry.SHA256)
+			return packInstalledMsg{name: entry.Name, err: err}
+		}
+	}
+	return m, nil
+}
+
+func (m model) viewIndexView() string {
+	switch {
+	case m.index.err != nil:
+		return "Error: " + m.index.err.Error() + "\n\n" +
+			subtleStyle.Render("Press 'r' to retry or 'esc' to return to the menu.") + "\n"
+	case m.index.loading:
+		return "Loading pack index...\n"
+	}
+
+	s := "Select a level pack to install:\n\n"
+	for i, entry := range m.index.packs {
+		line := fmt.Sprintf("%s v%d by %s", entry.Name, entry.Version, entry.Author)
+		switch installed := m.installedVersion(entry.Name); {
+		case entry.Name == m.index.installing:
+			line += " (installing...)"
+		case installed == 0:
+		case installed < entry.Version:
+			line += fmt.Sprintf(" (update from v%d)", installed)
+		default:
+			line += " (installed)"
+		}
+		if i == m.index.cursor {
+			s += focusedStyle.Render("> "+line) + "\n"
+			if entry.Description != "" {
+				s += subtleStyle.Render("    "+entry.Description) + "\n"
+			}
+		} else {
+			s += blurredStyle.Render("  "+line) + "\n"
+		}
+	}
+	if len(m.index.packs) == 0 {
+		s += subtleStyle.Render("The pack index is empty.") + "\n"
+	}
+	s += "\n" + subtleStyle.Render("Press 'enter' to install the selected level pack, 'r' to reload the index.") + "\n"
+	s += subtleStyle.Render("Press 'esc' to return to the menu.") + "\n"
+	return s
+}
@@ -7,6 +7,24 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
+const blockBanner = `
+  ▌       ▘    ▜ 
+▛▘▛▌▛▘▛▌▛▌▌▛▘▀▌▐ 
+▙▖▌▌▌ ▙▌▌▌▌▙▖█▌▐▖
+`
+
+// menuBanners holds the menu title for each glyph set. The block character
+// banner can't be drawn without unicode, so ascii gets a plain one.
+var menuBanners = map[string]string{
+	unicodeGlyphs: blockBanner,
+	emojiGlyphs:   blockBanner,
+	asciiGlyphs: `
++-----------+
+| chronical |
++-----------+
+`,
+}
+
 func (m *model) updateMenuView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "q", "ctrl+c", "esc":
@@ -16,7 +34,7 @@ func (m *model) updateMenuView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.menuIndex--
 		}
 	case "down", "j":
-		if m.menuIndex < 2 {
+		if m.menuIndex < 4 {
 			m.menuIndex++
 		}
 	case "enter":
@@ -24,8 +42,14 @@ func (m *model) updateMenuView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		case 0:
 			m.state = browseView
 		case 1:
-			m.state = exportView
+			m.state = editorView
+			m.editor.step = editorSetup
 		case 2:
+			m.state = indexView
+			return m, m.loadPackIndex()
+		case 3:
+			m.state = exportView
+		case 4:
 			return m, tea.Quit
 		}
 	}
@@ -33,19 +57,18 @@ func (m *model) updateMenuView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m model) viewMenuView() string {
-	title := `
-  ▌       ▘    ▜ 
-▛▘▛▌▛▘▛▌▛▌▌▛▘▀▌▐ 
-▙▖▌▌▌ ▙▌▌▌▌▙▖█▌▐▖
-`
+	title, ok := menuBanners[activeGlyphSet]
+	if !ok {
+		title = menuBanners[asciiGlyphs]
+	}
 	var s string
-	s += lipgloss.NewStyle().Margin(1).Foreground(lipgloss.Color("130")).Render(title)
+	s += bannerStyle.Render(title)
 
-	buttons := []string{"Browse", "Export", "Quit"}
+	buttons := []string{"Browse", "Edit", "Install", "Export", "Quit"}
 	for i, button := range buttons {
-		style := lipgloss.NewStyle().Padding(1, 2)
+		style := blurredStyle.Padding(1, 2)
 		if i == m.menuIndex {
-			style = style.Foreground(lipgloss.Color("205")).Bold(true)
+			style = focusedStyle.Padding(1, 2).Bold(true)
 			s += style.Render("> " + button)
 		} else {
 			s += style.Render("  " + button)
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"log"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// prompt is a one line dialog shown under the current view. It either asks
+// to confirm an action or asks for a line of text, and runs done with the
+// answer once the user accepts.
+type prompt struct {
+	active  bool
+	confirm bool
+	label   string
+	value   string
+	done    func(m *model, value string) error
+}
+
+// askConfirm opens a yes/no prompt that runs done when the user says yes.
+func (m *model) askConfirm(label string, done func(m *model) error) {
+	m.message = ""
+	m.prompt = prompt{
+		active:  true,
+		confirm: true,
+		label:   label,
+		done:    func(m *model, _ string) error { return done(m) },
+	}
+}
+
+// askText opens a text prompt filled with value that runs done with the text
+// the user enters.
+func (m *model) askText(label, value string, done func(m *model, value string) error) {
+	m.message = ""
+	m.prompt = prompt{
+		active: true,
+		label:  label,
+		value:  value,
+		done:   done,
+	}
+}
+
+func (m *model) updatePrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := &m.prompt
+	accept := false
+
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.prompt = prompt{}
+		return m, nil
+	case "enter":
+		accept = !p.confirm
+	case "backspace":
+		if !p.confirm && len(p.value) > 0 {
+			r := []rune(p.value)
+			p.value = string(r[:len(r)-1])
+		}
+	default:
+		if p.confirm {
+			switch msg.String() {
+			case "y", "Y":
+				accept = true
+			case "n", "N":
+				m.prompt = prompt{}
+			}
+		} else if msg.Type == tea.KeyRunes {
+			p.value += string(msg.Runes)
+		} else if msg.Type == tea.KeySpace {
+			p.value += " "
+		}
+	}
+
+	if accept {
+		done, value := p.done, p.value
+		m.prompt = prompt{}
+		if err := done(m, value); err != nil {
+			log.Printf("event=\"prompt_action_failed\" err=\"%v\"", err)
+			m.message = "Error: " + err.Error()
+		}
+	}
+	return m, nil
+}
+
+func (m model) viewPrompt() string {
+	if m.prompt.confirm {
+		return focusedStyle.Render(m.prompt.label+" (y/n)") + "\n"
+	}
+	return focusedStyle.Render(m.prompt.label+": ") + m.prompt.value + subtleStyle.Render("_") + "\n" +
+		subtleStyle.Render("enter: accept\tesc: cancel") + "\n"
+}
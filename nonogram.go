@@ -13,6 +13,8 @@
 // The primary action will color a cell with the FilledTile rune.
 // The secondary action will mark a cell as a known empty tile with the KnownEmptyTile rune.
 // The puzzle is evaluated as a solve if the save and solution tomography matches for both rows and columns.
+// Each row and column is also checked on its own after every move so that
+// satisfied clues can be greyed out and impossible lines flagged.
 
 package main
 
@@ -30,19 +32,34 @@ const (
 )
 
 var (
-	renderStyles = map[rune]lipgloss.Style{
-		FilledTile:     lipgloss.NewStyle().Background(lipgloss.Color("255")).Foreground(lipgloss.Color("255")), // White background
-		KnownEmptyTile: lipgloss.NewStyle().Foreground(lipgloss.Color("245")),                                   // Light grey foreground
-		EmptyTile:      lipgloss.NewStyle().Foreground(lipgloss.Color("250")),                                   // Dim grey foreground
-	}
-	highlightStyle = lipgloss.NewStyle().Background(lipgloss.Color("205")).Foreground(lipgloss.Color("255")) // Magenta background for the cursor
-	hintStyle      = lipgloss.NewStyle()
-	renderRunes    = map[rune]string{
-		FilledTile:     "⬤",
-		KnownEmptyTile: "⊗",
-		EmptyTile:      "◯",
-	}
-	cellWidth = 3
+	nonogramGlyphs = map[string]GlyphSet{
+		unicodeGlyphs: {
+			FilledTile:     "⬤",
+			KnownEmptyTile: "⊗",
+			EmptyTile:      "◯",
+		},
+		asciiGlyphs: {
+			FilledTile:     "#",
+			KnownEmptyTile: "x",
+			EmptyTile:      ".",
+		},
+		emojiGlyphs: {
+			FilledTile:     "⬛",
+			KnownEmptyTile: "❌",
+			EmptyTile:      "⬜",
+		},
+	}
+	// cellWidths lists the zoom levels from largest to most compact.
+	cellWidths = []int{3, 2, 1}
+)
+
+// lineStatus describes how a single row or column compares to its clue.
+type lineStatus uint
+
+const (
+	lineOpen lineStatus = iota
+	lineSatisfied
+	lineBroken
 )
 
 type NonogramEngine struct {
@@ -51,6 +68,10 @@ type NonogramEngine struct {
 	colHints      [][]int
 	hintRowWidth  int
 	hintColHeight int
+	rowStatus     []lineStatus
+	colStatus     []lineStatus
+	zoom          int
+	viewport      gridViewport
 }
 
 func (e *NonogramEngine) New(l Level, s *Save) (GameEngine, error) {
@@ -60,30 +81,66 @@ func (e *NonogramEngine) New(l Level, s *Save) (GameEngine, error) {
 	}
 
 	e.updateHintInfo()
+	e.updateLineStatus()
+	e.viewport.fit(0, 0, e.cellWidth(), e.GetWidth(), e.GetHeight(), 0, 0)
 
 	return e, nil
 }
 
 func (e *NonogramEngine) PrimaryAction(x, y int) error {
-	return e.setCellValue(x, y, FilledTile)
+	if err := e.setCellValue(x, y, FilledTile); err != nil {
+		return err
+	}
+	e.updateLineStatus()
+	return nil
 }
 
 func (e *NonogramEngine) SecondaryAction(x, y int) error {
-	return e.setCellValue(x, y, KnownEmptyTile)
+	if err := e.setCellValue(x, y, KnownEmptyTile); err != nil {
+		return err
+	}
+	e.updateLineStatus()
+	return nil
+}
+
+// dragPaints lets a drag fill or mark a run of cells.
+func (e *NonogramEngine) dragPaints() {}
+
+func (e *NonogramEngine) ClearCell(x, y int) error {
+	if err := e.Engine.ClearCell(x, y); err != nil {
+		return err
+	}
+	e.updateLineStatus()
+	return nil
 }
 
+// Evaluate reports whether every row and column matches its clue. Known
+// empty marks count as blank, so they never affect the result.
 func (e *NonogramEngine) Evaluate() (bool, error) {
 	r, c := generateTomography(e.Save.State)
-
-	result := cmpTomo(r, e.rowHints) && cmpTomo(c, e.colHints)
-	return result, nil
+	if len(r) != len(e.rowHints) || len(c) != len(e.colHints) {
+		return false, nil
+	}
+	for i := range r {
+		if !cmpLine(r[i], e.rowHints[i]) {
+			return false, nil
+		}
+	}
+	for i := range c {
+		if !cmpLine(c[i], e.colHints[i]) {
+			return false, nil
+		}
+	}
+	return true, nil
 }
 
 func (e *NonogramEngine) View(m model) string {
-	g := e.gridView(m)
-	r := e.rowHintView()
-	c := e.colHintView()
 	h := e.helpView(m)
+	e.viewport.fitTo(m, e.hintRowWidth, e.hintColHeight+lipgloss.Height(h), e.cellWidth(), e.GetWidth(), e.GetHeight())
+
+	g := e.gridView(m)
+	r := e.rowHintView(m)
+	c := e.colHintView(m)
 
 	spacer := hintStyle.Width(e.hintRowWidth).Height(e.hintColHeight).Render("")
 
@@ -94,32 +151,61 @@ func (e *NonogramEngine) View(m model) string {
 	return s
 }
 
+// CellAt maps a position within the rendered view to grid coordinates. The
+// grid sits below the column hints and to the right of the row hints.
+func (e *NonogramEngine) CellAt(col, row int) (int, int, bool) {
+	return e.viewport.cellAt(col-e.hintRowWidth, row-e.hintColHeight, e.cellWidth())
+}
+
+// GlyphSets returns the glyphs nonogram tiles can be drawn with.
+func (e *NonogramEngine) GlyphSets() map[string]GlyphSet {
+	return nonogramGlyphs
+}
+
+// Zoom steps through the cell widths. A positive step makes cells larger and
+// a negative step makes them more compact so big puzzles fit on screen.
+func (e *NonogramEngine) Zoom(step int) {
+	e.zoom = zoomBy(e.zoom, step)
+}
+
 // --- Private Functions ---
 
-func tileView(c Cell, h bool) string {
+// cellWidth is the width of the current zoom level, widened when the glyph
+// set draws anything wider than that.
+func (e *NonogramEngine) cellWidth() int {
+	return max(cellWidths[e.zoom], widestGlyph(glyphSetFor(nonogramGlyphs)))
+}
+
+func tileView(c Cell, h bool, crosshair bool, width int) string {
 	s, ok := renderStyles[c.value]
 	if !ok {
 		//TODO: structured log out the unknown tile.
 		s = renderStyles[EmptyTile]
 	}
+	if crosshair {
+		s = s.Inherit(crosshairStyle)
+	}
 	if h {
 		s = highlightStyle
 	}
-	r, ok := renderRunes[c.value]
+	glyphs := glyphSetFor(nonogramGlyphs)
+	r, ok := glyphs[c.value]
 	if !ok {
 		//TODO structured log out the unknown tile.
-		r = renderRunes[EmptyTile]
+		r = glyphs[EmptyTile]
 	}
-	return s.Width(cellWidth).AlignHorizontal(lipgloss.Center).Render(r)
+	return s.Width(width).AlignHorizontal(lipgloss.Center).Render(r)
 }
 
 func (e *NonogramEngine) gridView(m model) string {
+	v := e.viewport
 	var rows []string
-	for y, row := range e.Grid {
+	for y := v.offsetY; y < v.offsetY+v.rows; y++ {
 		var rowBuider []string
-		for x, cell := range row {
+		for x := v.offsetX; x < v.offsetX+v.cols; x++ {
 			highlighted := x == m.cursorX && y == m.cursorY
-			cell := tileView(cell, highlighted)
+			crosshair := x == m.cursorX || y == m.cursorY
+			cell := tileView(e.Grid[y][x], highlighted, crosshair, e.cellWidth())
 			rowBuider = append(rowBuider, cell)
 
 		}
@@ -130,33 +216,58 @@ func (e *NonogramEngine) gridView(m model) string {
 	return grid
 }
 
-func (e *NonogramEngine) colHintView() string {
+// hintLineStyle picks the clue style for a line with the given status.
+func hintLineStyle(status lineStatus) lipgloss.Style {
+	switch status {
+	case lineSatisfied:
+		return hintDoneStyle
+	case lineBroken:
+		return hintErrorStyle
+	default:
+		return hintStyle
+	}
+}
+
+func (e *NonogramEngine) colHintView(m model) string {
+	v := e.viewport
+	w := e.cellWidth()
 	var cols []string
-	for _, hints := range e.colHints {
+	for x := v.offsetX; x < v.offsetX+v.cols; x++ {
+		hints := e.colHints[x]
+		style := hintLineStyle(e.colStatus[x])
+		if x == m.cursorX {
+			style = style.Inherit(crosshairStyle).Bold(true)
+		}
 		var cells []string
 		// Pad hints to align to the bottom of the hint area.
 		for i := 0; i < e.hintColHeight-len(hints); i++ {
 			// an empty cell
-			cells = append(cells, hintStyle.Width(cellWidth).Render(" "))
+			cells = append(cells, hintStyle.Width(w).Render(" "))
 		}
 		// Add hint cells.
 		for _, h := range hints {
-			cells = append(cells, hintStyle.Width(cellWidth).Align(lipgloss.Center).Render(fmt.Sprintf("%d", h)))
+			cells = append(cells, style.Width(w).Align(lipgloss.Center).Render(colHintLabel(h, w)))
 		}
 		cols = append(cols, lipgloss.JoinVertical(lipgloss.Left, cells...))
 	}
 	return lipgloss.JoinHorizontal(lipgloss.Top, cols...)
 }
 
-func (e *NonogramEngine) rowHintView() string {
+func (e *NonogramEngine) rowHintView(m model) string {
+	v := e.viewport
 	var rows []string
-	for _, hints := range e.rowHints {
+	for y := v.offsetY; y < v.offsetY+v.rows; y++ {
+		hints := e.rowHints[y]
 		var b []string
 		for _, h := range hints {
 			b = append(b, fmt.Sprintf("%2d", h))
 		}
 		s := strings.Join(b, " ")
-		rows = append(rows, hintStyle.Width(e.hintRowWidth).Align(lipgloss.Right).Render(s))
+		style := hintLineStyle(e.rowStatus[y])
+		if y == m.cursorY {
+			style = style.Inherit(crosshairStyle).Bold(true)
+		}
+		rows = append(rows, style.Width(e.hintRowWidth).Align(lipgloss.Right).Render(s))
 	}
 	s := lipgloss.JoinVertical(lipgloss.Left, rows...)
 	return s
@@ -169,14 +280,42 @@ func (e *NonogramEngine) helpView(m model) string {
 	} else {
 		help += "\n"
 	}
-	help += "arrow keys or hjkl to move\n"
+	help += e.statusView(m)
+	help += "arrow keys or hjkl to move\t+/-: zoom\n"
 	help += "Press 'esc' to return to the menu.\n"
+	if e.viewport.clipped(e.GetWidth(), e.GetHeight()) {
+		v := e.viewport
+		help += fmt.Sprintf("Showing columns %d-%d of %d, rows %d-%d of %d\n",
+			v.offsetX+1, v.offsetX+v.cols, e.GetWidth(),
+			v.offsetY+1, v.offsetY+v.rows, e.GetHeight())
+	}
 	if e.Save.Solved {
 		help += "Congrats!\n"
 	}
 	return help
 }
 
+// statusView reports the filled run under the cursor and how many cells of
+// the cursor's row and column are filled against what their clues need.
+func (e *NonogramEngine) statusView(m model) string {
+	row := e.rowLine(m.cursorY)
+	col := e.colLine(m.cursorX)
+	return fmt.Sprintf("Run: %d across, %d down\tRow: %d/%d\tCol: %d/%d\n",
+		runAt(row, m.cursorX), runAt(col, m.cursorY),
+		countFilled(row), sumHints(e.rowHints[m.cursorY]),
+		countFilled(col), sumHints(e.colHints[m.cursorX]))
+}
+
+// colHintLabel formats a column clue to fit its cell. Single column cells
+// can't hold two digits, so clues of ten or more are shown as letters, with
+// a standing for 10.
+func colHintLabel(h, width int) string {
+	if width < 2 && h >= 10 && h < 36 {
+		return string(rune('a' + h - 10))
+	}
+	return fmt.Sprintf("%d", h)
+}
+
 func generateTomography(state string) ([][]int, [][]int) {
 	s := strings.Split(state, "\n")
 	h := len(s)
@@ -260,20 +399,173 @@ func (e *NonogramEngine) updateHintInfo() {
 	e.hintRowWidth *= 3 // Each hint is typically 3 characters " 4 " or "10 "
 }
 
-// cmpTomo compares two sets of tomography hints for equality.
-func cmpTomo(a [][]int, b [][]int) bool {
+// updateLineStatus checks every row and column of the grid against its clue
+// and refreshes the solved flag on the save.
+func (e *NonogramEngine) updateLineStatus() {
+	e.rowStatus = make([]lineStatus, len(e.rowHints))
+	for y := range e.rowHints {
+		e.rowStatus[y] = checkLine(e.rowLine(y), e.rowHints[y])
+	}
+
+	e.colStatus = make([]lineStatus, len(e.colHints))
+	for x := range e.colHints {
+		e.colStatus[x] = checkLine(e.colLine(x), e.colHints[x])
+	}
+
+	solved, err := e.Evaluate()
+	e.Save.Solved = err == nil && solved
+}
+
+// rowLine returns the values of a row of the grid.
+func (e *NonogramEngine) rowLine(y int) []rune {
+	var line []rune
+	if y >= 0 && y < len(e.Grid) {
+		for _, cell := range e.Grid[y] {
+			line = append(line, cell.value)
+		}
+	}
+	return line
+}
+
+// colLine returns the values of a column of the grid.
+func (e *NonogramEngine) colLine(x int) []rune {
+	var line []rune
+	for y := range e.Grid {
+		if x >= 0 && x < len(e.Grid[y]) {
+			line = append(line, e.Grid[y][x].value)
+		}
+	}
+	return line
+}
+
+// runAt returns the length of the filled run that covers position i of a
+// line, or zero when that cell isn't filled.
+func runAt(line []rune, i int) int {
+	if i < 0 || i >= len(line) || line[i] != FilledTile {
+		return 0
+	}
+	start, end := i, i
+	for start > 0 && line[start-1] == FilledTile {
+		start--
+	}
+	for end < len(line)-1 && line[end+1] == FilledTile {
+		end++
+	}
+	return end - start + 1
+}
+
+// countFilled returns the number of filled cells in a line.
+func countFilled(line []rune) int {
+	n := 0
+	for _, r := range line {
+		if r == FilledTile {
+			n++
+		}
+	}
+	return n
+}
+
+// sumHints returns the number of filled cells a clue asks for.
+func sumHints(hints []int) int {
+	n := 0
+	for _, h := range hints {
+		n += h
+	}
+	return n
+}
+
+// checkLine compares a single row or column with its clue. A line is
+// satisfied when its filled runs match the clue exactly, and broken when no
+// assignment of its undecided cells could ever match it.
+func checkLine(line []rune, hints []int) lineStatus {
+	if cmpLine(lineTomography(line), hints) {
+		return lineSatisfied
+	}
+	if !lineFits(line, hints) {
+		return lineBroken
+	}
+	return lineOpen
+}
+
+// lineTomography returns the run lengths of filled cells in a line, using the
+// same {0} convention as generateTomography for a line with no runs.
+func lineTomography(line []rune) []int {
+	var runs []int
+	c := 0
+	for _, r := range line {
+		if r == FilledTile {
+			c++
+			continue
+		}
+		if c > 0 {
+			runs = append(runs, c)
+		}
+		c = 0
+	}
+	if c > 0 {
+		runs = append(runs, c)
+	}
+	if len(runs) == 0 {
+		runs = append(runs, 0)
+	}
+	return runs
+}
+
+// lineFits reports whether the undecided cells of a line can still be filled
+// or left blank so that the line matches the clue. Filled cells must be
+// covered by a run and known empty cells must not be.
+func lineFits(line []rune, hints []int) bool {
+	if len(hints) == 1 && hints[0] == 0 {
+		hints = nil
+	}
+	n := len(line)
+	memo := make(map[[2]int]bool)
+	var fits func(i, j int) bool
+	fits = func(i, j int) bool {
+		if i >= n {
+			return j == len(hints)
+		}
+		key := [2]int{i, j}
+		if v, ok := memo[key]; ok {
+			return v
+		}
+		result := false
+		// Leave this cell blank.
+		if line[i] != FilledTile {
+			result = fits(i+1, j)
+		}
+		// Start the next run at this cell.
+		if !result && j < len(hints) && i+hints[j] <= n {
+			end := i + hints[j]
+			ok := true
+			for k := i; k < end; k++ {
+				if line[k] == KnownEmptyTile {
+					ok = false
+					break
+				}
+			}
+			if ok && end < n && line[end] == FilledTile {
+				ok = false
+			}
+			if ok {
+				result = fits(end+1, j+1)
+			}
+		}
+		memo[key] = result
+		return result
+	}
+	return fits(0, 0)
+}
+
+// cmpLine compares the tomography of a single line for equality.
+func cmpLine(a []int, b []int) bool {
 	if len(a) != len(b) {
 		return false
 	}
-	for i, r := range a {
-		if len(r) != len(b[i]) {
+	for i, v := range a {
+		if v != b[i] {
 			return false
 		}
-		for j, v := range r {
-			if v != b[i][j] {
-				return false
-			}
-		}
 	}
 	return true
 }
@@ -0,0 +1,147 @@
+package main
+
+import "strings"
+
+// nonogramSearchBudget caps how many guesses the solver makes before it
+// gives up, so checking a large or badly underdetermined puzzle can't hang
+// the editor.
+const nonogramSearchBudget = 20000
+
+// countNonogramSolutions counts the fillings of a level's grid that match the
+// clues of its solution, stopping once limit is reached. Cells given in the
+// initial state are fixed. ok is false when the search ran out of budget.
+//
+// Lines are solved by trying each undecided cell both ways with lineFits and
+// keeping whichever value is forced. When that stalls, the solver guesses
+// and backtracks.
+func countNonogramSolutions(l Level, limit int) (int, bool) {
+	rowHints, colHints := generateTomography(l.Solution)
+	h, w := len(rowHints), len(colHints)
+
+	grid := make([][]rune, h)
+	initial := strings.Split(l.Initial, "\n")
+	for y := range grid {
+		grid[y] = make([]rune, w)
+		for x := range grid[y] {
+			grid[y][x] = EmptyTile
+			if y < len(initial) && x < len(initial[y]) {
+				switch r := rune(initial[y][x]); r {
+				case FilledTile:
+					grid[y][x] = FilledTile
+				case EmptyTile, '.':
+				default:
+					grid[y][x] = KnownEmptyTile
+				}
+			}
+		}
+	}
+
+	s := &nonogramSolver{rowHints: rowHints, colHints: colHints, limit: limit, budget: nonogramSearchBudget}
+	s.search(grid)
+	return s.count, s.budget >= 0
+}
+
+type nonogramSolver struct {
+	rowHints [][]int
+	colHints [][]int
+	limit    int
+	count    int
+	budget   int
+}
+
+func (s *nonogramSolver) search(grid [][]rune) {
+	if s.count >= s.limit || s.budget < 0 {
+		return
+	}
+	s.budget--
+	if !s.propagate(grid) {
+		return
+	}
+	for y, row := range grid {
+		for x, r := range row {
+			if r != EmptyTile {
+				continue
+			}
+			for _, guess := range []rune{FilledTile, KnownEmptyTile} {
+				next := copyGrid(grid)
+				next[y][x] = guess
+				s.search(next)
+				if s.count >= s.limit || s.budget < 0 {
+					return
+				}
+			}
+			return
+		}
+	}
+	s.count++
+}
+
+// propagate fills in every cell forced by its row or column clue until
+// nothing changes. It returns false when the grid contradicts a clue.
+func (s *nonogramSolver) propagate(grid [][]rune) bool {
+	for changed := true; changed; {
+		changed = false
+		for y := range grid {
+			line := grid[y]
+			ok, c := solveLine(line, s.rowHints[y])
+			if !ok {
+				return false
+			}
+			changed = changed || c
+		}
+		for x := range s.colHints {
+			line := make([]rune, len(grid))
+			for y := range grid {
+				line[y] = grid[y][x]
+			}
+			ok, c := solveLine(line, s.colHints[x])
+			if !ok {
+				return false
+			}
+			for y := range grid {
+				grid[y][x] = line[y]
+			}
+			changed = changed || c
+		}
+	}
+	return true
+}
+
+// solveLine decides every undecided cell of a line that can only take one
+// value. It reports whether the line can still match its clue and whether
+// any cell changed.
+func solveLine(line []rune, hints []int) (bool, bool) {
+	if !lineFits(line, hints) {
+		return false, false
+	}
+	changed := false
+	for i, r := range line {
+		if r != EmptyTile {
+			continue
+		}
+		line[i] = FilledTile
+		canFill := lineFits(line, hints)
+		line[i] = KnownEmptyTile
+		canEmpty := lineFits(line, hints)
+		switch {
+		case canFill && canEmpty:
+			line[i] = EmptyTile
+		case canFill:
+			line[i] = FilledTile
+			changed = true
+		case canEmpty:
+			changed = true
+		default:
+			return false, false
+		}
+	}
+	return true, changed
+}
+
+func copyGrid(grid [][]rune) [][]rune {
+	next := make([][]rune, len(grid))
+	for y := range grid {
+		next[y] = append([]rune(nil), grid[y]...)
+	}
+	return next
+}
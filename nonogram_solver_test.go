@@ -0,0 +1,61 @@
+package main
+
+import "testing"
+
+func TestCountNonogramSolutions(t *testing.T) {
+	testCases := []struct {
+		name      string
+		initial   string
+		solution  string
+		wantCount int
+	}{
+		{
+			name:      "unique cross",
+			initial:   "   \n   \n   ",
+			solution:  " 1 \n111\n 1 ",
+			wantCount: 1,
+		},
+		{
+			name:      "ambiguous diagonal",
+			initial:   "  \n  ",
+			solution:  "1 \n 1",
+			wantCount: 2,
+		},
+		{
+			name:      "given settles the diagonal",
+			initial:   "1 \n  ",
+			solution:  "1 \n 1",
+			wantCount: 1,
+		},
+		{
+			name:      "known empty given settles the diagonal",
+			initial:   " X\n  ",
+			solution:  "1 \n 1",
+			wantCount: 1,
+		},
+		{
+			name:      "given contradicts the clues",
+			initial:   "11\n  ",
+			solution:  "1 \n 1",
+			wantCount: 0,
+		},
+		{
+			name:      "empty grid",
+			initial:   "  \n  ",
+			solution:  "  \n  ",
+			wantCount: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			count, ok := countNonogramSolutions(Level{Initial: tc.initial, Solution: tc.solution}, 2)
+			if !ok {
+				t.Fatalf("solver gave up")
+			}
+			if count != tc.wantCount {
+				t.Errorf("countNonogramSolutions() = %d, want %d", count, tc.wantCount)
+			}
+		})
+	}
+}
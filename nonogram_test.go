@@ -109,3 +109,160 @@ func TestGenerateTomography(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckLine(t *testing.T) {
+	testCases := []struct {
+		name  string
+		line  string
+		hints []int
+		want  lineStatus
+	}{
+		{name: "empty line open", line: "     ", hints: []int{2, 1}, want: lineOpen},
+		{name: "exact match", line: "11 1 ", hints: []int{2, 1}, want: lineSatisfied},
+		{name: "exact match with X marks", line: "11X1X", hints: []int{2, 1}, want: lineSatisfied},
+		{name: "zero clue satisfied by blanks", line: "  X  ", hints: []int{0}, want: lineSatisfied},
+		{name: "zero clue broken by fill", line: "  1  ", hints: []int{0}, want: lineBroken},
+		{name: "partial fill still fits", line: "1    ", hints: []int{2, 1}, want: lineOpen},
+		{name: "run too long", line: "111  ", hints: []int{2, 1}, want: lineBroken},
+		{name: "too many runs", line: "1 1 1", hints: []int{2, 1}, want: lineBroken},
+		{name: "X blocks the only space", line: "X X  ", hints: []int{3}, want: lineBroken},
+		{name: "X leaves room", line: "X    ", hints: []int{3}, want: lineOpen},
+		{name: "X splits a needed run", line: "  X  ", hints: []int{4}, want: lineBroken},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := checkLine([]rune(tc.line), tc.hints); got != tc.want {
+				t.Errorf("checkLine(%q, %v) = %v, want %v", tc.line, tc.hints, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNonogramEvaluateWithKnownEmpties(t *testing.T) {
+	level := Level{
+		ID:       1,
+		Name:     "Known Empties",
+		Engine:   "nonogram",
+		Initial:  "   \n   \n   ",
+		Solution: "1 1\n 1 \n1 1",
+	}
+	game, err := new(NonogramEngine).New(level, nil)
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+	e := game.(*NonogramEngine)
+
+	moves := []struct {
+		x, y    int
+		primary bool
+	}{
+		{0, 0, true}, {1, 0, false}, {2, 0, true},
+		{0, 1, false}, {1, 1, true}, {2, 1, false},
+		{0, 2, true}, {1, 2, false},
+	}
+	for _, mv := range moves {
+		if mv.primary {
+			e.PrimaryAction(mv.x, mv.y)
+		} else {
+			e.SecondaryAction(mv.x, mv.y)
+		}
+	}
+
+	if e.GetSave().Solved {
+		t.Fatalf("expected puzzle to be unsolved before the final move")
+	}
+	if e.rowStatus[0] != lineSatisfied || e.rowStatus[1] != lineSatisfied {
+		t.Errorf("expected first two rows satisfied, got %v", e.rowStatus)
+	}
+	if e.rowStatus[2] != lineOpen {
+		t.Errorf("expected last row open, got %v", e.rowStatus[2])
+	}
+
+	e.PrimaryAction(2, 2)
+	if solved, _ := e.Evaluate(); !solved {
+		t.Errorf("expected Evaluate to report solved with X marks on the board")
+	}
+	if !e.GetSave().Solved {
+		t.Errorf("expected save to be marked solved with X marks on the board")
+	}
+
+	e.PrimaryAction(1, 2)
+	if e.GetSave().Solved {
+		t.Errorf("expected save to be unsolved after filling an extra cell")
+	}
+	if e.rowStatus[2] != lineBroken || e.colStatus[1] != lineBroken {
+		t.Errorf("expected last row and middle column to be broken, got rows %v cols %v", e.rowStatus, e.colStatus)
+	}
+}
+
+func TestNonogramCellAt(t *testing.T) {
+	level := Level{
+		ID:       1,
+		Name:     "Layout",
+		Engine:   "nonogram",
+		Initial:  "   \n   ",
+		Solution: "1 1\n111",
+	}
+	game, err := new(NonogramEngine).New(level, nil)
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+	e := game.(*NonogramEngine)
+
+	// Row hints are at most two clues wide and column hints at most one tall.
+	left, top := e.hintRowWidth, e.hintColHeight
+	cellWidth := e.cellWidth()
+	testCases := []struct {
+		name     string
+		col, row int
+		wantX    int
+		wantY    int
+		wantOK   bool
+	}{
+		{name: "first cell", col: left, row: top, wantX: 0, wantY: 0, wantOK: true},
+		{name: "inside first cell", col: left + cellWidth - 1, row: top, wantX: 0, wantY: 0, wantOK: true},
+		{name: "last cell", col: left + 2*cellWidth, row: top + 1, wantX: 2, wantY: 1, wantOK: true},
+		{name: "row hints", col: left - 1, row: top, wantOK: false},
+		{name: "column hints", col: left, row: top - 1, wantOK: false},
+		{name: "past the grid", col: left + 3*cellWidth, row: top, wantOK: false},
+		{name: "below the grid", col: left, row: top + 2, wantOK: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			x, y, ok := e.CellAt(tc.col, tc.row)
+			if ok != tc.wantOK {
+				t.Fatalf("CellAt(%d, %d) ok = %v, want %v", tc.col, tc.row, ok, tc.wantOK)
+			}
+			if ok && (x != tc.wantX || y != tc.wantY) {
+				t.Errorf("CellAt(%d, %d) = (%d, %d), want (%d, %d)", tc.col, tc.row, x, y, tc.wantX, tc.wantY)
+			}
+		})
+	}
+}
+
+func TestRunAt(t *testing.T) {
+	testCases := []struct {
+		name string
+		line string
+		i    int
+		want int
+	}{
+		{name: "empty cell", line: "1 11", i: 1, want: 0},
+		{name: "single run", line: "1 11", i: 0, want: 1},
+		{name: "start of run", line: "1 111X", i: 2, want: 3},
+		{name: "middle of run", line: "1 111X", i: 3, want: 3},
+		{name: "end of line", line: "X 111", i: 4, want: 3},
+		{name: "known empty", line: "1X1", i: 1, want: 0},
+		{name: "out of range", line: "11", i: 5, want: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := runAt([]rune(tc.line), tc.i); got != tc.want {
+				t.Errorf("runAt(%q, %d) = %d, want %d", tc.line, tc.i, got, tc.want)
+			}
+		})
+	}
+}
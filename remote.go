@@ -0,0 +1,146 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// Downloads are capped in size and time so a slow or hostile server can't
+// hang the importer or exhaust memory.
+var (
+	maxDownloadSize int64 = 32 << 20
+	httpClient            = &http.Client{Timeout: 30 * time.Second}
+)
+
+// isURL reports whether ref is an http or https URL rather than a file path.
+func isURL(ref string) bool {
+	return strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://")
+}
+
+// download fetches rawURL. When sum is set the body must have that hex
+// encoded SHA-256 checksum.
+func download(rawURL, sum string) ([]byte, error) {
+	log.Printf("event=\"download\" url=\"%s\"", rawURL)
+	resp, err := httpClient.Get(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", rawURL, resp.Status)
+	}
+	if resp.ContentLength > maxDownloadSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", rawURL, maxDownloadSize)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxDownloadSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", rawURL, maxDownloadSize)
+	}
+
+	if sum != "" {
+		got := sha256.Sum256(data)
+		if !strings.EqualFold(hex.EncodeToString(got[:]), sum) {
+			return nil, fmt.Errorf("checksum mismatch for %s: got %x, want %s", rawURL, got, sum)
+		}
+	}
+	return data, nil
+}
+
+// ImportURL downloads a level pack or bundle and imports it. When sum is set
+// the download must have that SHA-256 checksum. It returns the imported
+// packs.
+func (s *Store) ImportURL(rawURL, sum string) ([]*LevelPack, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	data, err := download(rawURL, sum)
+	if err != nil {
+		return nil, err
+	}
+
+	if format := bundleArchiveFormat(u.Path); format != "" {
+		tmp, err := os.CreateTemp("", "chronical-*."+format)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(tmp.Name())
+		_, err = tmp.Write(data)
+		if cerr := tmp.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, err
+		}
+		return s.ImportBundle(tmp.Name())
+	}
+
+	levelPackYAML, err := DecodeLevelPackFile(path.Base(u.Path), data)
+	if err != nil {
+		return nil, err
+	}
+	var pack *LevelPack
+	err = s.InTx(func(tx *Store) error {
+		pack, err = tx.importPack(levelPackYAML)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []*LevelPack{pack}, nil
+}
+
+// PackIndex is a JSON document listing level packs available to install.
+type PackIndex struct {
+	Packs []PackIndexEntry `json:"packs"`
+}
+
+// PackIndexEntry is one pack in a pack index. URL may be relative to the
+// index, and SHA256, when set, is checked on install.
+type PackIndexEntry struct {
+	Name        string `json:"name"`
+	Author      string `json:"author"`
+	Version     int    `json:"version"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+	SHA256      string `json:"sha256"`
+}
+
+// FetchPackIndex downloads the pack index at indexURL and resolves the URLs
+// of its packs against it.
+func FetchPackIndex(indexURL string) (*PackIndex, error) {
+	base, err := url.Parse(indexURL)
+	if err != nil {
+		return nil, err
+	}
+	data, err := download(indexURL, "")
+	if err != nil {
+		return nil, err
+	}
+	var index PackIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("invalid pack index: %w", err)
+	}
+	for i, entry := range index.Packs {
+		ref, err := url.Parse(entry.URL)
+		if err != nil || entry.URL == "" {
+			return nil, fmt.Errorf("invalid url %q for pack %s", entry.URL, entry.Name)
+		}
+		index.Packs[i].URL = base.ResolveReference(ref).String()
+	}
+	return &index, nil
+}
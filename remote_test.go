@@ -0,0 +1,145 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newPackServer(t *testing.T, files map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(data))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestImportURL(t *testing.T) {
+	srv := newPackServer(t, map[string]string{"/packs/a.yaml": bundlePackA})
+
+	tests := []struct {
+		name    string
+		path    string
+		sum     string
+		wantErr string
+	}{
+		{"no checksum", "/packs/a.yaml", "", ""},
+		{"matching checksum", "/packs/a.yaml", sha256Hex(bundlePackA), ""},
+		{"checksum mismatch", "/packs/a.yaml", sha256Hex("something else"), "checksum mismatch"},
+		{"not found", "/packs/missing.yaml", "", "404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			packs, err := store.ImportURL(srv.URL+tt.path, tt.sum)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				if packs, _ := store.GetAllLevelPacks(); len(packs) != 0 {
+					t.Errorf("expected nothing imported, got %+v", packs)
+				}
+				return
+			}
+			if err != nil || len(packs) != 1 {
+				t.Fatalf("expected 1 pack imported, got %d, %v", len(packs), err)
+			}
+			if _, err := store.FindLevelPack("Pack A"); err != nil {
+				t.Errorf("expected Pack A to be imported: %v", err)
+			}
+		})
+	}
+}
+
+func TestImportURLLimits(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow.yaml" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Write([]byte(bundlePackA))
+	}))
+	defer srv.Close()
+
+	oldSize, oldClient := maxDownloadSize, httpClient
+	defer func() { maxDownloadSize, httpClient = oldSize, oldClient }()
+	httpClient = &http.Client{Timeout: 50 * time.Millisecond}
+
+	store := newTestStore(t)
+	if _, err := store.ImportURL(srv.URL+"/slow.yaml", ""); err == nil {
+		t.Error("expected a slow server to time out")
+	}
+
+	maxDownloadSize = 10
+	if _, err := store.ImportURL(srv.URL+"/big.yaml", ""); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("expected a size limit error, got %v", err)
+	}
+}
+
+func TestImportURLBundle(t *testing.T) {
+	src := newTestStore(t)
+	dir := writeBundleDir(t, map[string]string{"a.yaml": bundlePackA, "b.json": bundlePackB})
+	if _, err := src.ImportBundle(dir); err != nil {
+		t.Fatalf("failed to import bundle: %v", err)
+	}
+	out := filepath.Join(t.TempDir(), "packs.tar.gz")
+	if err := src.ExportBundle(out, false); err != nil {
+		t.Fatalf("failed to export bundle: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read bundle: %v", err)
+	}
+
+	srv := newPackServer(t, map[string]string{"/packs.tar.gz": string(data)})
+	store := newTestStore(t)
+	packs, err := store.ImportURL(srv.URL+"/packs.tar.gz", "")
+	if err != nil || len(packs) != 2 {
+		t.Fatalf("expected 2 packs imported, got %d, %v", len(packs), err)
+	}
+}
+
+func TestFetchPackIndex(t *testing.T) {
+	srv := newPackServer(t, map[string]string{
+		"/index/index.json": `{"packs": [
+			{"name": "Pack A", "version": 2, "url": "packs/a.yaml", "sha256": "` + sha256Hex(bundlePackA) + `"},
+			{"name": "Pack B", "url": "https://example.com/b.json"}
+		]}`,
+		"/index/packs/a.yaml": bundlePackA,
+	})
+
+	index, err := FetchPackIndex(srv.URL + "/index/index.json")
+	if err != nil {
+		t.Fatalf("failed to fetch pack index: %v", err)
+	}
+	if len(index.Packs) != 2 {
+		t.Fatalf("expected 2 packs, got %+v", index.Packs)
+	}
+	if want := srv.URL + "/index/packs/a.yaml"; index.Packs[0].URL != want {
+		t.Errorf("expected relative url resolved to %s, got %s", want, index.Packs[0].URL)
+	}
+	if want := "https://example.com/b.json"; index.Packs[1].URL != want {
+		t.Errorf("expected absolute url kept as %s, got %s", want, index.Packs[1].URL)
+	}
+
+	store := newTestStore(t)
+	if _, err := store.ImportURL(index.Packs[0].URL, index.Packs[0].SHA256); err != nil {
+		t.Errorf("failed to install pack from index: %v", err)
+	}
+}
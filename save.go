@@ -3,9 +3,10 @@ package main
 import "time"
 
 type Save struct {
-	LevelID   int
-	State     string
-	Solved    bool
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	LevelID       int
+	LevelIdentity string
+	State         string
+	Solved        bool
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
 }
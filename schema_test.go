@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestLevelPackSchema(t *testing.T) {
+	schema := levelPackSchema()
+
+	if got := schema["required"]; !reflect.DeepEqual(got, []string{"name", "levels"}) {
+		t.Errorf("unexpected required pack fields %v", got)
+	}
+	levels := schema["properties"].(map[string]any)["levels"].(map[string]any)
+	level := levels["items"].(map[string]any)
+	if got := level["required"]; !reflect.DeepEqual(got, []string{"name", "solution", "engine"}) {
+		t.Errorf("unexpected required level fields %v", got)
+	}
+	if level["properties"].(map[string]any)["width"].(map[string]any)["type"] != "integer" {
+		t.Errorf("expected width to be an integer")
+	}
+}
+
+// TestLevelPackSchemaMatchesYAML checks that every key in the bundled packs
+// is described by the schema, since the schema is generated from json tags
+// but packs are usually written in YAML.
+func TestLevelPackSchemaMatchesYAML(t *testing.T) {
+	data, err := os.ReadFile("packs/nonogram.yaml")
+	if err != nil {
+		t.Fatalf("failed to read pack: %v", err)
+	}
+	var pack map[string]any
+	if err := yaml.Unmarshal(data, &pack); err != nil {
+		t.Fatalf("failed to parse pack: %v", err)
+	}
+
+	schema := levelPackSchema()
+	checkKeys(t, "pack", pack, schema)
+	level := schema["properties"].(map[string]any)["levels"].(map[string]any)["items"].(map[string]any)
+	for _, l := range pack["levels"].([]any) {
+		checkKeys(t, "level", l.(map[string]any), level)
+	}
+}
+
+func checkKeys(t *testing.T, what string, value map[string]any, schema map[string]any) {
+	t.Helper()
+	properties := schema["properties"].(map[string]any)
+	var keys []string
+	for k := range value {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if _, ok := properties[k]; !ok {
+			t.Errorf("%s key %q is not in the schema", what, k)
+		}
+	}
+}
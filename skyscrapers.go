@@ -0,0 +1,224 @@
+// This file implements the Skyscrapers game logic on the Latin-square
+// engine.
+//
+// Each digit is the height of a building, and a clue around the edge of the
+// grid counts the buildings seen from there looking along its row or
+// column, where a taller building hides every shorter one behind it. The
+// clues are kept in the level's edges, with "." for a place without one.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// skyscraperClue is a count of the buildings seen from one place on the
+// edge, looking along its cells from the nearest.
+type skyscraperClue struct {
+	side   int
+	target int
+	cells  [][2]int
+}
+
+// skyscraperRules are the clues around a level's grid.
+type skyscraperRules struct {
+	size  int
+	clues []skyscraperClue
+	// at indexes the clue at each place on each side, or holds -1.
+	at [4][]int
+}
+
+// skyscraperRulesFor reads the clues of a level from its edges.
+func skyscraperRulesFor(l Level) (*skyscraperRules, error) {
+	n, err := latinSize(l)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(l.Edges) == "" {
+		return nil, errors.New("skyscrapers levels need edges")
+	}
+	sides, err := parseEdgeClues(l.Edges, n, n)
+	if err != nil {
+		return nil, err
+	}
+	r := &skyscraperRules{size: n}
+	for side, clues := range sides {
+		r.at[side] = make([]int, n)
+		for i, v := range clues {
+			r.at[side][i] = -1
+			if v == "." {
+				continue
+			}
+			target, err := strconv.Atoi(v)
+			if err != nil || target < 1 || target > n {
+				return nil, fmt.Errorf("%s edge has an invalid clue %q", sideNames[side], v)
+			}
+			clue := skyscraperClue{side: side, target: target}
+			for j := 0; j < n; j++ {
+				var c [2]int
+				switch side {
+				case sideTop:
+					c = [2]int{i, j}
+				case sideRight:
+					c = [2]int{n - 1 - j, i}
+				case sideBottom:
+					c = [2]int{i, n - 1 - j}
+				case sideLeft:
+					c = [2]int{j, i}
+				}
+				clue.cells = append(clue.cells, c)
+			}
+			r.at[side][i] = len(r.clues)
+			r.clues = append(r.clues, clue)
+		}
+	}
+	return r, nil
+}
+
+// seen counts the buildings a clue sees over the digits filled in from its
+// edge, and reports whether the count is final: either the whole line is
+// filled in or the tallest building hides the rest. The most it could still
+// see is returned too.
+func (r *skyscraperRules) seen(c skyscraperClue, values [][]rune) (int, bool, int) {
+	count, tallest := 0, '0'
+	for i, p := range c.cells {
+		v := values[p[1]][p[0]]
+		if !latinDigit(v, r.size) {
+			return count, false, count + min(int('0'+rune(r.size)-tallest), len(c.cells)-i)
+		}
+		if v > tallest {
+			count, tallest = count+1, v
+		}
+		if int(tallest-'0') == r.size {
+			break
+		}
+	}
+	return count, true, count
+}
+
+// check reports how each clue stands. Clues don't flag cells, since no one
+// building is to blame.
+func (r *skyscraperRules) check(values [][]rune, broken [][]bool) []lineStatus {
+	status := make([]lineStatus, len(r.clues))
+	for i, c := range r.clues {
+		count, final, most := r.seen(c, values)
+		switch {
+		case final && count == c.target:
+			status[i] = lineSatisfied
+		case count > c.target || most < c.target:
+			status[i] = lineBroken
+		}
+	}
+	return status
+}
+
+// validateSkyscrapers refuses levels whose clues are invalid, whose solution
+// breaks the rules or whose givens don't match the solution.
+func validateSkyscrapers(l Level) (string, error) {
+	r, err := skyscraperRulesFor(l)
+	if err != nil {
+		return "", err
+	}
+	return validateLatin(l, r)
+}
+
+// countSkyscrapersSolutions counts the ways to fill in a level's grid, up to
+// limit.
+func countSkyscrapersSolutions(l Level, limit int) (int, bool) {
+	r, err := skyscraperRulesFor(l)
+	if err != nil {
+		return 0, true
+	}
+	return countLatinSolutions(l, r, limit)
+}
+
+type SkyscrapersEngine struct {
+	LatinSquareEngine
+	rules *skyscraperRules
+}
+
+func (e *SkyscrapersEngine) New(l Level, s *Save) (GameEngine, error) {
+	rules, err := skyscraperRulesFor(l)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.newLatin(l, s, rules); err != nil {
+		return nil, err
+	}
+	e.rules = rules
+	return e, nil
+}
+
+func (e *SkyscrapersEngine) View(m model) string {
+	top := e.clueRowView(sideTop, m)
+	bottom := e.clueRowView(sideBottom, m)
+	grid := lipgloss.JoinHorizontal(lipgloss.Top, e.clueColumnView(sideLeft, m), e.gridView(m), e.clueColumnView(sideRight, m))
+	return lipgloss.JoinVertical(lipgloss.Left, top, grid, bottom, e.helpView(m))
+}
+
+// CellAt maps a position within the rendered view to grid coordinates. The
+// grid sits inside a ring of clues one cell wide.
+func (e *SkyscrapersEngine) CellAt(col, row int) (int, int, bool) {
+	col -= latinCellWidth
+	row--
+	if col < 0 || row < 0 {
+		return 0, 0, false
+	}
+	x, y := col/latinCellWidth, row
+	if !e.HasCell(x, y) {
+		return 0, 0, false
+	}
+	return x, y, true
+}
+
+// --- Private Functions ---
+
+// clueView draws the clue at a place on a side, or a gap when there is none.
+func (e *SkyscrapersEngine) clueView(side, i int, active bool) string {
+	style := hintStyle
+	label := ""
+	if c := e.rules.at[side][i]; c >= 0 {
+		style = hintLineStyle(e.clueStatus[c])
+		label = strconv.Itoa(e.rules.clues[c].target)
+	}
+	if active {
+		style = style.Inherit(crosshairStyle).Bold(true)
+	}
+	return style.Width(latinCellWidth).Align(lipgloss.Center).Render(label)
+}
+
+// clueRowView draws the clues above or below the grid, one per column.
+func (e *SkyscrapersEngine) clueRowView(side int, m model) string {
+	corner := hintStyle.Width(latinCellWidth).Render("")
+	cells := []string{corner}
+	for x := 0; x < e.size; x++ {
+		cells = append(cells, e.clueView(side, x, x == m.cursorX))
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, append(cells, corner)...)
+}
+
+// clueColumnView draws the clues left or right of the grid, one per row.
+func (e *SkyscrapersEngine) clueColumnView(side int, m model) string {
+	var cells []string
+	for y := 0; y < e.size; y++ {
+		cells = append(cells, e.clueView(side, y, y == m.cursorY))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, cells...)
+}
+
+func (e *SkyscrapersEngine) gridView(m model) string {
+	var rows []string
+	for y := 0; y < e.size; y++ {
+		var cells []string
+		for x := 0; x < e.size; x++ {
+			cells = append(cells, e.cellView(x, y, m))
+		}
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, cells...))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, rows...)
+}
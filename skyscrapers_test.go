@@ -0,0 +1,143 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	// testSkyscrapersEdges are every clue testLatinSolution has.
+	testSkyscrapersEdges = "3 1 3 2\n2 1 2 3\n1 4 2 2\n2 3 2 1"
+
+	// testSkyscrapersUniqueEdges settle testLatinSolution with three clues.
+	testSkyscrapersUniqueEdges = "3 . . .\n. . . 3\n. 4 . .\n. . . ."
+)
+
+func TestSkyscraperRulesErrors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		edges string
+		want  string
+	}{
+		{name: "no edges", want: "need edges"},
+		{name: "missing side", edges: "1 . . .\n. . . .\n. . . .", want: "want 4"},
+		{name: "short side", edges: "1 . . .\n. . .\n. . . .\n. . . .", want: "right edge has 3 clues"},
+		{name: "clue too big", edges: ". . . .\n. . . .\n. 5 . .\n. . . .", want: "bottom edge has an invalid clue"},
+		{name: "not a number", edges: ". . . .\n. . . .\n. . . .\nx . . .", want: "left edge has an invalid clue"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := skyscraperRulesFor(Level{Solution: testLatinSolution, Edges: tc.edges})
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected an error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestSkyscraperClueStatus(t *testing.T) {
+	// The clue is on the left of the first row, so it looks along the row.
+	testCases := []struct {
+		name   string
+		target string
+		row    string
+		want   lineStatus
+	}{
+		{name: "blank", target: "2", row: "....", want: lineOpen},
+		{name: "seen enough so far", target: "2", row: "13..", want: lineOpen},
+		{name: "seen too many", target: "2", row: "123.", want: lineBroken},
+		{name: "tallest hides the rest", target: "2", row: "14..", want: lineSatisfied},
+		{name: "tallest hides too soon", target: "3", row: "14..", want: lineBroken},
+		{name: "can't see enough", target: "4", row: "2...", want: lineBroken},
+		{name: "full row", target: "3", row: "2134", want: lineSatisfied},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			edges := ". . . .\n. . . .\n. . . .\n" + tc.target + " . . ."
+			r, err := skyscraperRulesFor(Level{Solution: testLatinSolution, Edges: edges})
+			if err != nil {
+				t.Fatalf("invalid rules: %v", err)
+			}
+			values := takuzuGrid(tc.row + "\n....\n....\n....")
+			if got := r.check(values, nil)[0]; got != tc.want {
+				t.Errorf("expected status %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSkyscrapersPlay(t *testing.T) {
+	e := newTestEngine(t, Level{Engine: "skyscrapers", Initial: testLatinBlank, Solution: testLatinSolution, Edges: testSkyscrapersEdges}).(*SkyscrapersEngine)
+	if len(e.rules.clues) != 16 {
+		t.Fatalf("expected 16 clues, got %d", len(e.rules.clues))
+	}
+	for y, row := range strings.Split(testLatinSolution, "\n") {
+		for x, v := range row {
+			if err := e.EnterValue(x, y, v); err != nil {
+				t.Fatalf("failed to enter %c: %v", v, err)
+			}
+		}
+	}
+	if !e.Save.Solved {
+		t.Fatalf("expected the grid to be solved, got %q", e.Save.State)
+	}
+	for i, s := range e.clueStatus {
+		if s != lineSatisfied {
+			t.Errorf("expected clue %d to be satisfied, got %d", i, s)
+		}
+	}
+
+	// Swapping two rows keeps the square latin but breaks the clues.
+	for x, v := range "1324" {
+		e.EnterValue(x, 0, v)
+	}
+	for x, v := range "2413" {
+		e.EnterValue(x, 1, v)
+	}
+	if e.Save.Solved {
+		t.Error("expected swapped rows to break the clues")
+	}
+}
+
+func TestSkyscrapersView(t *testing.T) {
+	// The grid sits inside a ring of clues.
+	e := newTestEngine(t, Level{Engine: "skyscrapers", Initial: testLatinBlank, Solution: testLatinSolution, Edges: testSkyscrapersEdges}).(*SkyscrapersEngine)
+	lines := strings.Split(e.View(model{}), "\n")
+	if !strings.Contains(lines[0], "3  1  3  2") {
+		t.Errorf("expected the top clues on the first line, got %q", lines[0])
+	}
+	if x, y, ok := e.CellAt(4, 1); !ok || x != 0 || y != 0 {
+		t.Errorf("expected column 4 row 1 to be cell 0,0, got %d,%d %v", x, y, ok)
+	}
+	if _, _, ok := e.CellAt(1, 1); ok {
+		t.Error("expected a clue not to be a cell")
+	}
+	if _, _, ok := e.CellAt(4, 5); ok {
+		t.Error("expected the bottom clues not to be cells")
+	}
+}
+
+func TestCountSkyscrapersSolutions(t *testing.T) {
+	testCases := []struct {
+		name  string
+		edges string
+		want  int
+	}{
+		{name: "unique", edges: testSkyscrapersUniqueEdges, want: 1},
+		{name: "every clue", edges: testSkyscrapersEdges, want: 1},
+		{name: "one clue", edges: "3 . . .\n. . . .\n. . . .\n. . . .", want: 2},
+		{name: "clashing clues", edges: "4 . . .\n. . . .\n4 . . .\n. . . .", want: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := Level{Initial: testLatinBlank, Solution: testLatinSolution, Edges: tc.edges}
+			got, ok := countSkyscrapersSolutions(l, 2)
+			if !ok || got != tc.want {
+				t.Errorf("expected %d solutions, got %d (ok %v)", tc.want, got, ok)
+			}
+		})
+	}
+}
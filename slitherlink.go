@@ -0,0 +1,426 @@
+// This file implements the Slitherlink game logic, the first engine with its
+// state on the edges between cells.
+//
+// The grids are lattices, as laid out in lattice.go: points are +, a cell
+// holds a clue from 0 to 4 or . for none, and an edge holds - across or |
+// down for a line, X for an edge ruled out, or . when undecided. The clues
+// and any edges in the initial state are given. The player draws a single
+// closed loop along the edges, with as many of each clued cell's edges on
+// the loop as its clue says.
+//
+// The loop is checked against the rules rather than the solution, which
+// only has to draw a loop that follows them.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	SlitherPointTile  = rune('+')
+	SlitherAcrossTile = rune('-')
+	SlitherDownTile   = rune('|')
+)
+
+var slitherlinkGlyphs = map[string]GlyphSet{
+	unicodeGlyphs: {
+		SlitherPointTile:  "·",
+		SlitherAcrossTile: "━",
+		SlitherDownTile:   "┃",
+		KnownEmptyTile:    "×",
+		EmptyTile:         " ",
+	},
+	asciiGlyphs: {
+		SlitherPointTile:  "+",
+		SlitherAcrossTile: "-",
+		SlitherDownTile:   "|",
+		KnownEmptyTile:    "x",
+		EmptyTile:         " ",
+	},
+	emojiGlyphs: {
+		SlitherPointTile:  "·",
+		SlitherAcrossTile: "━",
+		SlitherDownTile:   "┃",
+		KnownEmptyTile:    "×",
+		EmptyTile:         " ",
+	},
+}
+
+// slitherlinkCellWidth is the width of a cell, and of the edge across above
+// and below it.
+const slitherlinkCellWidth = 3
+
+// slitherlinkLine reports whether an edge holds a line.
+func slitherlinkLine(v rune) bool {
+	return v == SlitherAcrossTile || v == SlitherDownTile
+}
+
+// slitherlinkCross reports whether an edge has been ruled out.
+func slitherlinkCross(v rune) bool {
+	return v == KnownEmptyTile || v == 'x'
+}
+
+// slitherlinkRules are the clues of a level and the size of its lattice.
+type slitherlinkRules struct {
+	width, height int
+	clues         map[[2]int]int
+}
+
+// slitherlinkRulesFor reads the clues of a level from its initial state.
+func slitherlinkRulesFor(l Level) (*slitherlinkRules, error) {
+	initial, err := latticeGrid(l.Initial, SlitherPointTile)
+	if err != nil {
+		return nil, fmt.Errorf("initial state: %w", err)
+	}
+	r := &slitherlinkRules{height: len(initial), width: len(initial[0]), clues: make(map[[2]int]int)}
+	for y, row := range initial {
+		for x, v := range row {
+			switch k := latticeKindAt(x, y); {
+			case k == latticeCell && v >= '0' && v <= '4':
+				r.clues[[2]int{x, y}] = int(v - '0')
+			case k == latticeCell && v != '.' && v != ' ':
+				return nil, fmt.Errorf("cell at row %d column %d should be a clue from 0 to 4 or ., got %q", y+1, x+1, v)
+			case k == latticeEdgeAcross && v == SlitherDownTile, k == latticeEdgeDown && v == SlitherAcrossTile:
+				return nil, fmt.Errorf("edge at row %d column %d has its line the wrong way", y+1, x+1)
+			case latticeIsEdge(x, y) && !slitherlinkLine(v) && !slitherlinkCross(v) && v != '.' && v != ' ':
+				return nil, fmt.Errorf("edge at row %d column %d should be -, |, X or ., got %q", y+1, x+1, v)
+			}
+		}
+	}
+	return r, nil
+}
+
+// in reports whether a position is on the lattice.
+func (r *slitherlinkRules) in(x, y int) bool {
+	return x >= 0 && y >= 0 && x < r.width && y < r.height
+}
+
+// around counts the lines and undecided edges around a cell or point.
+func (r *slitherlinkRules) around(values [][]rune, x, y int) (int, int) {
+	lines, open := 0, 0
+	for _, p := range latticeAround(x, y) {
+		if !r.in(p[0], p[1]) {
+			continue
+		}
+		switch v := values[p[1]][p[0]]; {
+		case slitherlinkLine(v):
+			lines++
+		case !slitherlinkCross(v):
+			open++
+		}
+	}
+	return lines, open
+}
+
+// check returns the lines that branch off the loop, where three or four
+// meet at a point, and how each clue stands.
+func (r *slitherlinkRules) check(values [][]rune) (map[[2]int]bool, map[[2]int]lineStatus) {
+	broken := make(map[[2]int]bool)
+	for y := 0; y < r.height; y += 2 {
+		for x := 0; x < r.width; x += 2 {
+			if lines, _ := r.around(values, x, y); lines <= 2 {
+				continue
+			}
+			for _, p := range latticeAround(x, y) {
+				if r.in(p[0], p[1]) && slitherlinkLine(values[p[1]][p[0]]) {
+					broken[p] = true
+				}
+			}
+		}
+	}
+	status := make(map[[2]int]lineStatus)
+	for c, n := range r.clues {
+		lines, open := r.around(values, c[0], c[1])
+		switch {
+		case lines > n || lines+open < n:
+			status[c] = lineBroken
+		case lines == n:
+			status[c] = lineSatisfied
+		}
+	}
+	return broken, status
+}
+
+// pieces counts the separate runs of line, and reports whether every point
+// on them has exactly two lines, so each one is a closed loop.
+func (r *slitherlinkRules) pieces(values [][]rune) (int, bool) {
+	seen := make(map[[2]int]bool)
+	count, closed := 0, true
+	for y := 0; y < r.height; y += 2 {
+		for x := 0; x < r.width; x += 2 {
+			lines, _ := r.around(values, x, y)
+			if lines == 0 || seen[[2]int{x, y}] {
+				continue
+			}
+			count++
+			queue := [][2]int{{x, y}}
+			seen[[2]int{x, y}] = true
+			for len(queue) > 0 {
+				p := queue[0]
+				queue = queue[1:]
+				if lines, _ := r.around(values, p[0], p[1]); lines != 2 {
+					closed = false
+				}
+				for _, e := range latticeAround(p[0], p[1]) {
+					if !r.in(e[0], e[1]) || !slitherlinkLine(values[e[1]][e[0]]) {
+						continue
+					}
+					for _, q := range latticeEnds(e[0], e[1]) {
+						if !seen[q] {
+							seen[q] = true
+							queue = append(queue, q)
+						}
+					}
+				}
+			}
+		}
+	}
+	return count, closed
+}
+
+// solved reports whether the lines make a single closed loop and every clue
+// has exactly its count of lines.
+func (r *slitherlinkRules) solved(values [][]rune) bool {
+	if n, closed := r.pieces(values); n != 1 || !closed {
+		return false
+	}
+	for c, n := range r.clues {
+		if lines, _ := r.around(values, c[0], c[1]); lines != n {
+			return false
+		}
+	}
+	return true
+}
+
+// validateSlitherlink refuses levels whose grids aren't lattices, whose
+// solution doesn't draw a loop that follows the clues, or whose given edges
+// don't match the solution.
+func validateSlitherlink(l Level) (string, error) {
+	r, err := slitherlinkRulesFor(l)
+	if err != nil {
+		return "", err
+	}
+	solution, err := latticeGrid(l.Solution, SlitherPointTile)
+	if err != nil {
+		return "", fmt.Errorf("solution: %w", err)
+	}
+	if len(solution) != r.height || len(solution[0]) != r.width {
+		return "", errors.New("initial state and solution are different sizes")
+	}
+	if !r.solved(solution) {
+		return "", errors.New("solution doesn't draw a single loop that meets every clue")
+	}
+	initial, _ := latticeGrid(l.Initial, SlitherPointTile)
+	for y, row := range initial {
+		for x, v := range row {
+			line := slitherlinkLine(solution[y][x])
+			if (slitherlinkLine(v) && !line) || (slitherlinkCross(v) && line) {
+				return "", fmt.Errorf("given edge at row %d column %d doesn't match the solution", y+1, x+1)
+			}
+		}
+	}
+	return "", nil
+}
+
+type SlitherlinkEngine struct {
+	Engine
+	rules      *slitherlinkRules
+	clueStatus map[[2]int]lineStatus
+}
+
+func (e *SlitherlinkEngine) New(l Level, s *Save) (GameEngine, error) {
+	rules, err := slitherlinkRulesFor(l)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := e.Engine.New(l, s); err != nil {
+		return nil, err
+	}
+	e.rules = rules
+	e.updateStatus()
+	return e, nil
+}
+
+// PrimaryAction draws a line along an edge, or rubs it out again.
+func (e *SlitherlinkEngine) PrimaryAction(x, y int) error {
+	v := SlitherAcrossTile
+	if latticeKindAt(x, y) == latticeEdgeDown {
+		v = SlitherDownTile
+	}
+	return e.toggle(x, y, v)
+}
+
+// SecondaryAction rules an edge out, or lets it back in.
+func (e *SlitherlinkEngine) SecondaryAction(x, y int) error {
+	return e.toggle(x, y, KnownEmptyTile)
+}
+
+func (e *SlitherlinkEngine) ClearCell(x, y int) error {
+	if err := e.Engine.ClearCell(x, y); err != nil {
+		return err
+	}
+	e.updateStatus()
+	return nil
+}
+
+// Evaluate reports whether the lines make a single closed loop that meets
+// every clue, whether or not it is the level's solution.
+func (e *SlitherlinkEngine) Evaluate() (bool, error) {
+	return e.rules.solved(e.values()), nil
+}
+
+// MoveCursor steps the cursor between the edges and cells of the lattice,
+// passing over the points.
+func (e *SlitherlinkEngine) MoveCursor(x, y, dx, dy int) (int, int, bool) {
+	return latticeMove(x, y, dx, dy, e.GetWidth(), e.GetHeight())
+}
+
+func (e *SlitherlinkEngine) View(m model) string {
+	return lipgloss.JoinVertical(lipgloss.Left, e.gridView(m), e.helpView(m))
+}
+
+// CellAt maps a position within the rendered view to an edge or cell of the
+// lattice. Points aren't hit.
+func (e *SlitherlinkEngine) CellAt(col, row int) (int, int, bool) {
+	return latticeCellAt(col, row, slitherlinkCellWidth, e.GetWidth(), e.GetHeight())
+}
+
+// GlyphSets returns the glyphs points, lines and crosses are drawn with.
+func (e *SlitherlinkEngine) GlyphSets() map[string]GlyphSet {
+	return slitherlinkGlyphs
+}
+
+// --- Private Functions ---
+
+// toggle puts a value on an edge, or clears the edge if it already holds it.
+func (e *SlitherlinkEngine) toggle(x, y int, v rune) error {
+	if !e.HasCell(x, y) || !latticeIsEdge(x, y) {
+		return errors.New("not an edge")
+	}
+	if e.Grid[y][x].value == v {
+		return e.ClearCell(x, y)
+	}
+	if err := e.setCellValue(x, y, v); err != nil {
+		return err
+	}
+	e.updateStatus()
+	return nil
+}
+
+// updateStatus flags lines that branch, checks the clues and refreshes the
+// solved flag on the save.
+func (e *SlitherlinkEngine) updateStatus() {
+	values := e.values()
+	broken, status := e.rules.check(values)
+	for y, row := range e.Grid {
+		for x := range row {
+			e.Grid[y][x].RunValidation(!broken[[2]int{x, y}])
+		}
+	}
+	e.clueStatus = status
+	e.Save.Solved = e.rules.solved(values)
+}
+
+// pointView draws a point, joining up the lines that meet there.
+func (e *SlitherlinkEngine) pointView(x, y int) string {
+	glyphs := glyphSetFor(slitherlinkGlyphs)
+	edges := [4]gridEdge{edgeNone, edgeNone, edgeNone, edgeNone}
+	lines := 0
+	for i, p := range latticeAround(x, y) {
+		if e.rules.in(p[0], p[1]) && slitherlinkLine(e.Grid[p[1]][p[0]].value) {
+			edges[i] = edgeRegion
+			lines++
+		}
+	}
+	if lines == 0 || activeGlyphSet == asciiGlyphs {
+		return subtleStyle.Render(glyphs[SlitherPointTile])
+	}
+	return focusedStyle.Render(boxJunction(edges))
+}
+
+// positionView draws an edge or cell of the lattice.
+func (e *SlitherlinkEngine) positionView(x, y int, m model) string {
+	glyphs := glyphSetFor(slitherlinkGlyphs)
+	cell := e.Grid[y][x]
+	width := 1
+	if x%2 == 1 {
+		width = slitherlinkCellWidth
+	}
+
+	var s lipgloss.Style
+	g := glyphs[EmptyTile]
+	switch {
+	case latticeKindAt(x, y) == latticeCell:
+		if n, ok := e.rules.clues[[2]int{x, y}]; ok {
+			s = hintLineStyle(e.clueStatus[[2]int{x, y}])
+			g = fmt.Sprint(n)
+		}
+	case slitherlinkLine(cell.value):
+		s = focusedStyle
+		if cell.state == invalid {
+			s = hintErrorStyle
+		}
+		g = glyphs[cell.value]
+		g = strings.Repeat(g, width)
+	case slitherlinkCross(cell.value):
+		s = subtleStyle
+		g = glyphs[KnownEmptyTile]
+	}
+	if x == m.cursorX && y == m.cursorY {
+		s = highlightStyle
+	}
+	return s.Width(width).AlignHorizontal(lipgloss.Center).Render(g)
+}
+
+// gridView draws the lattice: a line of points and edges across, then a
+// line of edges down and cells, for each row of cells.
+func (e *SlitherlinkEngine) gridView(m model) string {
+	var rows []string
+	for y := 0; y < e.GetHeight(); y++ {
+		var parts []string
+		for x := 0; x < e.GetWidth(); x++ {
+			if latticeKindAt(x, y) == latticePoint {
+				parts = append(parts, e.pointView(x, y))
+			} else {
+				parts = append(parts, e.positionView(x, y, m))
+			}
+		}
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, parts...))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, rows...)
+}
+
+func (e *SlitherlinkEngine) helpView(m model) string {
+	help := "\n"
+	if e.HasCell(m.cursorX, m.cursorY) && latticeIsEdge(m.cursorX, m.cursorY) && e.Grid[m.cursorY][m.cursorX].state != given {
+		help += "z: Line\tx: Rule out\tbackspace: clear\n"
+	} else {
+		help += "\n"
+	}
+	help += e.statusView()
+	help += "arrow keys or hjkl to move\n"
+	help += "Press 'esc' to return to the menu.\n"
+	if e.Save.Solved {
+		help += "Congrats!\n"
+	}
+	return help
+}
+
+// statusView says how many pieces the lines are in, and whether they close.
+func (e *SlitherlinkEngine) statusView() string {
+	n, closed := e.rules.pieces(e.values())
+	switch {
+	case n == 0:
+		return "Loop: not started\n"
+	case n == 1 && closed:
+		return "Loop: closed\n"
+	case n == 1:
+		return "Loop: open\n"
+	}
+	return fmt.Sprintf("Loop: %d pieces\n", n)
+}
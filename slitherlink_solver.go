@@ -0,0 +1,145 @@
+package main
+
+// slitherlinkSearchBudget caps how many edges countSlitherlinkSolutions
+// tries before giving up on a level.
+const slitherlinkSearchBudget = 2000000
+
+// slitherlinkSolver decides the edges of a lattice one at a time in reading
+// order, backing off as soon as a point or clue can no longer be met. A
+// line that closes a loop ends the search down that branch, since every
+// other edge then has to stay empty.
+type slitherlinkSolver struct {
+	rules  *slitherlinkRules
+	values [][]rune
+	edges  [][2]int
+	budget int
+}
+
+// countSlitherlinkSolutions counts the loops that meet a level's clues, up
+// to limit, keeping the edges its initial state gives.
+func countSlitherlinkSolutions(l Level, limit int) (int, bool) {
+	rules, err := slitherlinkRulesFor(l)
+	if err != nil {
+		return 0, true
+	}
+	values, _ := latticeGrid(l.Initial, SlitherPointTile)
+	s := &slitherlinkSolver{rules: rules, values: values, budget: slitherlinkSearchBudget}
+	for y, row := range values {
+		for x, v := range row {
+			if !latticeIsEdge(x, y) {
+				continue
+			}
+			switch {
+			case slitherlinkLine(v):
+			case slitherlinkCross(v):
+				s.values[y][x] = KnownEmptyTile
+			default:
+				s.values[y][x] = EmptyTile
+				s.edges = append(s.edges, [2]int{x, y})
+			}
+		}
+	}
+	count := s.search(0, limit)
+	return count, s.budget > 0
+}
+
+// search counts the ways to decide the edges from the i'th on, up to limit.
+func (s *slitherlinkSolver) search(i, limit int) int {
+	if i == len(s.edges) {
+		if s.rules.solved(s.values) {
+			return 1
+		}
+		return 0
+	}
+	e := s.edges[i]
+	line := SlitherAcrossTile
+	if latticeKindAt(e[0], e[1]) == latticeEdgeDown {
+		line = SlitherDownTile
+	}
+
+	count := 0
+	for _, v := range []rune{line, KnownEmptyTile} {
+		if s.budget--; s.budget <= 0 {
+			break
+		}
+		closes := v == line && s.joined(e)
+		s.values[e[1]][e[0]] = v
+		switch {
+		case !s.fits(e):
+		case closes:
+			count += s.finish(i + 1)
+		default:
+			count += s.search(i+1, limit-count)
+		}
+		if count >= limit {
+			break
+		}
+	}
+	s.values[e[1]][e[0]] = EmptyTile
+	return count
+}
+
+// fits reports whether the points at the ends of an edge and the cells
+// either side of it can still be met.
+func (s *slitherlinkSolver) fits(e [2]int) bool {
+	for _, p := range latticeEnds(e[0], e[1]) {
+		lines, open := s.rules.around(s.values, p[0], p[1])
+		if lines > 2 || (lines == 1 && open == 0) {
+			return false
+		}
+	}
+	for _, c := range latticeAround(e[0], e[1]) {
+		n, ok := s.rules.clues[c]
+		if !ok {
+			continue
+		}
+		lines, open := s.rules.around(s.values, c[0], c[1])
+		if lines > n || lines+open < n {
+			return false
+		}
+	}
+	return true
+}
+
+// joined reports whether the ends of an edge are already joined by lines,
+// so that a line along it would close a loop.
+func (s *slitherlinkSolver) joined(e [2]int) bool {
+	ends := latticeEnds(e[0], e[1])
+	seen := map[[2]int]bool{ends[0]: true}
+	queue := [][2]int{ends[0]}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if p == ends[1] {
+			return true
+		}
+		for _, n := range latticeAround(p[0], p[1]) {
+			if !s.rules.in(n[0], n[1]) || !slitherlinkLine(s.values[n[1]][n[0]]) {
+				continue
+			}
+			for _, q := range latticeEnds(n[0], n[1]) {
+				if !seen[q] {
+					seen[q] = true
+					queue = append(queue, q)
+				}
+			}
+		}
+	}
+	return false
+}
+
+// finish rules out every edge from the i'th on and reports whether that
+// leaves a solution, as it must once a loop is closed.
+func (s *slitherlinkSolver) finish(i int) int {
+	for _, e := range s.edges[i:] {
+		s.values[e[1]][e[0]] = KnownEmptyTile
+	}
+	count := 0
+	if s.rules.solved(s.values) {
+		count = 1
+	}
+	for _, e := range s.edges[i:] {
+		s.values[e[1]][e[0]] = EmptyTile
+	}
+	return count
+}
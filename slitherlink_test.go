@@ -0,0 +1,186 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	testSlitherlinkInitial  = "+.+.+.+\n...3...\n+.+.+.+\n.3.1.3.\n+.+.+.+\n.......\n+.+.+.+"
+	testSlitherlinkSolution = "+.+-+.+\n..|3|..\n+-+.+-+\n|3.1.3|\n+-+-+-+\n.......\n+.+.+.+"
+)
+
+var testSlitherlinkLevel = Level{
+	Engine:   "slitherlink",
+	Initial:  testSlitherlinkInitial,
+	Solution: testSlitherlinkSolution,
+}
+
+// drawLoop draws the lines of a lattice with the primary action.
+func drawLoop(t *testing.T, e *SlitherlinkEngine, lattice string) {
+	t.Helper()
+	for y, row := range strings.Split(lattice, "\n") {
+		for x, v := range row {
+			if slitherlinkLine(v) && !slitherlinkLine(e.Grid[y][x].value) {
+				if err := e.PrimaryAction(x, y); err != nil {
+					t.Fatalf("failed to draw a line at %d,%d: %v", x, y, err)
+				}
+			}
+		}
+	}
+}
+
+func TestSlitherlinkRulesErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		initial string
+		want    string
+	}{
+		{name: "not a lattice", initial: "+.+.\n....", want: "odd number"},
+		{name: "bad clue", initial: "+.+\n.5.\n+.+", want: "clue from 0 to 4"},
+		{name: "line the wrong way", initial: "+|+\n...\n+.+", want: "wrong way"},
+		{name: "bad edge", initial: "+.+\n?..\n+.+", want: "should be -, |, X or ."},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := slitherlinkRulesFor(Level{Initial: tc.initial})
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected an error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestSlitherlinkPlay(t *testing.T) {
+	e := newTestEngine(t, testSlitherlinkLevel).(*SlitherlinkEngine)
+	if err := e.PrimaryAction(3, 3); err == nil {
+		t.Error("expected a line in a cell to be refused")
+	}
+	if err := e.PrimaryAction(0, 0); err == nil {
+		t.Error("expected a line on a point to be refused")
+	}
+
+	e.PrimaryAction(1, 0)
+	if v := e.Grid[0][1].value; v != SlitherAcrossTile {
+		t.Errorf("expected a line across, got %q", v)
+	}
+	e.PrimaryAction(1, 0)
+	if v := e.Grid[0][1].value; v != EmptyTile {
+		t.Errorf("expected a second press to rub the line out, got %q", v)
+	}
+	e.SecondaryAction(0, 1)
+	if v := e.Grid[1][0].value; v != KnownEmptyTile {
+		t.Errorf("expected the edge to be ruled out, got %q", v)
+	}
+	e.ClearCell(0, 1)
+
+	drawLoop(t, e, testSlitherlinkSolution)
+	if !e.Save.Solved {
+		t.Fatalf("expected the loop to solve the level, got %q", e.Save.State)
+	}
+
+	// A line off the loop branches where it meets it.
+	e.PrimaryAction(2, 5)
+	if e.Save.Solved || e.Grid[5][2].state != invalid || e.Grid[4][1].state != invalid {
+		t.Error("expected a branch off the loop to be invalid")
+	}
+	e.PrimaryAction(2, 5)
+	if !e.Save.Solved || e.Grid[4][1].state == invalid {
+		t.Error("expected rubbing out the branch to solve the level again")
+	}
+}
+
+func TestSlitherlinkClueStatus(t *testing.T) {
+	e := newTestEngine(t, testSlitherlinkLevel).(*SlitherlinkEngine)
+	center := [2]int{3, 3}
+	e.PrimaryAction(3, 4)
+	if e.clueStatus[center] != lineSatisfied {
+		t.Errorf("expected one line to satisfy the 1, got %d", e.clueStatus[center])
+	}
+	e.PrimaryAction(3, 2)
+	if e.clueStatus[center] != lineBroken {
+		t.Errorf("expected two lines to break the 1, got %d", e.clueStatus[center])
+	}
+
+	// Ruling out three edges of the top 3 leaves it short.
+	top := [2]int{3, 1}
+	for _, p := range [][2]int{{2, 1}, {4, 1}, {3, 0}} {
+		e.SecondaryAction(p[0], p[1])
+	}
+	if e.clueStatus[top] != lineBroken {
+		t.Errorf("expected a 3 with two edges ruled out to break, got %d", e.clueStatus[top])
+	}
+}
+
+func TestSlitherlinkPieces(t *testing.T) {
+	e := newTestEngine(t, testSlitherlinkLevel).(*SlitherlinkEngine)
+	if !strings.Contains(e.statusView(), "not started") {
+		t.Errorf("expected no loop yet, got %q", e.statusView())
+	}
+	e.PrimaryAction(1, 6)
+	e.PrimaryAction(5, 6)
+	if got := e.statusView(); got != "Loop: 2 pieces\n" {
+		t.Errorf("expected 2 pieces, got %q", got)
+	}
+	drawLoop(t, e, "+.+.+.+\n.......\n+.+.+.+\n.......\n+-+.+-+\n|.|.|.|\n+-+.+-+")
+	if got := e.statusView(); got != "Loop: 2 pieces\n" || e.Save.Solved {
+		t.Errorf("expected two closed loops not to solve the level, got %q", got)
+	}
+}
+
+func TestSlitherlinkCursor(t *testing.T) {
+	e := newTestEngine(t, testSlitherlinkLevel).(*SlitherlinkEngine)
+	m := model{engine: e}
+	m.moveCursor(1, 0)
+	m.moveCursor(1, 0)
+	if m.cursorX != 3 || m.cursorY != 0 {
+		t.Errorf("expected the cursor to step over the point to 3,0, got %d,%d", m.cursorX, m.cursorY)
+	}
+	m.moveCursor(0, 1)
+	if m.cursorX != 3 || m.cursorY != 1 {
+		t.Errorf("expected the cursor to move down into the cell, got %d,%d", m.cursorX, m.cursorY)
+	}
+	m.moveCursor(0, -1)
+	m.moveCursor(0, -1)
+	if m.cursorY != 0 {
+		t.Errorf("expected the cursor to stay on the lattice, got %d,%d", m.cursorX, m.cursorY)
+	}
+}
+
+func TestSlitherlinkView(t *testing.T) {
+	e := newTestEngine(t, testSlitherlinkLevel).(*SlitherlinkEngine)
+	drawLoop(t, e, testSlitherlinkSolution)
+	lines := strings.Split(e.gridView(model{}), "\n")
+	if len(lines) != 7 {
+		t.Fatalf("expected 7 lines, got %d", len(lines))
+	}
+	if !strings.Contains(lines[0], "┏━━━┓") {
+		t.Errorf("expected the loop to turn at the points, got\n%s", strings.Join(lines, "\n"))
+	}
+	if x, y, ok := e.CellAt(5, 1); !ok || x != 3 || y != 1 {
+		t.Errorf("expected column 5 row 1 to be the top clue, got %d,%d %v", x, y, ok)
+	}
+}
+
+func TestCountSlitherlinkSolutions(t *testing.T) {
+	testCases := []struct {
+		name    string
+		initial string
+		want    int
+	}{
+		{name: "unique", initial: testSlitherlinkInitial, want: 1},
+		{name: "without the 1", initial: strings.Replace(testSlitherlinkInitial, ".1.", "...", 1), want: 2},
+		{name: "given line", initial: strings.Replace(strings.Replace(testSlitherlinkInitial, ".1.", "...", 1), "+.+.+.+\n...3", "+.+-+.+\n...3", 1), want: 2},
+		{name: "impossible", initial: "+.+.+.+\n.1.....\n+.+.+.+\n...4...\n+.+.+.+\n.......\n+.+.+.+", want: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := countSlitherlinkSolutions(Level{Initial: tc.initial, Solution: testSlitherlinkSolution}, 2)
+			if !ok || got != tc.want {
+				t.Errorf("expected %d solutions, got %d (ok %v)", tc.want, got, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,566 @@
+// This file implements Sokoban.
+//
+// Boards are written in the standard XSB notation: # is a wall, @ the player,
+// $ a box, . a goal, * a box on a goal and + the player on a goal, with a
+// space, - or _ for floor. Rows can be ragged. The player walks the floor and
+// pushes boxes one at a time, and the level is solved once every box is on a
+// goal.
+//
+// Moves are recorded in LURD notation, one letter for the way each move
+// goes, in capitals for a push. The save holds the board followed by the
+// record on a line of its own, so the moves and pushes can be counted, and
+// undone, after the level is left and opened again. The solution isn't used
+// in play, but when it is a move record it is checked to solve the level.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	SokobanWallTile         = rune('#')
+	SokobanPlayerTile       = rune('@')
+	SokobanPlayerOnGoalTile = rune('+')
+	SokobanBoxTile          = rune('$')
+	SokobanBoxOnGoalTile    = rune('*')
+	SokobanGoalTile         = rune('.')
+)
+
+var sokobanGlyphs = map[string]GlyphSet{
+	unicodeGlyphs: {
+		SokobanWallTile:         "█",
+		SokobanPlayerTile:       "●",
+		SokobanPlayerOnGoalTile: "●",
+		SokobanBoxTile:          "■",
+		SokobanBoxOnGoalTile:    "▣",
+		SokobanGoalTile:         "·",
+		EmptyTile:               " ",
+	},
+	asciiGlyphs: {
+		SokobanWallTile:         "#",
+		SokobanPlayerTile:       "@",
+		SokobanPlayerOnGoalTile: "+",
+		SokobanBoxTile:          "$",
+		SokobanBoxOnGoalTile:    "*",
+		SokobanGoalTile:         ".",
+		EmptyTile:               " ",
+	},
+	emojiGlyphs: {
+		SokobanWallTile:         "🧱",
+		SokobanPlayerTile:       "🙂",
+		SokobanPlayerOnGoalTile: "🙂",
+		SokobanBoxTile:          "📦",
+		SokobanBoxOnGoalTile:    "✅",
+		SokobanGoalTile:         "🎯",
+		EmptyTile:               " ",
+	},
+}
+
+// sokobanSteps maps the letters of a move record to the way they go.
+var sokobanSteps = map[rune][2]int{
+	'u': {0, -1},
+	'r': {1, 0},
+	'd': {0, 1},
+	'l': {-1, 0},
+}
+
+// sokobanRecord reports whether a line is a move record.
+func sokobanRecord(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if _, ok := sokobanSteps[unicode.ToLower(r)]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// sokobanBoard is a sokoban level as it is played: the walls and goals that
+// stay put, and the boxes and player that move.
+type sokobanBoard struct {
+	width, height int
+	walls, goals  map[[2]int]bool
+	boxes         map[[2]int]bool
+	player        [2]int
+	// outside holds the floor beyond the walls, which is left undrawn.
+	outside map[[2]int]bool
+}
+
+// parseSokoban reads a board in XSB notation and checks that it can be
+// played: one player, shut in by walls, and as many goals as boxes.
+func parseSokoban(s string) (*sokobanBoard, error) {
+	b := &sokobanBoard{
+		walls:   make(map[[2]int]bool),
+		goals:   make(map[[2]int]bool),
+		boxes:   make(map[[2]int]bool),
+		outside: make(map[[2]int]bool),
+	}
+	players := 0
+	rows := strings.Split(strings.Trim(s, "\n"), "\n")
+	b.height = len(rows)
+	for y, row := range rows {
+		for x, v := range []rune(row) {
+			p := [2]int{x, y}
+			b.width = max(b.width, x+1)
+			switch v {
+			case SokobanWallTile:
+				b.walls[p] = true
+			case SokobanPlayerTile, SokobanPlayerOnGoalTile:
+				b.player = p
+				players++
+			case SokobanBoxTile, SokobanBoxOnGoalTile:
+				b.boxes[p] = true
+			case SokobanGoalTile, EmptyTile, '-', '_':
+			default:
+				return nil, fmt.Errorf("row %d column %d should be one of #@+$*. or floor, got %q", y+1, x+1, v)
+			}
+			if v == SokobanGoalTile || v == SokobanBoxOnGoalTile || v == SokobanPlayerOnGoalTile {
+				b.goals[p] = true
+			}
+		}
+	}
+	switch {
+	case players != 1:
+		return nil, fmt.Errorf("want one player, found %d", players)
+	case len(b.boxes) == 0:
+		return nil, errors.New("no boxes")
+	case len(b.boxes) != len(b.goals):
+		return nil, fmt.Errorf("%d boxes for %d goals, want as many of each", len(b.boxes), len(b.goals))
+	}
+
+	// Flood the floor in from the edges to find what lies outside the
+	// walls. The player must not be out there.
+	var queue [][2]int
+	for y := 0; y < b.height; y++ {
+		for x := 0; x < b.width; x++ {
+			if (x == 0 || y == 0 || x == b.width-1 || y == b.height-1) && !b.walls[[2]int{x, y}] {
+				b.outside[[2]int{x, y}] = true
+				queue = append(queue, [2]int{x, y})
+			}
+		}
+	}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, d := range sokobanSteps {
+			q := [2]int{p[0] + d[0], p[1] + d[1]}
+			if b.in(q) && !b.walls[q] && !b.outside[q] {
+				b.outside[q] = true
+				queue = append(queue, q)
+			}
+		}
+	}
+	if b.outside[b.player] {
+		return nil, errors.New("the walls don't shut the player in")
+	}
+	return b, nil
+}
+
+// in reports whether a position is on the board.
+func (b *sokobanBoard) in(p [2]int) bool {
+	return p[0] >= 0 && p[1] >= 0 && p[0] < b.width && p[1] < b.height
+}
+
+// at returns the XSB value of a position.
+func (b *sokobanBoard) at(p [2]int) rune {
+	switch {
+	case b.walls[p]:
+		return SokobanWallTile
+	case p == b.player && b.goals[p]:
+		return SokobanPlayerOnGoalTile
+	case p == b.player:
+		return SokobanPlayerTile
+	case b.boxes[p] && b.goals[p]:
+		return SokobanBoxOnGoalTile
+	case b.boxes[p]:
+		return SokobanBoxTile
+	case b.goals[p]:
+		return SokobanGoalTile
+	}
+	return EmptyTile
+}
+
+// String writes the board in XSB notation, without trailing spaces.
+func (b *sokobanBoard) String() string {
+	rows := make([]string, b.height)
+	for y := range rows {
+		var row strings.Builder
+		for x := 0; x < b.width; x++ {
+			row.WriteRune(b.at([2]int{x, y}))
+		}
+		rows[y] = strings.TrimRight(row.String(), " ")
+	}
+	return strings.Join(rows, "\n")
+}
+
+// move walks the player one step the way a letter goes, pushing any box in
+// the way. It returns the letter for the record, in capitals for a push,
+// and false when a wall or a second box is in the way.
+func (b *sokobanBoard) move(letter rune) (rune, bool) {
+	d := sokobanSteps[unicode.ToLower(letter)]
+	next := [2]int{b.player[0] + d[0], b.player[1] + d[1]}
+	if b.walls[next] {
+		return 0, false
+	}
+	letter = unicode.ToLower(letter)
+	if b.boxes[next] {
+		beyond := [2]int{next[0] + d[0], next[1] + d[1]}
+		if b.walls[beyond] || b.boxes[beyond] {
+			return 0, false
+		}
+		delete(b.boxes, next)
+		b.boxes[beyond] = true
+		letter = unicode.ToUpper(letter)
+	}
+	b.player = next
+	return letter, true
+}
+
+// undo takes back a move from the record.
+func (b *sokobanBoard) undo(letter rune) {
+	d := sokobanSteps[unicode.ToLower(letter)]
+	if unicode.IsUpper(letter) {
+		box := [2]int{b.player[0] + d[0], b.player[1] + d[1]}
+		delete(b.boxes, box)
+		b.boxes[b.player] = true
+	}
+	b.player = [2]int{b.player[0] - d[0], b.player[1] - d[1]}
+}
+
+// replay makes the moves of a record, returning how many it made and an
+// error for a move the board doesn't allow.
+func (b *sokobanBoard) replay(record string) (int, error) {
+	for i, letter := range []rune(record) {
+		got, ok := b.move(letter)
+		if ok && got != letter {
+			b.undo(got)
+		}
+		if !ok || got != letter {
+			return i, fmt.Errorf("move %d (%c) doesn't fit the board", i+1, letter)
+		}
+	}
+	return len([]rune(record)), nil
+}
+
+// solved reports whether every box is on a goal.
+func (b *sokobanBoard) solved() bool {
+	for p := range b.boxes {
+		if !b.goals[p] {
+			return false
+		}
+	}
+	return true
+}
+
+// path finds the shortest walk to a position that doesn't push any boxes,
+// as a move record.
+func (b *sokobanBoard) path(to [2]int) (string, bool) {
+	from := map[[2]int]rune{b.player: 0}
+	queue := [][2]int{b.player}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if p == to {
+			var letters []rune
+			for p != b.player {
+				letter := from[p]
+				letters = append([]rune{letter}, letters...)
+				d := sokobanSteps[letter]
+				p = [2]int{p[0] - d[0], p[1] - d[1]}
+			}
+			return string(letters), true
+		}
+		for _, letter := range "urdl" {
+			d := sokobanSteps[letter]
+			q := [2]int{p[0] + d[0], p[1] + d[1]}
+			if _, seen := from[q]; seen || !b.in(q) || b.walls[q] || b.boxes[q] {
+				continue
+			}
+			from[q] = letter
+			queue = append(queue, q)
+		}
+	}
+	return "", false
+}
+
+// sokobanSolvedBoard returns a board with every box moved onto a goal and
+// the player left out, which stands in for the solution of a level without
+// a move record.
+func sokobanSolvedBoard(initial string) string {
+	return strings.NewReplacer(
+		string(SokobanBoxTile), " ",
+		string(SokobanGoalTile), string(SokobanBoxOnGoalTile),
+		string(SokobanPlayerTile), " ",
+		string(SokobanPlayerOnGoalTile), string(SokobanBoxOnGoalTile),
+	).Replace(initial)
+}
+
+// validateSokoban refuses boards that can't be played, and solutions that
+// are move records but don't solve the level. A solution that solves it is
+// noted with its moves and pushes.
+func validateSokoban(l Level) (string, error) {
+	b, err := parseSokoban(l.Initial)
+	if err != nil {
+		return "", err
+	}
+	record := strings.TrimSpace(l.Solution)
+	if !sokobanRecord(record) {
+		return "", nil
+	}
+	if _, err := b.replay(record); err != nil {
+		return "", fmt.Errorf("solution: %w", err)
+	}
+	if !b.solved() {
+		return "", errors.New("solution doesn't put every box on a goal")
+	}
+	return fmt.Sprintf("solution in %d moves, %d pushes", len(record), sokobanPushes(record)), nil
+}
+
+// sokobanPushes counts the pushes in a move record.
+func sokobanPushes(record string) int {
+	n := 0
+	for _, r := range record {
+		if unicode.IsUpper(r) {
+			n++
+		}
+	}
+	return n
+}
+
+type SokobanEngine struct {
+	Engine
+	board  *sokobanBoard
+	record []rune
+}
+
+// New loads a level, replaying the move record in the save. A record that
+// no longer fits the board is kept up to the first move that doesn't.
+func (e *SokobanEngine) New(l Level, s *Save) (GameEngine, error) {
+	board, err := parseSokoban(l.Initial)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		log.Printf("event=\"EmptyLevelLoad\" level_id=%d", l.ID)
+		s = l.CreateSave(l.Initial, false)
+	} else {
+		log.Printf("event=\"StatefulLevelLoad\" level_id=%d state=\"%v\"", l.ID, s.State)
+	}
+
+	lines := strings.Split(s.State, "\n")
+	if last := lines[len(lines)-1]; sokobanRecord(last) {
+		n, err := board.replay(last)
+		if err != nil {
+			log.Printf("event=\"sokoban_replay_failed\" level_id=%d err=\"%v\"", l.ID, err)
+		}
+		e.record = []rune(last)[:n]
+	}
+
+	e.GameName = l.Engine
+	e.Level = l
+	e.Save = *s
+	e.board = board
+	e.Grid = make([][]Cell, board.height)
+	for y := range e.Grid {
+		e.Grid[y] = make([]Cell, board.width)
+		for x := range e.Grid[y] {
+			e.Grid[y][x] = Cell{x: x, y: y, state: filled}
+			if board.walls[[2]int{x, y}] {
+				e.Grid[y][x].state = given
+			}
+		}
+	}
+	e.updateState()
+	return e, nil
+}
+
+// PrimaryAction walks the player to a cell. A cell next to the player is a
+// single step, pushing any box there.
+func (e *SokobanEngine) PrimaryAction(x, y int) error {
+	if !e.HasCell(x, y) {
+		return errors.New("coordinates out of bounds")
+	}
+	p := e.board.player
+	for letter, d := range sokobanSteps {
+		if p[0]+d[0] == x && p[1]+d[1] == y {
+			return e.step(letter)
+		}
+	}
+	path, ok := e.board.path([2]int{x, y})
+	if !ok {
+		return errors.New("the player can't walk there")
+	}
+	for _, letter := range path {
+		if err := e.step(letter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SecondaryAction takes back the last move.
+func (e *SokobanEngine) SecondaryAction(x, y int) error {
+	return e.undo()
+}
+
+// ClearCell takes back the last move.
+func (e *SokobanEngine) ClearCell(x, y int) error {
+	return e.undo()
+}
+
+// EnterValue takes back the last move on u.
+func (e *SokobanEngine) EnterValue(x, y int, v rune) error {
+	if v != 'u' {
+		return fmt.Errorf("%q doesn't do anything", v)
+	}
+	return e.undo()
+}
+
+// Evaluate reports whether every box is on a goal.
+func (e *SokobanEngine) Evaluate() (bool, error) {
+	return e.board.solved(), nil
+}
+
+// MoveCursor moves the player, and the cursor with it.
+func (e *SokobanEngine) MoveCursor(x, y, dx, dy int) (int, int, bool) {
+	for letter, d := range sokobanSteps {
+		if d == [2]int{dx, dy} {
+			e.step(letter)
+		}
+	}
+	return e.board.player[0], e.board.player[1], true
+}
+
+func (e *SokobanEngine) View(m model) string {
+	return lipgloss.JoinVertical(lipgloss.Left, e.gridView(), e.helpView())
+}
+
+// CellAt maps a position within the rendered view to grid coordinates.
+func (e *SokobanEngine) CellAt(col, row int) (int, int, bool) {
+	if col < 0 || row < 0 {
+		return 0, 0, false
+	}
+	x, y := col/e.cellWidth(), row
+	if !e.HasCell(x, y) {
+		return 0, 0, false
+	}
+	return x, y, true
+}
+
+// GlyphSets returns the glyphs walls, boxes, goals and the player are drawn
+// with.
+func (e *SokobanEngine) GlyphSets() map[string]GlyphSet {
+	return sokobanGlyphs
+}
+
+// --- Private Functions ---
+
+// step moves the player and records the move.
+func (e *SokobanEngine) step(letter rune) error {
+	got, ok := e.board.move(letter)
+	if !ok {
+		return errors.New("the way is blocked")
+	}
+	e.record = append(e.record, got)
+	e.updateState()
+	return nil
+}
+
+// undo takes back the last move in the record.
+func (e *SokobanEngine) undo() error {
+	if len(e.record) == 0 {
+		return errors.New("nothing to undo")
+	}
+	e.board.undo(e.record[len(e.record)-1])
+	e.record = e.record[:len(e.record)-1]
+	e.updateState()
+	return nil
+}
+
+// updateState copies the board into the grid and the save. With no moves
+// made the state is the level's initial board, so no save is kept.
+func (e *SokobanEngine) updateState() {
+	for y, row := range e.Grid {
+		for x := range row {
+			e.Grid[y][x].value = e.board.at([2]int{x, y})
+		}
+	}
+	e.Save.State = e.Level.Initial
+	if len(e.record) > 0 {
+		e.Save.State = e.board.String() + "\n" + string(e.record)
+	}
+	e.Save.Solved = e.board.solved()
+}
+
+// cellWidth is wide enough for the glyphs, and at least two columns so
+// cells come out roughly square.
+func (e *SokobanEngine) cellWidth() int {
+	return max(2, widestGlyph(glyphSetFor(sokobanGlyphs)))
+}
+
+func (e *SokobanEngine) gridView() string {
+	glyphs := glyphSetFor(sokobanGlyphs)
+	w := e.cellWidth()
+	var rows []string
+	for y := range e.Grid {
+		var cells []string
+		for x, cell := range e.Grid[y] {
+			p := [2]int{x, y}
+			g := glyphs[cell.value]
+			var s lipgloss.Style
+			switch cell.value {
+			case SokobanWallTile:
+				s = subtleStyle
+				if glyphWidth(g) == 1 {
+					g = strings.Repeat(g, w)
+				}
+			case SokobanPlayerTile, SokobanPlayerOnGoalTile:
+				s = focusedStyle.Bold(true)
+			case SokobanBoxTile:
+				s = digitStyle
+			case SokobanBoxOnGoalTile:
+				s = hintDoneStyle
+			case SokobanGoalTile:
+				s = hintStyle
+			}
+			if e.board.outside[p] {
+				g = ""
+			}
+			cells = append(cells, s.Width(w).AlignHorizontal(lipgloss.Center).Render(g))
+		}
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, cells...))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, rows...)
+}
+
+func (e *SokobanEngine) helpView() string {
+	help := "\nu, x or backspace: Undo\n"
+	help += e.statusView()
+	help += "arrow keys or hjkl to move\n"
+	help += "Press 'esc' to return to the menu.\n"
+	if e.Save.Solved {
+		help += "Congrats!\n"
+	}
+	return help
+}
+
+// statusView counts the moves and pushes made and the boxes on goals.
+func (e *SokobanEngine) statusView() string {
+	placed := 0
+	for p := range e.board.boxes {
+		if e.board.goals[p] {
+			placed++
+		}
+	}
+	record := string(e.record)
+	return fmt.Sprintf("Moves: %d\tPushes: %d\tBoxes: %d/%d\n", len(e.record), sokobanPushes(record), placed, len(e.board.boxes))
+}
@@ -0,0 +1,146 @@
+// This file reads sokoban collections, the .sok and .txt files sokoban
+// levels are commonly shared in, as level packs.
+//
+// A collection is a run of boards in XSB notation with text between them. A
+// line of text just before a board, or a comment starting with ;, names the
+// level, and "Key: value" lines after a board describe it: Title, Author and
+// Solution are read, a solution running on over following lines of moves.
+// The same keys before the first board describe the whole collection, along
+// with any other text there. Lines starting with :: are comments.
+//
+//	; Microban
+//	Author: David W. Skinner
+//
+//	; 1
+//	####
+//	# .#
+//	#  ###
+//	#*@  #
+//	#  $ #
+//	#  ###
+//	####
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// sokobanBoardLine reports whether a line of a collection is part of a
+// board: nothing but XSB characters, with at least one wall.
+func sokobanBoardLine(line string) bool {
+	if !strings.ContainsRune(line, SokobanWallTile) {
+		return false
+	}
+	for _, r := range line {
+		if !strings.ContainsRune("#@+$*.-_ \t", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// sokobanKeyValue splits a "Key: value" line, reporting false for lines
+// that aren't one.
+func sokobanKeyValue(line string) (string, string, bool) {
+	key, value, ok := strings.Cut(line, ":")
+	if !ok || key == "" || strings.ContainsAny(key, " \t") {
+		return "", "", false
+	}
+	return strings.ToLower(key), strings.TrimSpace(value), true
+}
+
+// decodeSokobanCollection reads the levels of a sokoban collection into a
+// level pack. Levels without a name are numbered, and levels without a move
+// record for a solution are given their solved board instead.
+func decodeSokobanCollection(data []byte) (*LevelPackYAML, error) {
+	pack := &LevelPackYAML{}
+	var level *Level
+	var board, text, description []string
+	inSolution := false
+
+	// endBoard finishes the board being read, if there is one, as a level.
+	endBoard := func() {
+		if board == nil {
+			return
+		}
+		pack.Levels = append(pack.Levels, Level{Engine: "sokoban", Initial: strings.Join(board, "\n")})
+		level = &pack.Levels[len(pack.Levels)-1]
+		if len(text) > 0 {
+			level.Name = text[len(text)-1]
+			text = text[:len(text)-1]
+		}
+		if len(pack.Levels) == 1 {
+			description = append(description, text...)
+		}
+		board, text = nil, nil
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		trimmed := strings.TrimSpace(line)
+		if sokobanBoardLine(line) {
+			board = append(board, strings.ReplaceAll(line, "\t", " "))
+			inSolution = false
+			continue
+		}
+		endBoard()
+
+		switch key, value, ok := sokobanKeyValue(trimmed); {
+		case trimmed == "" || strings.HasPrefix(trimmed, "::"):
+			inSolution = false
+		case inSolution && sokobanRecord(trimmed):
+			level.Solution += trimmed
+		case ok && level != nil:
+			inSolution = false
+			switch key {
+			case "title":
+				level.Name = value
+			case "author":
+				level.Author = value
+			case "solution":
+				level.Solution = value
+				inSolution = true
+			}
+		case ok && len(pack.Levels) == 0:
+			switch key {
+			case "title", "collection", "set":
+				pack.Name = value
+			case "author":
+				pack.Author = value
+			case "description", "comment":
+				description = append(description, value)
+			}
+		case strings.HasPrefix(trimmed, ";"):
+			if comment := strings.TrimSpace(strings.TrimPrefix(trimmed, ";")); comment != "" {
+				text = append(text, comment)
+			}
+		default:
+			text = append(text, trimmed)
+		}
+	}
+	endBoard()
+
+	if len(pack.Levels) == 0 {
+		return nil, errors.New("no sokoban levels found")
+	}
+	pack.Description = strings.Join(description, " ")
+	if pack.Name == "" && len(description) > 0 {
+		pack.Name, pack.Description = description[0], strings.Join(description[1:], " ")
+	}
+	names := make(map[string]bool)
+	for i := range pack.Levels {
+		l := &pack.Levels[i]
+		if l.Name == "" || names[l.Name] {
+			l.Name = strings.TrimSpace(fmt.Sprintf("%s %d", l.Name, i+1))
+		}
+		names[l.Name] = true
+		if !sokobanRecord(l.Solution) {
+			l.Solution = sokobanSolvedBoard(l.Initial)
+		}
+	}
+	return pack, nil
+}
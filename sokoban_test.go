@@ -0,0 +1,320 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSokobanInitial = "######\n#    #\n#@$ .#\n#    #\n######"
+
+// sokobanLevel is a sokoban level with the given board, solved by pushing
+// right twice.
+func sokobanLevel(initial string) Level {
+	return Level{Engine: "sokoban", Initial: initial, Solution: "RR"}
+}
+
+func TestParseSokobanErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		initial string
+		want    string
+	}{
+		{name: "bad character", initial: "#####\n#@$.#\n#x###", want: "should be one of"},
+		{name: "two players", initial: "######\n#@$.@#\n######", want: "want one player, found 2"},
+		{name: "no boxes", initial: "####\n#@ #\n####", want: "no boxes"},
+		{name: "more boxes than goals", initial: "######\n#@$$.#\n######", want: "2 boxes for 1 goals"},
+		{name: "open walls", initial: "#####\n @$.#\n#####", want: "don't shut the player in"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseSokoban(tc.initial)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected an error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestSokobanPlay(t *testing.T) {
+	e := newTestEngine(t, sokobanLevel(testSokobanInitial)).(*SokobanEngine)
+	if x, y, _ := e.MoveCursor(0, 0, -1, 0); x != 1 || y != 2 {
+		t.Errorf("expected the player to stay put against the wall, got %d,%d", x, y)
+	}
+	if e.Save.State != testSokobanInitial {
+		t.Errorf("expected no moves to leave the initial state, got %q", e.Save.State)
+	}
+
+	if x, y, _ := e.MoveCursor(0, 0, 1, 0); x != 2 || y != 2 {
+		t.Errorf("expected the cursor to follow the player to 2,2, got %d,%d", x, y)
+	}
+	e.MoveCursor(2, 2, 0, -1)
+	if got := e.statusView(); got != "Moves: 2\tPushes: 1\tBoxes: 0/1\n" {
+		t.Errorf("expected two moves and a push, got %q", got)
+	}
+	if want := "######\n# @  #\n#  $.#\n#    #\n######\nRu"; e.Save.State != want {
+		t.Errorf("expected the board and the record in the save, got %q", e.Save.State)
+	}
+
+	// Undo takes back the step, then the push.
+	e.EnterValue(0, 0, 'u')
+	e.ClearCell(0, 0)
+	if e.Save.State != testSokobanInitial || e.board.player != [2]int{1, 2} || !e.board.boxes[[2]int{2, 2}] {
+		t.Errorf("expected undoing every move to restore the level, got %q", e.Save.State)
+	}
+	if err := e.SecondaryAction(0, 0); err == nil {
+		t.Error("expected nothing to undo")
+	}
+	if err := e.EnterValue(0, 0, 'q'); err == nil {
+		t.Error("expected other keys to be refused")
+	}
+
+	e.MoveCursor(1, 2, 1, 0)
+	e.MoveCursor(2, 2, 1, 0)
+	if !e.Save.Solved || e.Outcome() != outcomeWon {
+		t.Errorf("expected the box on the goal to solve the level, got %q", e.Save.State)
+	}
+	if solved, _ := e.Evaluate(); !solved {
+		t.Error("expected Evaluate to find every box on a goal")
+	}
+
+	// Two boxes in a row can't be pushed.
+	e = newTestEngine(t, sokobanLevel("#######\n#@$$..#\n#######")).(*SokobanEngine)
+	e.MoveCursor(1, 1, 1, 0)
+	if len(e.record) != 0 {
+		t.Errorf("expected pushing two boxes to be refused, got %q", string(e.record))
+	}
+}
+
+func TestSokobanWalk(t *testing.T) {
+	e := newTestEngine(t, sokobanLevel(testSokobanInitial)).(*SokobanEngine)
+	if err := e.PrimaryAction(4, 3); err != nil {
+		t.Fatal(err)
+	}
+	if e.board.player != [2]int{4, 3} || sokobanPushes(string(e.record)) != 0 {
+		t.Errorf("expected a walk round the box without pushing it, got %q", string(e.record))
+	}
+	if len(e.record) != 4 {
+		t.Errorf("expected the shortest walk of 4 moves, got %q", string(e.record))
+	}
+	if err := e.PrimaryAction(0, 0); err == nil {
+		t.Error("expected a wall to be out of reach")
+	}
+
+	// A box next to the player is pushed.
+	e = newTestEngine(t, sokobanLevel(testSokobanInitial)).(*SokobanEngine)
+	e.PrimaryAction(2, 2)
+	if string(e.record) != "R" {
+		t.Errorf("expected a push, got %q", string(e.record))
+	}
+}
+
+func TestSokobanReplay(t *testing.T) {
+	e := newTestEngine(t, sokobanLevel(testSokobanInitial)).(*SokobanEngine)
+	e.MoveCursor(1, 2, 1, 0)
+	e.MoveCursor(2, 2, 0, 1)
+
+	// Opening the level again replays the record, and undo still works.
+	e = loadTestEngine(t, sokobanLevel(testSokobanInitial), e.GetSave()).(*SokobanEngine)
+	if string(e.record) != "Rd" || e.board.player != [2]int{2, 3} || !e.board.boxes[[2]int{3, 2}] {
+		t.Fatalf("expected the record to be replayed, got %q", string(e.record))
+	}
+	e.EnterValue(0, 0, 'u')
+	if e.board.player != [2]int{2, 2} {
+		t.Errorf("expected undo to take back the step, got %v", e.board.player)
+	}
+
+	// A record that doesn't fit the board is kept up to where it stops.
+	e = loadTestEngine(t, sokobanLevel(testSokobanInitial), &Save{State: "######\nRL"}).(*SokobanEngine)
+	if string(e.record) != "R" {
+		t.Errorf("expected the record to stop at the first move that doesn't fit, got %q", string(e.record))
+	}
+}
+
+func TestSokobanView(t *testing.T) {
+	e := newTestEngine(t, sokobanLevel("  ####\n###  #\n#@$ .#\n######")).(*SokobanEngine)
+	lines := strings.Split(e.gridView(), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines, got %d", len(lines))
+	}
+	if !strings.HasPrefix(lines[0], "    ████") {
+		t.Errorf("expected the floor outside the walls to be blank, got\n%s", strings.Join(lines, "\n"))
+	}
+	if !strings.Contains(lines[2], "●") || !strings.Contains(lines[2], "■") || !strings.Contains(lines[2], "·") {
+		t.Errorf("expected the player, box and goal, got\n%s", strings.Join(lines, "\n"))
+	}
+	if x, y, ok := e.CellAt(5, 2); !ok || x != 2 || y != 2 {
+		t.Errorf("expected column 5 row 2 to be the box, got %d,%d %v", x, y, ok)
+	}
+}
+
+func TestValidateSokoban(t *testing.T) {
+	testCases := []struct {
+		name     string
+		solution string
+		note     string
+		err      string
+	}{
+		{name: "record", solution: "RR", note: "solution in 2 moves, 2 pushes"},
+		{name: "solved board", solution: sokobanSolvedBoard(testSokobanInitial)},
+		{name: "short record", solution: "R", err: "every box on a goal"},
+		{name: "record into a wall", solution: "L", err: "move 1 (L)"},
+		{name: "push that is a step", solution: "U", err: "move 1 (U)"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			note, err := validateSokoban(Level{Initial: testSokobanInitial, Solution: tc.solution})
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Errorf("expected an error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil || note != tc.note {
+				t.Errorf("expected note %q, got %q (%v)", tc.note, note, err)
+			}
+		})
+	}
+}
+
+const testSokobanCollection = `::  A made up collection
+Title: Warehouse
+Author: Someone
+
+; Corridor
+######
+#    #
+#@$ .#
+#    #
+######
+Author: Someone Else
+Solution: R
+R
+
+  ####
+###  #
+#@$ .#
+######
+
+Short
+#####
+#@$.#
+#####
+Title: Step
+`
+
+func TestDecodeSokobanCollection(t *testing.T) {
+	pack, err := DecodeLevelPack([]byte(testSokobanCollection), formatSokoban)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pack.Name != "Warehouse" || pack.Author != "Someone" {
+		t.Errorf("expected the collection's title and author, got %q by %q", pack.Name, pack.Author)
+	}
+	if len(pack.Levels) != 3 {
+		t.Fatalf("expected 3 levels, got %d", len(pack.Levels))
+	}
+
+	first := pack.Levels[0]
+	if first.Name != "Corridor" || first.Author != "Someone Else" || first.Solution != "RR" || first.Initial != testSokobanInitial {
+		t.Errorf("expected the first level with its solution over two lines, got %+v", first)
+	}
+	second := pack.Levels[1]
+	if second.Name != "2" || second.Solution != "  ####\n###  #\n#   *#\n######" {
+		t.Errorf("expected an unnamed level numbered with its solved board, got %+v", second)
+	}
+	if !strings.HasPrefix(second.Initial, "  ####\n") {
+		t.Errorf("expected the board's leading spaces kept, got %q", second.Initial)
+	}
+	if pack.Levels[2].Name != "Step" {
+		t.Errorf("expected a title after the board to name the level, got %q", pack.Levels[2].Name)
+	}
+
+	if _, err := DecodeLevelPack([]byte("just some text\n"), formatSokoban); err == nil {
+		t.Error("expected a file without boards to be refused")
+	}
+}
+
+func TestImportSokobanCollection(t *testing.T) {
+	store := newTestStore(t)
+	path := filepath.Join(t.TempDir(), "boxes.sok")
+	untitled := strings.Replace(testSokobanCollection, "Title: Warehouse\n", "", 1)
+	if err := os.WriteFile(path, []byte(untitled), 0644); err != nil {
+		t.Fatalf("failed to write collection: %v", err)
+	}
+	pack, err := store.ImportLevelPackFile(path)
+	if err != nil {
+		t.Fatalf("failed to import collection: %v", err)
+	}
+	if pack.Name != "boxes" {
+		t.Errorf("expected an untitled collection to be named after its file, got %q", pack.Name)
+	}
+
+	levels, err := store.GetLevelsByPack(pack.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(levels) != 3 {
+		t.Fatalf("expected 3 levels, got %d", len(levels))
+	}
+	for _, l := range levels {
+		if !strings.Contains(l.Initial, ".") {
+			t.Errorf("expected the goals of %s to be kept, got %q", l.Name, l.Initial)
+		}
+		if _, err := newEngine(l, nil); err != nil {
+			t.Errorf("expected %s to load, got %v", l.Name, err)
+		}
+	}
+
+	// A solution that doesn't solve its level is refused.
+	bad := filepath.Join(t.TempDir(), "bad.txt")
+	if err := os.WriteFile(bad, []byte(strings.Replace(testSokobanCollection, "Solution: R\nR\n", "Solution: R\n", 1)), 0644); err != nil {
+		t.Fatalf("failed to write collection: %v", err)
+	}
+	if err := store.ImportLevelPack(bad); err == nil || !strings.Contains(err.Error(), "every box on a goal") {
+		t.Errorf("expected a short solution to be refused, got %v", err)
+	}
+}
+
+func TestExportSokobanRoundTrip(t *testing.T) {
+	store := newTestStore(t)
+	path := filepath.Join(t.TempDir(), "boxes.sok")
+	if err := os.WriteFile(path, []byte(testSokobanCollection), 0644); err != nil {
+		t.Fatalf("failed to write collection: %v", err)
+	}
+	pack, err := store.ImportLevelPackFile(path)
+	if err != nil {
+		t.Fatalf("failed to import collection: %v", err)
+	}
+	levels, err := store.GetLevelsByPack(pack.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{formatYAML, formatJSON} {
+		t.Run(format, func(t *testing.T) {
+			data, err := store.EncodeLevelPack(pack.ID, format)
+			if err != nil {
+				t.Fatalf("failed to export pack: %v", err)
+			}
+			exported, err := DecodeLevelPack(data, format)
+			if err != nil {
+				t.Fatalf("failed to read export: %v", err)
+			}
+			for i, l := range exported.Levels {
+				if l.Initial != levels[i].Initial || l.Solution != levels[i].Solution {
+					t.Errorf("expected %s to export unchanged, got %q", l.Name, l.Initial)
+				}
+			}
+
+			// The export imports again as the same levels.
+			other := newTestStore(t)
+			if _, err := other.importPack(exported); err != nil {
+				t.Fatalf("failed to import export: %v", err)
+			}
+		})
+	}
+}
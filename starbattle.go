@@ -0,0 +1,366 @@
+// This file implements the Star Battle game logic.
+//
+// The grid is square and split into as many regions as it has rows, mapped
+// out in the level's regions. The solution marks stars with StarTile. Every
+// row, column and region holds the same number of stars, one unless the
+// level's stars option says otherwise, and no two stars touch, not even
+// diagonally.
+//
+// As in a nonogram, the primary action places a star and the secondary
+// action marks a cell as empty. Stars that touch, or that overfill their row,
+// column or region, are flagged as soon as they are placed.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const StarTile = rune('*')
+
+var starBattleGlyphs = map[string]GlyphSet{
+	unicodeGlyphs: {
+		StarTile:       "★",
+		KnownEmptyTile: "·",
+		EmptyTile:      " ",
+	},
+	asciiGlyphs: {
+		StarTile:       "*",
+		KnownEmptyTile: "x",
+		EmptyTile:      " ",
+	},
+	emojiGlyphs: {
+		StarTile:       "⭐",
+		KnownEmptyTile: "·",
+		EmptyTile:      " ",
+	},
+}
+
+// starBattleCellWidth is the width of a cell between its borders.
+const starBattleCellWidth = 3
+
+// starBattleRules are a level's regions and how many stars go in each row,
+// column and region.
+type starBattleRules struct {
+	size   int
+	stars  int
+	region [][]int
+	units  [][][2]int
+	// unitsAt lists the row, column and region of each cell by their index
+	// in units.
+	unitsAt [][][3]int
+}
+
+// starBattleRulesFor reads the rules of a level from its solution, regions
+// and options.
+func starBattleRulesFor(l Level) (*starBattleRules, error) {
+	rows := strings.Split(strings.Trim(l.Solution, "\n"), "\n")
+	n := len(rows)
+	for y, row := range rows {
+		if len(row) != n {
+			return nil, fmt.Errorf("solution row %d has %d cells, want %d for a square grid", y+1, len(row), n)
+		}
+	}
+	r := &starBattleRules{size: n, stars: 1}
+	if v, ok := l.Options["stars"]; ok {
+		stars, err := strconv.Atoi(v)
+		if err != nil || stars < 1 || stars > (n+1)/2 {
+			return nil, fmt.Errorf("invalid stars option %q", v)
+		}
+		r.stars = stars
+	}
+
+	if strings.TrimSpace(l.Regions) == "" {
+		return nil, errors.New("star battle levels need regions")
+	}
+	region, count, err := parseRegionMap(l.Regions, n, n)
+	if err != nil {
+		return nil, err
+	}
+	if count != n {
+		return nil, fmt.Errorf("regions name %d regions, want %d", count, n)
+	}
+	r.region = region
+
+	// Rows come first in units, then columns, then regions.
+	regions := make([][][2]int, n)
+	r.units = make([][][2]int, 2*n)
+	r.unitsAt = make([][][3]int, n)
+	for y := 0; y < n; y++ {
+		r.unitsAt[y] = make([][3]int, n)
+		for x := 0; x < n; x++ {
+			r.units[y] = append(r.units[y], [2]int{x, y})
+			r.units[n+x] = append(r.units[n+x], [2]int{x, y})
+			regions[region[y][x]] = append(regions[region[y][x]], [2]int{x, y})
+			r.unitsAt[y][x] = [3]int{y, n + x, 2*n + region[y][x]}
+		}
+	}
+	for _, cells := range regions {
+		if !connectedCells(cells) {
+			return nil, fmt.Errorf("region at row %d column %d isn't joined up", cells[0][1]+1, cells[0][0]+1)
+		}
+		r.units = append(r.units, cells)
+	}
+	return r, nil
+}
+
+// check counts the stars in every row, column and region. It returns the
+// stars that break a rule, by touching another star or by overfilling a
+// unit, and how each unit stands.
+func (r *starBattleRules) check(values [][]rune) ([][]bool, []lineStatus) {
+	broken := make([][]bool, r.size)
+	for y := range broken {
+		broken[y] = make([]bool, r.size)
+	}
+	status := make([]lineStatus, len(r.units))
+	for i, u := range r.units {
+		var stars [][2]int
+		for _, c := range u {
+			if values[c[1]][c[0]] == StarTile {
+				stars = append(stars, c)
+			}
+		}
+		switch {
+		case len(stars) > r.stars:
+			status[i] = lineBroken
+			for _, c := range stars {
+				broken[c[1]][c[0]] = true
+			}
+		case len(stars) == r.stars:
+			status[i] = lineSatisfied
+		}
+	}
+	for y, row := range values {
+		for x, v := range row {
+			if v != StarTile {
+				continue
+			}
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := x+dx, y+dy
+					if (dx != 0 || dy != 0) && ny >= 0 && ny < r.size && nx >= 0 && nx < r.size && values[ny][nx] == StarTile {
+						broken[y][x] = true
+					}
+				}
+			}
+		}
+	}
+	return broken, status
+}
+
+// solved reports whether every row, column and region has its stars and no
+// star breaks a rule.
+func (r *starBattleRules) solved(values [][]rune) bool {
+	broken, status := r.check(values)
+	for _, s := range status {
+		if s != lineSatisfied {
+			return false
+		}
+	}
+	for _, row := range broken {
+		for _, b := range row {
+			if b {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// edge returns the border between two neighbouring cells, heavy between
+// regions.
+func (r *starBattleRules) edge(x1, y1, x2, y2 int) gridEdge {
+	in := func(x, y int) bool { return x >= 0 && x < r.size && y >= 0 && y < r.size }
+	if !in(x1, y1) || !in(x2, y2) || r.region[y1][x1] != r.region[y2][x2] {
+		return edgeRegion
+	}
+	return edgeCell
+}
+
+// validateStarBattle refuses levels whose rules are invalid, whose solution
+// breaks them or whose givens don't match the solution.
+func validateStarBattle(l Level) (string, error) {
+	r, err := starBattleRulesFor(l)
+	if err != nil {
+		return "", err
+	}
+	solution := strings.Split(strings.Trim(l.Solution, "\n"), "\n")
+	values := make([][]rune, r.size)
+	for y, row := range solution {
+		values[y] = []rune(row)
+	}
+	if !r.solved(values) {
+		return "", errors.New("solution breaks the rules")
+	}
+	for y, row := range strings.Split(strings.Trim(l.Initial, "\n"), "\n") {
+		for x, v := range row {
+			if y >= r.size || x >= r.size {
+				continue
+			}
+			star := values[y][x] == StarTile
+			if (v == StarTile && !star) || (v == KnownEmptyTile && star) {
+				return "", fmt.Errorf("given at row %d column %d doesn't match the solution", y+1, x+1)
+			}
+		}
+	}
+	return "", nil
+}
+
+type StarBattleEngine struct {
+	Engine
+	rules      *starBattleRules
+	unitStatus []lineStatus
+}
+
+func (e *StarBattleEngine) New(l Level, s *Save) (GameEngine, error) {
+	rules, err := starBattleRulesFor(l)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := e.Engine.New(l, s); err != nil {
+		return nil, err
+	}
+	if e.GetHeight() != rules.size || e.GetWidth() != rules.size {
+		return nil, errors.New("initial state and solution are different sizes")
+	}
+	e.rules = rules
+	e.updateStatus()
+	return e, nil
+}
+
+// PrimaryAction places a star.
+func (e *StarBattleEngine) PrimaryAction(x, y int) error {
+	if err := e.setCellValue(x, y, StarTile); err != nil {
+		return err
+	}
+	e.updateStatus()
+	return nil
+}
+
+// SecondaryAction marks a cell as empty.
+func (e *StarBattleEngine) SecondaryAction(x, y int) error {
+	if err := e.setCellValue(x, y, KnownEmptyTile); err != nil {
+		return err
+	}
+	e.updateStatus()
+	return nil
+}
+
+// dragPaints lets a drag paint stars and empty marks across the grid.
+func (e *StarBattleEngine) dragPaints() {}
+
+func (e *StarBattleEngine) ClearCell(x, y int) error {
+	if err := e.Engine.ClearCell(x, y); err != nil {
+		return err
+	}
+	e.updateStatus()
+	return nil
+}
+
+// Evaluate reports whether every row, column and region holds its stars
+// without any of them touching. Empty marks count as blank.
+func (e *StarBattleEngine) Evaluate() (bool, error) {
+	return e.rules.solved(e.values()), nil
+}
+
+func (e *StarBattleEngine) View(m model) string {
+	return lipgloss.JoinVertical(lipgloss.Left, e.gridView(m), e.helpView(m))
+}
+
+// CellAt maps a position within the rendered view to grid coordinates.
+// Borders aren't cells.
+func (e *StarBattleEngine) CellAt(col, row int) (int, int, bool) {
+	return e.borderedGrid(nil).cellAt(col, row)
+}
+
+// GlyphSets returns the glyphs stars and empty marks can be drawn with.
+func (e *StarBattleEngine) GlyphSets() map[string]GlyphSet {
+	return starBattleGlyphs
+}
+
+// --- Private Functions ---
+
+// updateStatus flags stars that break a rule and refreshes the solved flag
+// on the save.
+func (e *StarBattleEngine) updateStatus() {
+	values := e.values()
+	broken, status := e.rules.check(values)
+	for y, row := range e.Grid {
+		for x := range row {
+			e.Grid[y][x].RunValidation(!broken[y][x])
+		}
+	}
+	e.unitStatus = status
+	e.Save.Solved = e.rules.solved(values)
+}
+
+// borderedGrid lays the grid out with heavy borders between regions.
+func (e *StarBattleEngine) borderedGrid(cell func(x, y int) string) borderedGrid {
+	return borderedGrid{
+		width:     e.rules.size,
+		height:    e.rules.size,
+		cellWidth: starBattleCellWidth,
+		edge:      e.rules.edge,
+		cell:      cell,
+	}
+}
+
+// gridView draws the grid, each cell on the background of its region.
+func (e *StarBattleEngine) gridView(m model) string {
+	glyphs := glyphSetFor(starBattleGlyphs)
+	return e.borderedGrid(func(x, y int) string {
+		cell := e.Grid[y][x]
+		s := regionStyle(e.rules.region[y][x])
+		switch {
+		case cell.state == invalid:
+			s = s.Inherit(hintErrorStyle).Bold(true)
+		case cell.value == KnownEmptyTile:
+			s = s.Inherit(renderStyles[KnownEmptyTile])
+		case cell.state == given:
+			s = s.Inherit(digitGivenStyle)
+		}
+		if x == m.cursorX && y == m.cursorY {
+			s = highlightStyle
+		}
+		g, ok := glyphs[cell.value]
+		if !ok {
+			g = glyphs[EmptyTile]
+		}
+		return s.Width(starBattleCellWidth).AlignHorizontal(lipgloss.Center).Render(g)
+	}).view()
+}
+
+func (e *StarBattleEngine) helpView(m model) string {
+	help := "\nz: Star\tx: Mark empty\tbackspace: clear\n"
+	help += e.statusView(m)
+	help += "arrow keys or hjkl to move\n"
+	help += "Press 'esc' to return to the menu.\n"
+	if e.Save.Solved {
+		help += "Congrats!\n"
+	}
+	return help
+}
+
+// statusView counts the stars in the row, column and region under the
+// cursor.
+func (e *StarBattleEngine) statusView(m model) string {
+	if !e.HasCell(m.cursorX, m.cursorY) {
+		return "\n"
+	}
+	var counts [3]string
+	for i, u := range e.rules.unitsAt[m.cursorY][m.cursorX] {
+		stars := 0
+		for _, c := range e.rules.units[u] {
+			if e.Grid[c[1]][c[0]].value == StarTile {
+				stars++
+			}
+		}
+		counts[i] = hintLineStyle(e.unitStatus[u]).Render(fmt.Sprintf("%d/%d", stars, e.rules.stars))
+	}
+	return fmt.Sprintf("Row: %s\tColumn: %s\tRegion: %s\n", counts[0], counts[1], counts[2])
+}
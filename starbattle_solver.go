@@ -0,0 +1,132 @@
+package main
+
+import "strings"
+
+// starBattleSearchBudget caps how many rows countStarBattleSolutions fills
+// in before giving up on a level.
+const starBattleSearchBudget = 1000000
+
+// starBattleSolver places stars a row at a time, keeping count of the stars
+// in each column and region.
+type starBattleSolver struct {
+	rules       *starBattleRules
+	initial     [][]rune
+	colStars    []int
+	regionStars []int
+	prev        []bool
+	budget      int
+}
+
+// countStarBattleSolutions counts the ways to place a level's stars, up to
+// limit, keeping the stars and empty marks its initial state gives.
+func countStarBattleSolutions(l Level, limit int) (int, bool) {
+	rules, err := starBattleRulesFor(l)
+	if err != nil {
+		return 0, true
+	}
+	n := rules.size
+	s := &starBattleSolver{
+		rules:       rules,
+		initial:     make([][]rune, n),
+		colStars:    make([]int, n),
+		regionStars: make([]int, n),
+		prev:        make([]bool, n),
+		budget:      starBattleSearchBudget,
+	}
+	rows := strings.Split(strings.Trim(l.Initial, "\n"), "\n")
+	for y := range s.initial {
+		s.initial[y] = make([]rune, n)
+		for x := range s.initial[y] {
+			if y < len(rows) && x < len(rows[y]) {
+				s.initial[y][x] = rune(rows[y][x])
+			}
+		}
+	}
+	count := s.search(0, limit)
+	return count, s.budget > 0
+}
+
+// search counts the ways to fill in the rows from y down, up to limit.
+func (s *starBattleSolver) search(y, limit int) int {
+	n, k := s.rules.size, s.rules.stars
+	if y == n {
+		for i := 0; i < n; i++ {
+			if s.colStars[i] != k || s.regionStars[i] != k {
+				return 0
+			}
+		}
+		return 1
+	}
+	if s.budget--; s.budget <= 0 {
+		return 0
+	}
+
+	// Every column needs its stars from the rows that are left.
+	for x := 0; x < n; x++ {
+		if s.colStars[x]+(n-y+1)/2 < k {
+			return 0
+		}
+	}
+
+	count := 0
+	row := make([]bool, n)
+	var place func(x, placed int)
+	place = func(x, placed int) {
+		if count >= limit || s.budget <= 0 {
+			return
+		}
+		if placed == k {
+			for i := x; i < n; i++ {
+				if s.initial[y][i] == StarTile {
+					return
+				}
+			}
+			if s.regionsReachable(y) {
+				prev := s.prev
+				s.prev = row
+				count += s.search(y+1, limit-count)
+				s.prev = prev
+			}
+			return
+		}
+		if n-x < 2*(k-placed)-1 {
+			return
+		}
+		r := s.rules.region[y][x]
+		fits := s.initial[y][x] != KnownEmptyTile &&
+			(x == 0 || !row[x-1]) &&
+			!s.prev[x] && (x == 0 || !s.prev[x-1]) && (x == n-1 || !s.prev[x+1]) &&
+			s.colStars[x] < k && s.regionStars[r] < k
+		if fits {
+			row[x] = true
+			s.colStars[x]++
+			s.regionStars[r]++
+			place(x+1, placed+1)
+			row[x] = false
+			s.colStars[x]--
+			s.regionStars[r]--
+		}
+		if s.initial[y][x] != StarTile {
+			place(x+1, placed)
+		}
+	}
+	place(0, 0)
+	return count
+}
+
+// regionsReachable reports whether, once row y is filled in, every region
+// could still get its stars from the cells it has in the rows below.
+func (s *starBattleSolver) regionsReachable(y int) bool {
+	left := make([]int, s.rules.size)
+	for yy := y + 1; yy < s.rules.size; yy++ {
+		for x := 0; x < s.rules.size; x++ {
+			left[s.rules.region[yy][x]]++
+		}
+	}
+	for r := range left {
+		if s.regionStars[r]+left[r] < s.rules.stars {
+			return false
+		}
+	}
+	return true
+}
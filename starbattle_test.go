@@ -0,0 +1,126 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	testStarBattleRegions  = "abbbc\nbbbbc\ndbdcc\ndddcc\ndddee"
+	testStarBattleSolution = "*....\n..*..\n....*\n.*...\n...*."
+	testStarBattleBlank    = ".....\n.....\n.....\n.....\n....."
+)
+
+var testStarBattleLevel = Level{
+	Engine:   "starbattle",
+	Initial:  testStarBattleBlank,
+	Solution: testStarBattleSolution,
+	Regions:  testStarBattleRegions,
+}
+
+func TestStarBattleRulesErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		regions string
+		options LevelOptions
+		want    string
+	}{
+		{name: "no regions", want: "need regions"},
+		{name: "too few regions", regions: "aabbc\naabbc\naabbc\naabbc\naabbc", want: "want 5"},
+		{name: "split region", regions: "abcda\nbbcdd\neecdd\neeccd\neeccd", want: "joined up"},
+		{name: "too many stars", regions: testStarBattleRegions, options: LevelOptions{"stars": "4"}, want: "stars option"},
+		{name: "stars not a number", regions: testStarBattleRegions, options: LevelOptions{"stars": "two"}, want: "stars option"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := starBattleRulesFor(Level{Solution: testStarBattleSolution, Regions: tc.regions, Options: tc.options})
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected an error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestStarBattlePlay(t *testing.T) {
+	e := newTestEngine(t, testStarBattleLevel).(*StarBattleEngine)
+	for y, row := range strings.Split(testStarBattleSolution, "\n") {
+		for x, v := range row {
+			if v == StarTile {
+				if err := e.PrimaryAction(x, y); err != nil {
+					t.Fatalf("failed to place a star: %v", err)
+				}
+			}
+		}
+	}
+	if !e.Save.Solved {
+		t.Fatalf("expected the stars to solve the level, got %q", e.Save.State)
+	}
+
+	// Empty marks don't count against a solution.
+	e.SecondaryAction(1, 0)
+	if !e.Save.Solved {
+		t.Error("expected an empty mark to leave the level solved")
+	}
+	e.ClearCell(0, 0)
+	if e.Save.Solved {
+		t.Error("expected clearing a star to unsolve the level")
+	}
+}
+
+func TestStarBattleConflicts(t *testing.T) {
+	testCases := []struct {
+		name    string
+		stars   [][2]int
+		invalid [][2]int
+	}{
+		{name: "apart", stars: [][2]int{{0, 0}, {2, 1}}},
+		{name: "touching diagonally", stars: [][2]int{{0, 0}, {1, 1}}, invalid: [][2]int{{0, 0}, {1, 1}}},
+		{name: "same row", stars: [][2]int{{0, 2}, {3, 2}}, invalid: [][2]int{{0, 2}, {3, 2}}},
+		{name: "same column", stars: [][2]int{{4, 0}, {4, 3}}, invalid: [][2]int{{4, 0}, {4, 3}}},
+		{name: "same region", stars: [][2]int{{1, 0}, {3, 0}, {0, 4}}, invalid: [][2]int{{1, 0}, {3, 0}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newTestEngine(t, testStarBattleLevel).(*StarBattleEngine)
+			for _, c := range tc.stars {
+				e.PrimaryAction(c[0], c[1])
+			}
+			want := make(map[[2]int]bool)
+			for _, c := range tc.invalid {
+				want[c] = true
+			}
+			for _, c := range tc.stars {
+				if got := e.Grid[c[1]][c[0]].state == invalid; got != want[c] {
+					t.Errorf("star at %v: expected invalid %v, got %v", c, want[c], got)
+				}
+			}
+		})
+	}
+}
+
+func TestCountStarBattleSolutions(t *testing.T) {
+	testCases := []struct {
+		name    string
+		initial string
+		regions string
+		options LevelOptions
+		want    int
+	}{
+		{name: "unique", initial: testStarBattleBlank, regions: testStarBattleRegions, want: 1},
+		{name: "columns as regions", initial: testStarBattleBlank, regions: "abcde\nabcde\nabcde\nabcde\nabcde", want: 2},
+		{name: "given star", initial: "..*..\n.....\n.....\n.....\n.....", regions: "abcde\nabcde\nabcde\nabcde\nabcde", want: 2},
+		{name: "given mark against the solution", initial: "X....\n.....\n.....\n.....\n.....", regions: testStarBattleRegions, want: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := Level{Initial: tc.initial, Solution: testStarBattleSolution, Regions: tc.regions, Options: tc.options}
+			got, ok := countStarBattleSolutions(l, 2)
+			if !ok || got != tc.want {
+				t.Errorf("expected %d solutions, got %d (ok %v)", tc.want, got, ok)
+			}
+		})
+	}
+}
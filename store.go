@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 
 	_ "modernc.org/sqlite"
@@ -22,14 +25,53 @@ func sqlIn(query string, args []int) (string, []any, error) {
 	return query, iargs, nil
 }
 
+// querier is the part of *sql.DB and *sql.Tx the store runs queries with.
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
 // Store handles all database operations.
 type Store struct {
 	db *sql.DB
+	tx *sql.Tx
+}
+
+// q returns the transaction the store is part of, or the database.
+func (s *Store) q() querier {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.db
+}
+
+// InTx runs fn with a store whose queries all run in one transaction. The
+// transaction commits if fn returns nil and rolls back otherwise. Nested
+// calls join the outer transaction.
+func (s *Store) InTx(fn func(tx *Store) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(&Store{db: s.db, tx: tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // NewStore creates a new Store and initializes the database connection.
+// Foreign keys are enforced on every connection.
 func NewStore(dataSourceName string) (*Store, error) {
-	db, err := sql.Open("sqlite", dataSourceName)
+	sep := "?"
+	if strings.Contains(dataSourceName, "?") {
+		sep = "&"
+	}
+	db, err := sql.Open("sqlite", dataSourceName+sep+"_pragma=foreign_keys(1)")
 	if err != nil {
 		return nil, err
 	}
@@ -43,74 +85,268 @@ func NewStore(dataSourceName string) (*Store, error) {
 	return store, nil
 }
 
-// Migrate creates the necessary database tables if they don't already exist.
-func (s *Store) Migrate() error {
-	_, err := s.db.Exec(`
-		CREATE TABLE IF NOT EXISTS level_packs (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT NOT NULL UNIQUE,
-			author TEXT,
-			version INTEGER NOT NULL DEFAULT 1,
-			description TEXT
-		);
+// migrations upgrade the schema one version at a time. The schema version is
+// kept in PRAGMA user_version, and migration i brings it to version i+1.
+var migrations = []string{
+	// 1: the original schema. Databases created before versioning already
+	// have these tables, so every statement must be safe to run again.
+	`
+	CREATE TABLE IF NOT EXISTS level_packs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE,
+		author TEXT,
+		version INTEGER NOT NULL DEFAULT 1,
+		description TEXT
+	);
+	CREATE TABLE IF NOT EXISTS levels (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		level_pack_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		author TEXT,
+		initial_state TEXT NOT NULL,
+		solution TEXT NOT NULL,
+		engine TEXT NOT NULL,
+		FOREIGN KEY (level_pack_id) REFERENCES level_packs(id),
+		UNIQUE(level_pack_id, name)
+	);
+	CREATE TABLE IF NOT EXISTS saves (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		level_id INTEGER NOT NULL UNIQUE,
+		state TEXT NOT NULL,
+		solved BOOLEAN NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (level_id) REFERENCES levels(id)
+	);
+	`,
+	// 2: rebuild levels and saves so deleting a pack cascades to its levels
+	// and their saves. Rows orphaned before foreign keys were enforced are
+	// dropped on the way.
+	`
+	CREATE TABLE levels_new (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		level_pack_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		author TEXT,
+		initial_state TEXT NOT NULL,
+		solution TEXT NOT NULL,
+		engine TEXT NOT NULL,
+		FOREIGN KEY (level_pack_id) REFERENCES level_packs(id) ON DELETE CASCADE,
+		UNIQUE(level_pack_id, name)
+	);
+	INSERT INTO levels_new (id, level_pack_id, name, author, initial_state, solution, engine)
+		SELECT id, level_pack_id, name, author, initial_state, solution, engine
+		FROM levels
+		WHERE level_pack_id IN (SELECT id FROM level_packs);
+	DROP TABLE levels;
+	ALTER TABLE levels_new RENAME TO levels;
+
+	CREATE TABLE saves_new (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		level_id INTEGER NOT NULL UNIQUE,
+		state TEXT NOT NULL,
+		solved BOOLEAN NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (level_id) REFERENCES levels(id) ON DELETE CASCADE
+	);
+	INSERT INTO saves_new (id, level_id, state, solved, created_at, updated_at)
+		SELECT id, level_id, state, solved, created_at, updated_at
+		FROM saves
+		WHERE level_id IN (SELECT id FROM levels);
+	DROP TABLE saves;
+	ALTER TABLE saves_new RENAME TO saves;
+	`,
+	// 3: files that ship with a pack in a bundle, such as a word list or a
+	// thumbnail.
+	`
+	CREATE TABLE pack_assets (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		level_pack_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		data BLOB NOT NULL,
+		FOREIGN KEY (level_pack_id) REFERENCES level_packs(id) ON DELETE CASCADE,
+		UNIQUE(level_pack_id, name)
+	);
+	`,
+	// 4: whether each pack's signature was verified on import, and by whom.
+	`
+	ALTER TABLE level_packs ADD COLUMN integrity TEXT NOT NULL DEFAULT 'unsigned';
+	ALTER TABLE level_packs ADD COLUMN signer TEXT NOT NULL DEFAULT '';
+	`,
+	// 5: a stable identity for each level, filled in by
+	// backfillLevelIdentities.
+	`
+	ALTER TABLE levels ADD COLUMN uuid TEXT NOT NULL DEFAULT '';
+	ALTER TABLE levels ADD COLUMN identity TEXT NOT NULL DEFAULT '';
+	CREATE INDEX levels_identity ON levels(identity);
+	`,
+	// 6: key saves by level identity rather than row id, so progress follows
+	// a level that is renamed or appears in several packs. Where two rows of
+	// the same level both had saves, a solved save wins, then the newest.
+	`
+	CREATE TABLE saves_new (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		level_identity TEXT NOT NULL UNIQUE,
+		state TEXT NOT NULL,
+		solved BOOLEAN NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT OR REPLACE INTO saves_new (level_identity, state, solved, created_at, updated_at)
+		SELECT levels.identity, saves.state, saves.solved, saves.created_at, saves.updated_at
+		FROM saves
+		JOIN levels ON levels.id = saves.level_id
+		ORDER BY saves.solved, saves.updated_at;
+	DROP TABLE saves;
+	ALTER TABLE saves_new RENAME TO saves;
+	`,
+	// 7: engine specific rules for each level, as JSON.
+	`
+	ALTER TABLE levels ADD COLUMN options TEXT NOT NULL DEFAULT '';
+	`,
+	// 8: cells that hold several values, such as kakuro clues.
+	`
+	ALTER TABLE levels ADD COLUMN cells TEXT NOT NULL DEFAULT '';
+	`,
+	// 9: region maps, such as jigsaw sudoku regions.
+	`
+	ALTER TABLE levels ADD COLUMN regions TEXT NOT NULL DEFAULT '';
+	`,
+	// 10: edge clues, such as skyscraper counts.
+	`
+	ALTER TABLE levels ADD COLUMN edges TEXT NOT NULL DEFAULT '';
+	`,
+}
+
+// migrationSteps run Go code after the migration to the schema version they
+// are keyed by, for data changes SQL can't express.
+var migrationSteps = map[int]func(tx *sql.Tx) error{
+	5: backfillLevelIdentities,
+}
+
+// backfillLevelIdentities works out the identity of levels stored before
+// levels had one.
+func backfillLevelIdentities(tx *sql.Tx) error {
+	rows, err := tx.Query(`
+		SELECT id, uuid, initial_state, solution, engine
+		FROM levels
+		WHERE identity = '';
 	`)
 	if err != nil {
 		return err
 	}
-	_, err = s.db.Exec(`
-		CREATE TABLE IF NOT EXISTS levels (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			level_pack_id INTEGER NOT NULL,
-			name TEXT NOT NULL,
-			author TEXT,
-			initial_state TEXT NOT NULL,
-			solution TEXT NOT NULL,
-			engine TEXT NOT NULL,
-			FOREIGN KEY (level_pack_id) REFERENCES level_packs(id),
-			UNIQUE(level_pack_id, name)
-		);
-	`)
+	identities := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var level Level
+		if err := rows.Scan(&id, &level.UUID, &level.Initial, &level.Solution, &level.Engine); err != nil {
+			rows.Close()
+			return err
+		}
+		identities[id] = level.LevelIdentity()
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, identity := range identities {
+		if _, err := tx.Exec("UPDATE levels SET identity = ? WHERE id = ?;", identity, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Migrate brings the database schema up to date. Pending migrations run in
+// one transaction. Table rebuilds need foreign keys off while they run, so
+// the keys are checked once before committing instead.
+func (s *Store) Migrate() error {
+	ctx := context.Background()
+	conn, err := s.db.Conn(ctx)
 	if err != nil {
 		return err
 	}
-	_, err = s.db.Exec(`
-		CREATE TABLE IF NOT EXISTS saves (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			level_id INTEGER NOT NULL UNIQUE,
-			state TEXT NOT NULL,
-			solved BOOLEAN NOT NULL DEFAULT 0,
-			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (level_id) REFERENCES levels(id)
-		);
-	`)
-	return err
+	defer conn.Close()
+
+	var version int
+	if err := conn.QueryRowContext(ctx, "PRAGMA user_version;").Scan(&version); err != nil {
+		return err
+	}
+	if version >= len(migrations) {
+		return nil
+	}
+
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF;"); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON;")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for v := version; v < len(migrations); v++ {
+		if _, err := tx.Exec(migrations[v]); err != nil {
+			return fmt.Errorf("migrating to schema version %d: %w", v+1, err)
+		}
+		if step, ok := migrationSteps[v+1]; ok {
+			if err := step(tx); err != nil {
+				return fmt.Errorf("migrating to schema version %d: %w", v+1, err)
+			}
+		}
+		log.Printf("event=\"migrated_schema\" version=%d", v+1)
+	}
+
+	rows, err := tx.Query("PRAGMA foreign_key_check;")
+	if err != nil {
+		return err
+	}
+	violated := rows.Next()
+	rows.Close()
+	if violated {
+		return errors.New("foreign key check failed after migrating")
+	}
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d;", len(migrations))); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // UpsertLevelPack inserts or updates a level pack.
+// Packs made locally are unsigned.
 func (s *Store) UpsertLevelPack(pack *LevelPack) error {
-	row := s.db.QueryRow(`
-		INSERT INTO level_packs (name, author, version, description)
-		VALUES (?, ?, ?, ?)
+	integrity := pack.Integrity
+	if integrity == "" {
+		integrity = integrityUnsigned
+	}
+	row := s.q().QueryRow(`
+		INSERT INTO level_packs (name, author, version, description, integrity, signer)
+		VALUES (?, ?, ?, ?, ?, ?)
 		ON CONFLICT(name) DO UPDATE SET
 			author = excluded.author,
 			version = excluded.version,
-			description = excluded.description
+			description = excluded.description,
+			integrity = excluded.integrity,
+			signer = excluded.signer
 		RETURNING id;
-	`, pack.Name, pack.Author, pack.Version, pack.Description)
+	`, pack.Name, pack.Author, pack.Version, pack.Description, integrity, pack.Signer)
 	return row.Scan(&pack.ID)
 }
 
 // GetLevelPack retrieves a level pack by its ID.
 func (s *Store) GetLevelPack(id int) (*LevelPack, error) {
 	log.Printf("event=\"get_level_pack\" id=%d", id)
-	row := s.db.QueryRow(`
-		SELECT id, name, author, version, description
+	row := s.q().QueryRow(`
+		SELECT id, name, author, version, description, integrity, signer
 		FROM level_packs
 		WHERE id = ?;
 	`, id)
 	pack := &LevelPack{}
-	err := row.Scan(&pack.ID, &pack.Name, &pack.Author, &pack.Version, &pack.Description)
+	err := row.Scan(&pack.ID, &pack.Name, &pack.Author, &pack.Version, &pack.Description, &pack.Integrity, &pack.Signer)
 	if err != nil {
 		return nil, err
 	}
@@ -121,8 +357,8 @@ func (s *Store) GetLevelPack(id int) (*LevelPack, error) {
 // GetLevelPacks retrieves all level packs.
 func (s *Store) GetAllLevelPacks() ([]LevelPack, error) {
 	log.Println("event=\"get_all_level_packs\"")
-	rows, err := s.db.Query(`
-		SELECT id, name, author, version, description
+	rows, err := s.q().Query(`
+		SELECT id, name, author, version, description, integrity, signer
 		FROM level_packs;
 	`)
 	if err != nil {
@@ -133,7 +369,7 @@ func (s *Store) GetAllLevelPacks() ([]LevelPack, error) {
 	var packs []LevelPack
 	for rows.Next() {
 		pack := LevelPack{}
-		err := rows.Scan(&pack.ID, &pack.Name, &pack.Author, &pack.Version, &pack.Description)
+		err := rows.Scan(&pack.ID, &pack.Name, &pack.Author, &pack.Version, &pack.Description, &pack.Integrity, &pack.Signer)
 		if err != nil {
 			return nil, err
 		}
@@ -143,30 +379,224 @@ func (s *Store) GetAllLevelPacks() ([]LevelPack, error) {
 	return packs, nil
 }
 
-// UpsertLevel inserts or updates a level.
+// FindLevelPack looks a level pack up by its ID or, failing that, its name.
+func (s *Store) FindLevelPack(ref string) (*LevelPack, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		pack, err := s.GetLevelPack(id)
+		if err == nil {
+			return pack, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+	}
+	log.Printf("event=\"get_level_pack_by_name\" name=\"%s\"", ref)
+	row := s.q().QueryRow(`
+		SELECT id, name, author, version, description, integrity, signer
+		FROM level_packs
+		WHERE name = ?;
+	`, ref)
+	pack := &LevelPack{}
+	err := row.Scan(&pack.ID, &pack.Name, &pack.Author, &pack.Version, &pack.Description, &pack.Integrity, &pack.Signer)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("no level pack with id or name %q", ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pack, nil
+}
+
+// RenameLevelPack changes the name of a level pack.
+func (s *Store) RenameLevelPack(id int, name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("level pack name cannot be empty")
+	}
+	res, err := s.q().Exec(`
+		UPDATE level_packs
+		SET name = ?
+		WHERE id = ?;
+	`, name, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("level pack %d not found", id)
+	}
+	log.Printf("event=\"renamed_level_pack\" id=%d name=\"%s\"", id, name)
+	return nil
+}
+
+// DeleteLevelPack deletes a level pack. Its levels and their saves are
+// deleted with it.
+func (s *Store) DeleteLevelPack(id int) error {
+	res, err := s.q().Exec(`
+		DELETE FROM level_packs
+		WHERE id = ?;
+	`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("level pack %d not found", id)
+	}
+	if err := s.deleteOrphanSaves(); err != nil {
+		return err
+	}
+	log.Printf("event=\"deleted_level_pack\" id=%d", id)
+	return nil
+}
+
+// deleteOrphanSaves deletes the saves of levels that are no longer
+// installed. Saves are shared by every copy of a level, so they go once the
+// last copy does, whether it was deleted or changed by a re-import.
+func (s *Store) deleteOrphanSaves() error {
+	res, err := s.q().Exec(`
+		DELETE FROM saves
+		WHERE level_identity NOT IN (SELECT identity FROM levels);
+	`)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n > 0 {
+		log.Printf("event=\"deleted_orphan_saves\" count=%d", n)
+	}
+	return nil
+}
+
+// CountLevelsByPack counts the levels in a level pack and how many of them
+// are solved.
+func (s *Store) CountLevelsByPack(levelPackID int) (int, int, error) {
+	row := s.q().QueryRow(`
+		SELECT COUNT(levels.id), COUNT(CASE WHEN saves.solved THEN 1 END)
+		FROM levels
+		LEFT JOIN saves ON saves.level_identity = levels.identity
+		WHERE levels.level_pack_id = ?;
+	`, levelPackID)
+	var total, solved int
+	if err := row.Scan(&total, &solved); err != nil {
+		return 0, 0, err
+	}
+	return total, solved, nil
+}
+
+// UpsertPackAsset inserts or replaces a file that ships with a level pack.
+func (s *Store) UpsertPackAsset(levelPackID int, name string, data []byte) error {
+	_, err := s.q().Exec(`
+		INSERT INTO pack_assets (level_pack_id, name, data)
+		VALUES (?, ?, ?)
+		ON CONFLICT(level_pack_id, name) DO UPDATE SET
+			data = excluded.data;
+	`, levelPackID, name, data)
+	return err
+}
+
+// GetPackAssets retrieves the files that ship with a level pack, by name.
+func (s *Store) GetPackAssets(levelPackID int) (map[string][]byte, error) {
+	rows, err := s.q().Query(`
+		SELECT name, data
+		FROM pack_assets
+		WHERE level_pack_id = ?;
+	`, levelPackID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assets := make(map[string][]byte)
+	for rows.Next() {
+		var name string
+		var data []byte
+		if err := rows.Scan(&name, &data); err != nil {
+			return nil, err
+		}
+		assets[name] = data
+	}
+	return assets, rows.Err()
+}
+
+// UpsertLevel inserts or updates a level, working out its identity.
 func (s *Store) UpsertLevel(level *Level, levelPackID int) error {
-	_, err := s.db.Exec(`
-		INSERT INTO levels (level_pack_id, name, author, initial_state, solution, engine)
-		VALUES (?, ?, ?, ?, ?, ?)
+	level.Identity = level.LevelIdentity()
+	_, err := s.q().Exec(`
+		INSERT INTO levels (level_pack_id, name, author, initial_state, solution, engine, uuid, identity, options, cells, regions, edges)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(level_pack_id, name) DO UPDATE SET
 			author = excluded.author,
 			initial_state = excluded.initial_state,
 			solution = excluded.solution,
-			engine = excluded.engine;
-	`, levelPackID, level.Name, level.Author, level.Initial, level.Solution, level.Engine)
+			engine = excluded.engine,
+			uuid = excluded.uuid,
+			identity = excluded.identity,
+			options = excluded.options,
+			cells = excluded.cells,
+			regions = excluded.regions,
+			edges = excluded.edges;
+	`, levelPackID, level.Name, level.Author, level.Initial, level.Solution, level.Engine, level.UUID, level.Identity, level.Options, level.Cells, level.Regions, level.Edges)
 	return err
 }
 
+// renameLevelByIdentity renames the level in a pack that has the given
+// identity, so a level renamed upstream updates in place on import instead
+// of being added again. Nothing happens if the name is already taken.
+func (s *Store) renameLevelByIdentity(levelPackID int, identity, name string) error {
+	_, err := s.q().Exec(`
+		UPDATE levels
+		SET name = ?
+		WHERE id = (
+			SELECT id FROM levels
+			WHERE level_pack_id = ? AND identity = ? AND name != ?
+			LIMIT 1
+		)
+		AND NOT EXISTS (SELECT 1 FROM levels WHERE level_pack_id = ? AND name = ?);
+	`, name, levelPackID, identity, name, levelPackID, name)
+	return err
+}
+
+// LevelDuplicate is a level that is also in another pack.
+type LevelDuplicate struct {
+	Level      string
+	OtherPack  string
+	OtherLevel string
+}
+
+// DuplicateLevels finds the levels in a pack that are also in other packs,
+// going by identity.
+func (s *Store) DuplicateLevels(levelPackID int) ([]LevelDuplicate, error) {
+	rows, err := s.q().Query(`
+		SELECT levels.name, level_packs.name, others.name
+		FROM levels
+		JOIN levels AS others ON others.identity = levels.identity AND others.level_pack_id != levels.level_pack_id
+		JOIN level_packs ON level_packs.id = others.level_pack_id
+		WHERE levels.level_pack_id = ?
+		ORDER BY levels.name, level_packs.name;
+	`, levelPackID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var duplicates []LevelDuplicate
+	for rows.Next() {
+		var d LevelDuplicate
+		if err := rows.Scan(&d.Level, &d.OtherPack, &d.OtherLevel); err != nil {
+			return nil, err
+		}
+		duplicates = append(duplicates, d)
+	}
+	return duplicates, rows.Err()
+}
+
 // GetLevel retrieves a level by its ID.
 func (s *Store) GetLevel(id int) (*Level, error) {
 	log.Printf("event=\"get_level\" id=%d", id)
-	row := s.db.QueryRow(`
-		SELECT id, name, author, initial_state, solution, engine
+	row := s.q().QueryRow(`
+		SELECT id, name, author, initial_state, solution, engine, uuid, identity, options, cells, regions, edges
 		FROM levels
 		WHERE id = ?;
 	`, id)
 	level := &Level{}
-	err := row.Scan(&level.ID, &level.Name, &level.Author, &level.Initial, &level.Solution, &level.Engine)
+	err := row.Scan(&level.ID, &level.Name, &level.Author, &level.Initial, &level.Solution, &level.Engine, &level.UUID, &level.Identity, &level.Options, &level.Cells, &level.Regions, &level.Edges)
 	if err != nil {
 		return nil, err
 	}
@@ -177,8 +607,8 @@ func (s *Store) GetLevel(id int) (*Level, error) {
 // GetLevelsByPack retrieves all levels for a given level pack.
 func (s *Store) GetLevelsByPack(levelPackID int) ([]Level, error) {
 	log.Printf("event=\"get_levels_by_pack\" level_pack_id=%d", levelPackID)
-	rows, err := s.db.Query(`
-		SELECT id, name, author, initial_state, solution, engine
+	rows, err := s.q().Query(`
+		SELECT id, name, author, initial_state, solution, engine, uuid, identity, options, cells, regions, edges
 		FROM levels
 		WHERE level_pack_id = ?;
 	`, levelPackID)
@@ -190,7 +620,7 @@ func (s *Store) GetLevelsByPack(levelPackID int) ([]Level, error) {
 	var levels []Level
 	for rows.Next() {
 		level := Level{}
-		err := rows.Scan(&level.ID, &level.Name, &level.Author, &level.Initial, &level.Solution, &level.Engine)
+		err := rows.Scan(&level.ID, &level.Name, &level.Author, &level.Initial, &level.Solution, &level.Engine, &level.UUID, &level.Identity, &level.Options, &level.Cells, &level.Regions, &level.Edges)
 		if err != nil {
 			return nil, err
 		}
@@ -210,27 +640,30 @@ func (s *Store) UpsertSave(save *Save) error {
 		log.Printf("event=\"delete_save_on_upsert\" level_id=%d", save.LevelID)
 		return s.DeleteSave(save.LevelID)
 	}
-	_, err = s.db.Exec(`
-		INSERT INTO saves (level_id, state, solved, updated_at)
+	save.LevelIdentity = level.Identity
+	_, err = s.q().Exec(`
+		INSERT INTO saves (level_identity, state, solved, updated_at)
 		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
-		ON CONFLICT(level_id) DO UPDATE SET
+		ON CONFLICT(level_identity) DO UPDATE SET
 			state = excluded.state,
 			solved = excluded.solved,
 			updated_at = CURRENT_TIMESTAMP;
-	`, save.LevelID, save.State, save.Solved)
+	`, save.LevelIdentity, save.State, save.Solved)
 	return err
 }
 
-// GetSave retrieves a save by its level ID.
+// GetSave retrieves the save for a level by the level's ID. Saves are kept
+// by level identity, so every copy of a level shares one.
 func (s *Store) GetSave(levelID int) (*Save, error) {
 	log.Printf("event=\"get_save\" level_id=%d", levelID)
-	row := s.db.QueryRow(`
-		SELECT level_id, state, solved, created_at, updated_at
+	row := s.q().QueryRow(`
+		SELECT levels.id, saves.level_identity, saves.state, saves.solved, saves.created_at, saves.updated_at
 		FROM saves
-		WHERE level_id = ?;
+		JOIN levels ON levels.identity = saves.level_identity
+		WHERE levels.id = ?;
 	`, levelID)
 	save := &Save{}
-	err := row.Scan(&save.LevelID, &save.State, &save.Solved, &save.CreatedAt, &save.UpdatedAt)
+	err := row.Scan(&save.LevelID, &save.LevelIdentity, &save.State, &save.Solved, &save.CreatedAt, &save.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -238,11 +671,11 @@ func (s *Store) GetSave(levelID int) (*Save, error) {
 	return save, nil
 }
 
-// DeleteSave deletes a save by its level ID.
+// DeleteSave deletes the save for a level by the level's ID.
 func (s *Store) DeleteSave(levelID int) error {
-	_, err := s.db.Exec(`
+	_, err := s.q().Exec(`
 		DELETE FROM saves
-		WHERE level_id = ?;
+		WHERE level_identity = (SELECT identity FROM levels WHERE id = ?);
 	`, levelID)
 	return err
 }
@@ -250,8 +683,8 @@ func (s *Store) DeleteSave(levelID int) error {
 // GetAllLevels is added to satisfy the model.go dependency.
 func (s *Store) GetAllLevels() ([]Level, error) {
 	log.Println("event=\"get_all_levels\"")
-	rows, err := s.db.Query(`
-		SELECT id, name, author, initial_state, solution, engine
+	rows, err := s.q().Query(`
+		SELECT id, name, author, initial_state, solution, engine, uuid, identity, options, cells, regions, edges
 		FROM levels;
 	`)
 	if err != nil {
@@ -262,7 +695,7 @@ func (s *Store) GetAllLevels() ([]Level, error) {
 	var levels []Level
 	for rows.Next() {
 		level := Level{}
-		err := rows.Scan(&level.ID, &level.Name, &level.Author, &level.Initial, &level.Solution, &level.Engine)
+		err := rows.Scan(&level.ID, &level.Name, &level.Author, &level.Initial, &level.Solution, &level.Engine, &level.UUID, &level.Identity, &level.Options, &level.Cells, &level.Regions, &level.Edges)
 		if err != nil {
 			return nil, err
 		}
@@ -275,7 +708,7 @@ func (s *Store) GetAllLevels() ([]Level, error) {
 // CountLevels counts the total number of levels.
 func (s *Store) CountLevels() (int, error) {
 	log.Println("event=\"count_levels\"")
-	row := s.db.QueryRow(`
+	row := s.q().QueryRow(`
 		SELECT COUNT(*)
 		FROM levels;
 	`)
@@ -299,12 +732,16 @@ func (s *Store) GetSaveIndicators(levelIDs []int) (map[int]string, error) {
 		return indicators, nil
 	}
 
-	query, args, err := sqlIn("SELECT level_id, solved FROM saves WHERE level_id IN (?)", levelIDs)
+	query, args, err := sqlIn(`
+		SELECT levels.id, saves.solved
+		FROM saves
+		JOIN levels ON levels.identity = saves.level_identity
+		WHERE levels.id IN (?)`, levelIDs)
 	if err != nil {
 		return nil, err
 	}
 
-	rows, err := s.db.Query(query, args...)
+	rows, err := s.q().Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -326,13 +763,14 @@ func (s *Store) GetSaveIndicators(levelIDs []int) (map[int]string, error) {
 	return indicators, nil
 }
 
-// CountSolvedLevels counts the number of solved levels.
+// CountSolvedLevels counts the number of solved levels. A level in several
+// packs counts once for each, as it does in CountLevelsByPack.
 func (s *Store) CountSolvedLevels() (int, error) {
 	log.Println("event=\"count_solved_levels\"")
-	row := s.db.QueryRow(`
-		SELECT COUNT(*)
-		FROM saves
-		WHERE solved = 1;
+	row := s.q().QueryRow(`
+		SELECT COUNT(CASE WHEN saves.solved THEN 1 END)
+		FROM levels
+		LEFT JOIN saves ON saves.level_identity = levels.identity;
 	`)
 	var count int
 	err := row.Scan(&count)
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"database/sql"
 	"log"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
 	"testing"
 )
 
@@ -26,8 +29,8 @@ func TestGetSaveIndicators(t *testing.T) {
 	// Insert levels
 	levels := []Level{
 		{Name: "Level 1", Author: "Tester", Initial: "00", Solution: "11", Engine: "test"},
-		{Name: "Level 2", Author: "Tester", Initial: "00", Solution: "11", Engine: "test"},
-		{Name: "Level 3", Author: "Tester", Initial: "00", Solution: "11", Engine: "test"},
+		{Name: "Level 2", Author: "Tester", Initial: "00", Solution: "10", Engine: "test"},
+		{Name: "Level 3", Author: "Tester", Initial: "00", Solution: "01", Engine: "test"},
 	}
 	var levelIDs []int
 	for i, level := range levels {
@@ -86,3 +89,308 @@ func (s *Store) GetLevelByName(name string, levelPackID int) (*Level, error) {
 	}
 	return level, nil
 }
+
+// newTestStore creates a store backed by a fresh database in a temp dir.
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := NewStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	t.Cleanup(func() { store.db.Close() })
+	return store
+}
+
+func TestDeleteLevelPackCascades(t *testing.T) {
+	store := newTestStore(t)
+
+	keep := &LevelPack{Name: "Keep", Author: "Tester"}
+	drop := &LevelPack{Name: "Drop", Author: "Tester"}
+	for _, pack := range []*LevelPack{keep, drop} {
+		if err := store.UpsertLevelPack(pack); err != nil {
+			t.Fatalf("failed to insert level pack: %v", err)
+		}
+		level := &Level{Name: "Level", Author: "Tester", Initial: "00", Solution: "11", Engine: "test"}
+		if err := store.UpsertLevel(level, pack.ID); err != nil {
+			t.Fatalf("failed to insert level: %v", err)
+		}
+		full, err := store.GetLevelByName("Level", pack.ID)
+		if err != nil {
+			t.Fatalf("failed to retrieve level: %v", err)
+		}
+		if err := store.UpsertSave(&Save{LevelID: full.ID, State: "11", Solved: true}); err != nil {
+			t.Fatalf("failed to insert save: %v", err)
+		}
+	}
+
+	if err := store.DeleteLevelPack(drop.ID); err != nil {
+		t.Fatalf("failed to delete level pack: %v", err)
+	}
+
+	packs, err := store.GetAllLevelPacks()
+	if err != nil {
+		t.Fatalf("failed to get level packs: %v", err)
+	}
+	if len(packs) != 1 || packs[0].Name != "Keep" {
+		t.Errorf("expected only the kept pack, got %v", packs)
+	}
+	if n, _ := store.CountLevels(); n != 1 {
+		t.Errorf("expected 1 level left, got %d", n)
+	}
+	if n, _ := store.CountSolvedLevels(); n != 1 {
+		t.Errorf("expected 1 save left, got %d", n)
+	}
+
+	if err := store.DeleteLevelPack(drop.ID); err == nil {
+		t.Errorf("expected an error deleting a missing pack")
+	}
+}
+
+func TestForeignKeysEnforced(t *testing.T) {
+	store := newTestStore(t)
+
+	level := &Level{Name: "Orphan", Initial: "00", Solution: "11", Engine: "test"}
+	if err := store.UpsertLevel(level, 42); err == nil {
+		t.Errorf("expected inserting a level into a missing pack to fail")
+	}
+}
+
+func TestRenameAndFindLevelPack(t *testing.T) {
+	store := newTestStore(t)
+
+	pack := &LevelPack{Name: "Old Name", Author: "Tester"}
+	if err := store.UpsertLevelPack(pack); err != nil {
+		t.Fatalf("failed to insert level pack: %v", err)
+	}
+	other := &LevelPack{Name: "Other", Author: "Tester"}
+	if err := store.UpsertLevelPack(other); err != nil {
+		t.Fatalf("failed to insert level pack: %v", err)
+	}
+
+	if err := store.RenameLevelPack(pack.ID, "New Name"); err != nil {
+		t.Fatalf("failed to rename level pack: %v", err)
+	}
+	if err := store.RenameLevelPack(pack.ID, "Other"); err == nil {
+		t.Errorf("expected renaming onto an existing name to fail")
+	}
+	if err := store.RenameLevelPack(pack.ID, " "); err == nil {
+		t.Errorf("expected renaming to a blank name to fail")
+	}
+
+	found, err := store.FindLevelPack("New Name")
+	if err != nil {
+		t.Fatalf("failed to find level pack by name: %v", err)
+	}
+	if found.ID != pack.ID {
+		t.Errorf("expected pack %d, got %d", pack.ID, found.ID)
+	}
+	found, err = store.FindLevelPack(strconv.Itoa(other.ID))
+	if err != nil {
+		t.Fatalf("failed to find level pack by id: %v", err)
+	}
+	if found.Name != "Other" {
+		t.Errorf("expected pack Other, got %q", found.Name)
+	}
+	if _, err := store.FindLevelPack("Old Name"); err == nil {
+		t.Errorf("expected the old name to be gone")
+	}
+}
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	// A database created before schema versioning, with an orphaned save.
+	if _, err := db.Exec(migrations[0]); err != nil {
+		t.Fatalf("failed to create legacy schema: %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO level_packs (id, name) VALUES (1, 'Pack');
+		INSERT INTO levels (id, level_pack_id, name, initial_state, solution, engine) VALUES (1, 1, 'Level', '00', '11', 'test');
+		INSERT INTO saves (level_id, state, solved) VALUES (1, '11', 1);
+		INSERT INTO saves (level_id, state, solved) VALUES (99, '11', 1);
+	`); err != nil {
+		t.Fatalf("failed to seed legacy database: %v", err)
+	}
+	db.Close()
+
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("failed to migrate legacy database: %v", err)
+	}
+	defer store.db.Close()
+
+	var version int
+	if err := store.db.QueryRow("PRAGMA user_version;").Scan(&version); err != nil {
+		t.Fatalf("failed to read schema version: %v", err)
+	}
+	if version != len(migrations) {
+		t.Errorf("expected schema version %d, got %d", len(migrations), version)
+	}
+	if n, _ := store.CountSolvedLevels(); n != 1 {
+		t.Errorf("expected the orphaned save to be dropped, got %d saves", n)
+	}
+	if save, err := store.GetSave(1); err != nil || save.LevelIdentity == "" {
+		t.Errorf("expected the save to move to the level's identity, got %+v, %v", save, err)
+	}
+	if err := store.DeleteLevelPack(1); err != nil {
+		t.Fatalf("failed to delete migrated pack: %v", err)
+	}
+	if n, _ := store.CountLevels(); n != 0 {
+		t.Errorf("expected levels to cascade, got %d", n)
+	}
+}
+
+func TestLevelIdentity(t *testing.T) {
+	base := Level{Engine: "nonogram", Initial: "..\n..\n", Solution: "1.\n.1"}
+
+	tests := []struct {
+		name  string
+		level Level
+		same  bool
+	}{
+		{"spaces for dots", Level{Engine: "nonogram", Initial: "  \n  ", Solution: "1 \n 1"}, true},
+		{"renamed", Level{Name: "Other", Engine: "nonogram", Initial: "..\n..", Solution: "1.\n.1"}, true},
+		{"other solution", Level{Engine: "nonogram", Initial: "..\n..", Solution: "11\n.1"}, false},
+		{"other engine", Level{Engine: "debug", Initial: "..\n..", Solution: "1.\n.1"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.level.LevelIdentity() == base.LevelIdentity(); got != tt.same {
+			t.Errorf("%s: same identity %v, want %v", tt.name, got, tt.same)
+		}
+	}
+
+	explicit := Level{UUID: " 0F3E7A52-1C2B-4D5E-8F90-123456789ABC ", Engine: "nonogram", Solution: "1"}
+	if got := explicit.LevelIdentity(); got != "0f3e7a52-1c2b-4d5e-8f90-123456789abc" {
+		t.Errorf("expected the explicit uuid, got %s", got)
+	}
+}
+
+func TestSavesFollowLevelIdentity(t *testing.T) {
+	store := newTestStore(t)
+	pack := func(name, levelName string) *LevelPackYAML {
+		return &LevelPackYAML{Name: name, Levels: []Level{
+			{Name: levelName, Engine: "nonogram", Initial: "..", Solution: "1."},
+		}}
+	}
+
+	first, err := store.importPack(pack("First", "Old Name"))
+	if err != nil {
+		t.Fatalf("failed to import pack: %v", err)
+	}
+	level, err := store.GetLevelByName("Old Name", first.ID)
+	if err != nil {
+		t.Fatalf("failed to get level: %v", err)
+	}
+	if err := store.UpsertSave(&Save{LevelID: level.ID, State: "1 ", Solved: true}); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+
+	// Renaming the level upstream renames it in place and keeps its save.
+	if _, err := store.importPack(pack("First", "New Name")); err != nil {
+		t.Fatalf("failed to re-import pack: %v", err)
+	}
+	levels, err := store.GetLevelsByPack(first.ID)
+	if err != nil {
+		t.Fatalf("failed to get levels: %v", err)
+	}
+	if len(levels) != 1 || levels[0].Name != "New Name" || levels[0].ID != level.ID {
+		t.Fatalf("expected the level to be renamed in place, got %+v", levels)
+	}
+	if save, err := store.GetSave(level.ID); err != nil || !save.Solved {
+		t.Errorf("expected the save to survive the rename, got %+v, %v", save, err)
+	}
+
+	// The same level in another pack is detected and shares the save.
+	second, err := store.importPack(pack("Second", "Copy"))
+	if err != nil {
+		t.Fatalf("failed to import pack: %v", err)
+	}
+	duplicates, err := store.DuplicateLevels(second.ID)
+	if err != nil {
+		t.Fatalf("failed to find duplicates: %v", err)
+	}
+	want := []LevelDuplicate{{Level: "Copy", OtherPack: "First", OtherLevel: "New Name"}}
+	if !reflect.DeepEqual(duplicates, want) {
+		t.Errorf("expected duplicates %+v, got %+v", want, duplicates)
+	}
+	copyLevel, err := store.GetLevelByName("Copy", second.ID)
+	if err != nil {
+		t.Fatalf("failed to get level: %v", err)
+	}
+	if save, err := store.GetSave(copyLevel.ID); err != nil || !save.Solved {
+		t.Errorf("expected the copy to share the save, got %+v, %v", save, err)
+	}
+	if n, _ := store.CountSolvedLevels(); n != 2 {
+		t.Errorf("expected both copies to count as solved, got %d", n)
+	}
+
+	// The save outlives one copy of the level, but not both.
+	if err := store.DeleteLevelPack(first.ID); err != nil {
+		t.Fatalf("failed to delete pack: %v", err)
+	}
+	if n, _ := store.CountSolvedLevels(); n != 1 {
+		t.Errorf("expected the shared save to remain, got %d", n)
+	}
+	if err := store.DeleteLevelPack(second.ID); err != nil {
+		t.Fatalf("failed to delete pack: %v", err)
+	}
+	if n, _ := store.CountSolvedLevels(); n != 0 {
+		t.Errorf("expected the save to go with the last copy, got %d", n)
+	}
+}
+
+func TestReimportDeletesOrphanSaves(t *testing.T) {
+	store := newTestStore(t)
+	pack := func(name, solution string) *LevelPackYAML {
+		return &LevelPackYAML{Name: name, Levels: []Level{
+			{Name: "One", Engine: "nonogram", Initial: "..", Solution: solution},
+		}}
+	}
+	countSaves := func() int {
+		var n int
+		if err := store.q().QueryRow(`SELECT COUNT(*) FROM saves;`).Scan(&n); err != nil {
+			t.Fatalf("failed to count saves: %v", err)
+		}
+		return n
+	}
+
+	first, err := store.importPack(pack("First", "1."))
+	if err != nil {
+		t.Fatalf("failed to import pack: %v", err)
+	}
+	if _, err := store.importPack(pack("Second", "1.")); err != nil {
+		t.Fatalf("failed to import pack: %v", err)
+	}
+	level, err := store.GetLevelByName("One", first.ID)
+	if err != nil {
+		t.Fatalf("failed to get level: %v", err)
+	}
+	if err := store.UpsertSave(&Save{LevelID: level.ID, State: "1 ", Solved: true}); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	if n, _ := store.CountSolvedLevels(); n != 2 {
+		t.Errorf("expected the level in both packs to count as solved, got %d", n)
+	}
+
+	// The copy in the other pack still has the old grids, so the save stays.
+	if _, err := store.importPack(pack("First", ".1")); err != nil {
+		t.Fatalf("failed to re-import pack: %v", err)
+	}
+	if n := countSaves(); n != 1 {
+		t.Errorf("expected the save shared with the other pack to stay, got %d saves", n)
+	}
+	if n, _ := store.CountSolvedLevels(); n != 1 {
+		t.Errorf("expected only the level with the old grids to count as solved, got %d", n)
+	}
+
+	// Once no level has the old grids, the save goes.
+	if _, err := store.importPack(pack("Second", ".1")); err != nil {
+		t.Fatalf("failed to re-import pack: %v", err)
+	}
+	if n := countSaves(); n != 0 {
+		t.Errorf("expected the save for the old grids to be deleted, got %d saves", n)
+	}
+}
@@ -1,22 +1,135 @@
 package main
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
 
 var (
-	titleStyle      = lipgloss.NewStyle().Background(lipgloss.Color("130")).Padding(0, 1)
-	subtleStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
-	focusedStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
-	tableTitleStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("145"))
-	blurredStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
+	titleStyle      lipgloss.Style
+	bannerStyle     lipgloss.Style
+	subtleStyle     lipgloss.Style
+	focusedStyle    lipgloss.Style
+	tableTitleStyle lipgloss.Style
+	blurredStyle    lipgloss.Style
+
+	cellStyle    lipgloss.Style
+	cursorStyle  lipgloss.Style
+	givenStyle   lipgloss.Style
+	filledStyle  lipgloss.Style
+	invalidStyle lipgloss.Style
+
+	// Nonogram styles.
+	renderStyles   map[rune]lipgloss.Style
+	highlightStyle lipgloss.Style
+	hintStyle      lipgloss.Style
+	hintDoneStyle  lipgloss.Style
+	hintErrorStyle lipgloss.Style
+	crosshairStyle lipgloss.Style
+
+	// Minesweeper styles.
+	mineStyles      map[rune]lipgloss.Style
+	mineNumberStyle lipgloss.Style
+
+	// Lights Out styles.
+	lightStyles map[rune]lipgloss.Style
+
+	// Region styles, one background colour per region in turn.
+	regionStyles []lipgloss.Style
+
+	// Digit puzzle styles.
+	digitStyle        lipgloss.Style
+	digitGivenStyle   lipgloss.Style
+	digitInvalidStyle lipgloss.Style
+
+	// Editor styles.
+	editorGivenStyle lipgloss.Style
+)
+
+func init() {
+	applyTheme(builtinThemes[defaultThemeName])
+}
+
+// applyTheme rebuilds every style from a theme. When the terminal can't show
+// colour at all, styles fall back to text attributes so the cursor, errors
+// and highlights stay visible.
+func applyTheme(t Theme) {
+	mono := lipgloss.ColorProfile() == termenv.Ascii
+
+	titleStyle = lipgloss.NewStyle().Background(t.Title.Color()).Foreground(t.TitleText.Color()).Padding(0, 1)
+	bannerStyle = lipgloss.NewStyle().Margin(1).Foreground(t.Title.Color())
+	subtleStyle = lipgloss.NewStyle().Foreground(t.Subtle.Color())
+	focusedStyle = lipgloss.NewStyle().Foreground(t.Accent.Color())
+	tableTitleStyle = lipgloss.NewStyle().Foreground(t.Heading.Color())
+	blurredStyle = lipgloss.NewStyle().Foreground(t.Text.Color())
 
 	cellStyle = lipgloss.NewStyle().
-			Border(lipgloss.NormalBorder(), true).
-			Padding(0, 1)
+		Border(lipgloss.NormalBorder(), true).
+		Padding(0, 1)
 	cursorStyle = cellStyle.
-			Border(lipgloss.ThickBorder(), true)
+		Border(lipgloss.ThickBorder(), true)
 	givenStyle = cellStyle.
-			BorderForeground(lipgloss.Color("242")) // Gray
-	filledStyle  = cellStyle
+		BorderForeground(t.Given.Color())
+	filledStyle = cellStyle
 	invalidStyle = cellStyle.
-			BorderForeground(lipgloss.Color("196")) // Red
-)
+		BorderForeground(t.Error.Color())
+
+	renderStyles = map[rune]lipgloss.Style{
+		FilledTile:     lipgloss.NewStyle().Background(t.Filled.Color()).Foreground(t.Filled.Color()),
+		KnownEmptyTile: lipgloss.NewStyle().Foreground(t.KnownEmpty.Color()),
+		EmptyTile:      lipgloss.NewStyle().Foreground(t.Empty.Color()),
+	}
+	highlightStyle = lipgloss.NewStyle().Background(t.Accent.Color()).Foreground(t.AccentText.Color())
+	hintStyle = lipgloss.NewStyle()
+	hintDoneStyle = lipgloss.NewStyle().Foreground(t.Done.Color())
+	hintErrorStyle = lipgloss.NewStyle().Foreground(t.Error.Color())
+	crosshairStyle = lipgloss.NewStyle().Background(t.Crosshair.Color())
+
+	mineStyles = map[rune]lipgloss.Style{
+		HiddenTile:   lipgloss.NewStyle().Foreground(t.Empty.Color()),
+		FlagTile:     lipgloss.NewStyle().Foreground(t.Accent.Color()).Bold(true),
+		MineTile:     lipgloss.NewStyle().Foreground(t.Error.Color()),
+		ExplodedTile: lipgloss.NewStyle().Foreground(t.Error.Color()).Bold(true),
+	}
+	mineNumberStyle = lipgloss.NewStyle().Foreground(t.Text.Color())
+
+	lightStyles = map[rune]lipgloss.Style{
+		LightOnTile:  lipgloss.NewStyle().Foreground(t.Filled.Color()).Bold(true),
+		LightOffTile: lipgloss.NewStyle().Foreground(t.Empty.Color()),
+	}
+
+	regionStyles = nil
+	for _, c := range t.Regions {
+		regionStyles = append(regionStyles, lipgloss.NewStyle().Background(c.Color()))
+	}
+
+	digitStyle = lipgloss.NewStyle().Foreground(t.Text.Color())
+	digitGivenStyle = lipgloss.NewStyle().Foreground(t.Given.Color()).Bold(true)
+	digitInvalidStyle = lipgloss.NewStyle().Foreground(t.Error.Color()).Bold(true)
+
+	editorGivenStyle = lipgloss.NewStyle().Foreground(t.Accent.Color()).Bold(true)
+
+	if mono {
+		titleStyle = titleStyle.Reverse(true)
+		focusedStyle = focusedStyle.Bold(true)
+		subtleStyle = subtleStyle.Faint(true)
+		invalidStyle = invalidStyle.Border(lipgloss.DoubleBorder(), true)
+		highlightStyle = highlightStyle.Reverse(true)
+		hintDoneStyle = hintDoneStyle.Faint(true)
+		hintErrorStyle = hintErrorStyle.Bold(true).Underline(true)
+		crosshairStyle = crosshairStyle.Underline(true)
+		mineStyles[ExplodedTile] = mineStyles[ExplodedTile].Reverse(true)
+		lightStyles[LightOffTile] = lightStyles[LightOffTile].Faint(true)
+		digitInvalidStyle = digitInvalidStyle.Underline(true)
+		editorGivenStyle = editorGivenStyle.Underline(true)
+	}
+}
+
+// regionStyle returns the background of a region, reusing the theme's
+// colours in turn when there are more regions than colours.
+func regionStyle(i int) lipgloss.Style {
+	if len(regionStyles) == 0 {
+		return lipgloss.NewStyle()
+	}
+	return regionStyles[i%len(regionStyles)]
+}
@@ -0,0 +1,485 @@
+// This file implements the Sudoku game logic, with its jigsaw and killer
+// variants.
+//
+// The solution is the filled grid: N rows of N digits from 1 to N, where N is
+// at most 9. Every row, column and region holds each digit once. Regions are
+// the usual boxes unless the level maps them out in its regions, one
+// character per cell, which makes a jigsaw sudoku:
+//
+//	regions: |-
+//	  aabb
+//	  abbb
+//	  accd
+//	  ccdd
+//
+// A level's cells add killer cages. Cells with the same name are in the same
+// cage, one of them carries the cage's sum after a backslash, and "." is a
+// cell outside every cage. A cage's digits add up to its sum without
+// repeating.
+//
+// All of these are checked by sudokuRules, whatever the layout of the regions.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var sudokuGlyphs = map[string]GlyphSet{
+	unicodeGlyphs: {EmptyTile: "·"},
+	asciiGlyphs:   {EmptyTile: "."},
+	emojiGlyphs:   {EmptyTile: "·"},
+}
+
+// sudokuCellWidth is the width of a cell between its borders.
+const sudokuCellWidth = 3
+
+// sudokuCage is a killer cage: a group of cells and the sum of their digits.
+type sudokuCage struct {
+	name   string
+	target int
+	cells  [][2]int
+}
+
+// sudokuRules are the constraints a sudoku grid is checked against: its
+// rows, columns and regions, and any killer cages.
+type sudokuRules struct {
+	size   int
+	region [][]int
+	units  [][][2]int
+	cages  []sudokuCage
+	cageAt [][]int
+}
+
+// sudokuBoxSize returns the width and height of the boxes of a classic
+// sudoku of the given size.
+func sudokuBoxSize(n int) (int, int, bool) {
+	switch n {
+	case 1:
+		return 1, 1, true
+	case 4:
+		return 2, 2, true
+	case 6:
+		return 3, 2, true
+	case 8:
+		return 4, 2, true
+	case 9:
+		return 3, 3, true
+	}
+	return 0, 0, false
+}
+
+// sudokuRulesFor reads the rules of a level from the size of its solution,
+// its regions and its cages.
+func sudokuRulesFor(l Level) (*sudokuRules, error) {
+	rows := strings.Split(strings.Trim(l.Solution, "\n"), "\n")
+	n := len(rows)
+	if n > 9 {
+		return nil, errors.New("a sudoku can't be bigger than 9x9")
+	}
+	for y, row := range rows {
+		if len(row) != n {
+			return nil, fmt.Errorf("solution row %d has %d cells, want %d for a square grid", y+1, len(row), n)
+		}
+	}
+
+	r := &sudokuRules{size: n}
+	if strings.TrimSpace(l.Regions) != "" {
+		region, count, err := parseRegionMap(l.Regions, n, n)
+		if err != nil {
+			return nil, err
+		}
+		if count != n {
+			return nil, fmt.Errorf("regions name %d regions, want %d", count, n)
+		}
+		r.region = region
+	} else {
+		bw, bh, ok := sudokuBoxSize(n)
+		if !ok {
+			return nil, fmt.Errorf("a %dx%d sudoku has no standard boxes, so it needs regions", n, n)
+		}
+		r.region = make([][]int, n)
+		for y := range r.region {
+			r.region[y] = make([]int, n)
+			for x := range r.region[y] {
+				r.region[y][x] = y/bh*(n/bw) + x/bw
+			}
+		}
+	}
+
+	regions := make([][][2]int, n)
+	for y := 0; y < n; y++ {
+		var row, col [][2]int
+		for x := 0; x < n; x++ {
+			row = append(row, [2]int{x, y})
+			col = append(col, [2]int{y, x})
+			regions[r.region[y][x]] = append(regions[r.region[y][x]], [2]int{x, y})
+		}
+		r.units = append(r.units, row, col)
+	}
+	for _, cells := range regions {
+		first := cells[0]
+		if len(cells) != n {
+			return nil, fmt.Errorf("region at row %d column %d has %d cells, want %d", first[1]+1, first[0]+1, len(cells), n)
+		}
+		if !connectedCells(cells) {
+			return nil, fmt.Errorf("region at row %d column %d isn't joined up", first[1]+1, first[0]+1)
+		}
+		r.units = append(r.units, cells)
+	}
+
+	if strings.TrimSpace(l.Cells) != "" {
+		var err error
+		if r.cages, r.cageAt, err = sudokuCages(l.Cells, n); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// sudokuCages reads killer cages from a level's cells.
+func sudokuCages(cells string, n int) ([]sudokuCage, [][]int, error) {
+	grid, err := parseCellGrid(cells, n, n)
+	if err != nil {
+		return nil, nil, err
+	}
+	var cages []sudokuCage
+	ids := make(map[string]int)
+	cageAt := make([][]int, n)
+	for y, row := range grid {
+		cageAt[y] = make([]int, n)
+		for x, values := range row {
+			cageAt[y][x] = -1
+			name := values[0]
+			switch {
+			case len(values) > 2:
+				return nil, nil, fmt.Errorf("cell at row %d column %d should be cage\\sum, got %q", y+1, x+1, strings.Join(values, cellSeparator))
+			case name == ".":
+				if len(values) > 1 {
+					return nil, nil, fmt.Errorf("cell at row %d column %d has a sum but no cage", y+1, x+1)
+				}
+				continue
+			}
+			id, ok := ids[name]
+			if !ok {
+				id = len(cages)
+				ids[name] = id
+				cages = append(cages, sudokuCage{name: name})
+			}
+			cageAt[y][x] = id
+			cages[id].cells = append(cages[id].cells, [2]int{x, y})
+			if len(values) < 2 {
+				continue
+			}
+			if cages[id].target != 0 {
+				return nil, nil, fmt.Errorf("cage %s has more than one sum", name)
+			}
+			sum, err := strconv.Atoi(values[1])
+			if err != nil || sum < 1 || sum > 45 {
+				return nil, nil, fmt.Errorf("cage %s has an invalid sum %q", name, values[1])
+			}
+			cages[id].target = sum
+		}
+	}
+	for _, c := range cages {
+		switch {
+		case c.target == 0:
+			return nil, nil, fmt.Errorf("cage %s has no sum", c.name)
+		case len(c.cells) > n:
+			return nil, nil, fmt.Errorf("cage %s has more cells than there are digits", c.name)
+		case !connectedCells(c.cells):
+			return nil, nil, fmt.Errorf("cage %s isn't joined up", c.name)
+		}
+	}
+	return cages, cageAt, nil
+}
+
+// digit reports whether v is one of the digits of the grid.
+func (r *sudokuRules) digit(v rune) bool {
+	return v >= '1' && v < '1'+rune(r.size)
+}
+
+// check is the constraint checker every sudoku layout shares. It returns the
+// cells whose digit repeats in their row, column, region or cage, and how
+// each cage stands against its sum.
+func (r *sudokuRules) check(values [][]rune) ([][]bool, []lineStatus) {
+	conflicts := make([][]bool, r.size)
+	for y := range conflicts {
+		conflicts[y] = make([]bool, r.size)
+	}
+	mark := func(cells [][2]int) {
+		seen := make(map[rune][][2]int)
+		for _, c := range cells {
+			if v := values[c[1]][c[0]]; r.digit(v) {
+				seen[v] = append(seen[v], c)
+			}
+		}
+		for _, same := range seen {
+			if len(same) > 1 {
+				for _, c := range same {
+					conflicts[c[1]][c[0]] = true
+				}
+			}
+		}
+	}
+	for _, u := range r.units {
+		mark(u)
+	}
+	status := make([]lineStatus, len(r.cages))
+	for i, c := range r.cages {
+		mark(c.cells)
+		cage := make([]rune, len(c.cells))
+		for j, p := range c.cells {
+			cage[j] = values[p[1]][p[0]]
+		}
+		status[i] = kakuroRunStatus(cage, c.target)
+	}
+	return conflicts, status
+}
+
+// solved reports whether a grid is full and breaks no rule.
+func (r *sudokuRules) solved(values [][]rune) bool {
+	conflicts, cages := r.check(values)
+	for y, row := range values {
+		for x, v := range row {
+			if !r.digit(v) || conflicts[y][x] {
+				return false
+			}
+		}
+	}
+	for _, s := range cages {
+		if s != lineSatisfied {
+			return false
+		}
+	}
+	return true
+}
+
+// edge returns the border between two neighbouring cells. Cells off the
+// grid count as another region.
+func (r *sudokuRules) edge(x1, y1, x2, y2 int) gridEdge {
+	in := func(x, y int) bool { return x >= 0 && x < r.size && y >= 0 && y < r.size }
+	switch {
+	case !in(x1, y1) || !in(x2, y2) || r.region[y1][x1] != r.region[y2][x2]:
+		return edgeRegion
+	case r.cageAt != nil && r.cageAt[y1][x1] != r.cageAt[y2][x2]:
+		return edgeCage
+	}
+	return edgeCell
+}
+
+// validateSudoku refuses levels whose regions or cages are invalid, whose
+// solution breaks the rules or whose givens don't match the solution.
+func validateSudoku(l Level) (string, error) {
+	r, err := sudokuRulesFor(l)
+	if err != nil {
+		return "", err
+	}
+	solution := strings.Split(strings.Trim(l.Solution, "\n"), "\n")
+	values := make([][]rune, r.size)
+	for y, row := range solution {
+		values[y] = []rune(row)
+	}
+	if !r.solved(values) {
+		return "", errors.New("solution breaks the rules")
+	}
+	for y, row := range strings.Split(strings.Trim(l.Initial, "\n"), "\n") {
+		for x, v := range row {
+			if v != '.' && v != ' ' && (y >= r.size || x >= r.size || v != values[y][x]) {
+				return "", fmt.Errorf("given at row %d column %d doesn't match the solution", y+1, x+1)
+			}
+		}
+	}
+	return "", nil
+}
+
+type SudokuEngine struct {
+	Engine
+	rules      *sudokuRules
+	cageStatus []lineStatus
+}
+
+func (e *SudokuEngine) New(l Level, s *Save) (GameEngine, error) {
+	rules, err := sudokuRulesFor(l)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := e.Engine.New(l, s); err != nil {
+		return nil, err
+	}
+	if e.GetHeight() != rules.size || e.GetWidth() != rules.size {
+		return nil, errors.New("initial state and solution are different sizes")
+	}
+	e.rules = rules
+	e.updateStatus()
+	return e, nil
+}
+
+// PrimaryAction steps the digit in a cell up, from blank through 1 to N.
+func (e *SudokuEngine) PrimaryAction(x, y int) error {
+	return stepDigit(e, x, y, 1, e.rules.size)
+}
+
+// SecondaryAction steps the digit in a cell down.
+func (e *SudokuEngine) SecondaryAction(x, y int) error {
+	return stepDigit(e, x, y, -1, e.rules.size)
+}
+
+// EnterValue puts a typed digit in a cell. Typing 0 clears it.
+func (e *SudokuEngine) EnterValue(x, y int, v rune) error {
+	switch {
+	case v == '0':
+		return e.ClearCell(x, y)
+	case !e.rules.digit(v):
+		return fmt.Errorf("%q is not a digit from 1 to %d", v, e.rules.size)
+	}
+	if err := e.setCellValue(x, y, v); err != nil {
+		return err
+	}
+	e.updateStatus()
+	return nil
+}
+
+func (e *SudokuEngine) ClearCell(x, y int) error {
+	if err := e.Engine.ClearCell(x, y); err != nil {
+		return err
+	}
+	e.updateStatus()
+	return nil
+}
+
+// Evaluate reports whether the grid is full and breaks no rule.
+func (e *SudokuEngine) Evaluate() (bool, error) {
+	return e.rules.solved(e.values()), nil
+}
+
+func (e *SudokuEngine) View(m model) string {
+	return lipgloss.JoinVertical(lipgloss.Left, e.gridView(m), e.helpView(m))
+}
+
+// CellAt maps a position within the rendered view to grid coordinates.
+// Borders aren't cells.
+func (e *SudokuEngine) CellAt(col, row int) (int, int, bool) {
+	return e.borderedGrid(nil, nil).cellAt(col, row)
+}
+
+// GlyphSets returns the glyph empty cells are drawn with. Digits are drawn
+// as they are.
+func (e *SudokuEngine) GlyphSets() map[string]GlyphSet {
+	return sudokuGlyphs
+}
+
+// --- Private Functions ---
+
+// updateStatus flags repeated digits, checks the cages and refreshes the
+// solved flag on the save.
+func (e *SudokuEngine) updateStatus() {
+	values := e.values()
+	conflicts, cages := e.rules.check(values)
+	for y, row := range e.Grid {
+		for x := range row {
+			e.Grid[y][x].RunValidation(!conflicts[y][x])
+		}
+	}
+	e.cageStatus = cages
+	e.Save.Solved = e.rules.solved(values)
+}
+
+// cursorCage returns the cage under the cursor, or -1.
+func (e *SudokuEngine) cursorCage(m model) int {
+	if e.rules.cageAt == nil || !e.HasCell(m.cursorX, m.cursorY) {
+		return -1
+	}
+	return e.rules.cageAt[m.cursorY][m.cursorX]
+}
+
+// gridView draws the grid with its borders: heavy lines around regions,
+// dotted lines around cages and light lines between the other cells. A
+// cage's sum sits in the border above its first cell.
+func (e *SudokuEngine) gridView(m model) string {
+	sums := make(map[[2]int]int)
+	for i, c := range e.rules.cages {
+		sums[c.cells[0]] = i
+	}
+	cursorCage := e.cursorCage(m)
+	return e.borderedGrid(
+		func(x, y int) string { return e.cellView(x, y, m) },
+		func(x, y int) string {
+			i, ok := sums[[2]int{x, y}]
+			if !ok {
+				return ""
+			}
+			style := hintLineStyle(e.cageStatus[i])
+			if i == cursorCage {
+				style = style.Bold(true)
+			}
+			return style.Render(strconv.Itoa(e.rules.cages[i].target))
+		},
+	).view()
+}
+
+// borderedGrid lays the grid out with its region and cage borders.
+func (e *SudokuEngine) borderedGrid(cell, label func(x, y int) string) borderedGrid {
+	return borderedGrid{
+		width:     e.rules.size,
+		height:    e.rules.size,
+		cellWidth: sudokuCellWidth,
+		edge:      e.rules.edge,
+		cell:      cell,
+		label:     label,
+	}
+}
+
+// cellView draws a digit cell, marking the row, column and region of the
+// cursor.
+func (e *SudokuEngine) cellView(x, y int, m model) string {
+	cell := e.Grid[y][x]
+	s := digitStyle
+	switch cell.state {
+	case given:
+		s = digitGivenStyle
+	case invalid:
+		s = digitInvalidStyle
+	}
+	if e.HasCell(m.cursorX, m.cursorY) && (x == m.cursorX || y == m.cursorY ||
+		e.rules.region[y][x] == e.rules.region[m.cursorY][m.cursorX]) {
+		s = s.Inherit(crosshairStyle)
+	}
+	if x == m.cursorX && y == m.cursorY {
+		s = highlightStyle
+	}
+	g := string(cell.value)
+	if !e.rules.digit(cell.value) {
+		g = glyphSetFor(sudokuGlyphs)[EmptyTile]
+	}
+	return s.Width(sudokuCellWidth).AlignHorizontal(lipgloss.Center).Render(g)
+}
+
+func (e *SudokuEngine) helpView(m model) string {
+	help := "\n"
+	if e.HasCell(m.cursorX, m.cursorY) && e.Grid[m.cursorY][m.cursorX].state != given {
+		help += fmt.Sprintf("1-%d: Enter digit\tz/x: Next/previous digit\tbackspace: clear\n", e.rules.size)
+	} else {
+		help += "\n"
+	}
+	if i := e.cursorCage(m); i >= 0 {
+		sum := 0
+		for _, c := range e.rules.cages[i].cells {
+			if v := e.Grid[c[1]][c[0]].value; e.rules.digit(v) {
+				sum += int(v - '0')
+			}
+		}
+		help += fmt.Sprintf("Cage: %d/%d\n", sum, e.rules.cages[i].target)
+	}
+	help += "arrow keys or hjkl to move\n"
+	help += "Press 'esc' to return to the menu.\n"
+	if e.Save.Solved {
+		help += "Congrats!\n"
+	}
+	return help
+}
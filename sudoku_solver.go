@@ -0,0 +1,126 @@
+package main
+
+import "strings"
+
+// sudokuSearchBudget caps how many digits countSudokuSolutions tries before
+// giving up on a level.
+const sudokuSearchBudget = 2000000
+
+// sudokuSolver fills in a sudoku by backtracking, always choosing the blank
+// cell with the fewest digits left. It works with any region layout.
+type sudokuSolver struct {
+	rules   *sudokuRules
+	values  [][]int
+	unitsAt [][][]int
+	budget  int
+}
+
+// countSudokuSolutions counts the ways to fill in a level's grid, up to
+// limit, keeping the digits its initial state gives.
+func countSudokuSolutions(l Level, limit int) (int, bool) {
+	rules, err := sudokuRulesFor(l)
+	if err != nil {
+		return 0, true
+	}
+	n := rules.size
+	s := &sudokuSolver{rules: rules, budget: sudokuSearchBudget}
+	s.values = make([][]int, n)
+	s.unitsAt = make([][][]int, n)
+	for y := range s.values {
+		s.values[y] = make([]int, n)
+		s.unitsAt[y] = make([][]int, n)
+	}
+	for i, u := range rules.units {
+		for _, c := range u {
+			s.unitsAt[c[1]][c[0]] = append(s.unitsAt[c[1]][c[0]], i)
+		}
+	}
+	for y, row := range strings.Split(strings.Trim(l.Initial, "\n"), "\n") {
+		for x, v := range row {
+			if y < n && x < n && rules.digit(v) {
+				s.values[y][x] = int(v - '0')
+			}
+		}
+	}
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			if d := s.values[y][x]; d != 0 && !s.fits(x, y, d) {
+				return 0, true
+			}
+		}
+	}
+	count := s.search(limit)
+	return count, s.budget > 0
+}
+
+// search counts the completions of the grid up to limit.
+func (s *sudokuSolver) search(limit int) int {
+	bestX, bestY := -1, -1
+	var best []int
+	for y, row := range s.values {
+		for x, v := range row {
+			if v != 0 {
+				continue
+			}
+			digits := s.candidates(x, y)
+			if len(digits) == 0 {
+				return 0
+			}
+			if bestX < 0 || len(digits) < len(best) {
+				bestX, bestY, best = x, y, digits
+			}
+		}
+	}
+	if bestX < 0 {
+		return 1
+	}
+
+	count := 0
+	for _, d := range best {
+		if s.budget--; s.budget <= 0 {
+			break
+		}
+		s.values[bestY][bestX] = d
+		count += s.search(limit - count)
+		if count >= limit {
+			break
+		}
+	}
+	s.values[bestY][bestX] = 0
+	return count
+}
+
+// candidates returns the digits that fit a blank cell.
+func (s *sudokuSolver) candidates(x, y int) []int {
+	var digits []int
+	for d := 1; d <= s.rules.size; d++ {
+		if s.fits(x, y, d) {
+			digits = append(digits, d)
+		}
+	}
+	return digits
+}
+
+// fits reports whether digit d can go in a cell: it isn't elsewhere in the
+// cell's row, column or region, and its cage can still reach its sum.
+func (s *sudokuSolver) fits(x, y, d int) bool {
+	for _, i := range s.unitsAt[y][x] {
+		for _, c := range s.rules.units[i] {
+			if (c[0] != x || c[1] != y) && s.values[c[1]][c[0]] == d {
+				return false
+			}
+		}
+	}
+	if s.rules.cageAt == nil || s.rules.cageAt[y][x] < 0 {
+		return true
+	}
+	cage := s.rules.cages[s.rules.cageAt[y][x]]
+	values := make([]int, len(cage.cells))
+	for i, c := range cage.cells {
+		values[i] = s.values[c[1]][c[0]]
+		if c[0] == x && c[1] == y {
+			values[i] = d
+		}
+	}
+	return sumFits(values, cage.target, s.rules.size)
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	testSudokuSolution  = "1234\n3412\n2143\n4321"
+	testJigsawRegions   = "aaab\ncabb\nccdb\ncddd"
+	testJigsawSolution  = "1234\n2413\n3142\n4321"
+	testKillerCells     = "a\\3 a b\\7 b\nc\\7 c d\\3 d\ne\\3 e f\\7 f\ng\\7 g h\\3 h"
+	testSudokuBlankGrid = "....\n....\n....\n...."
+
+	// testUniqueKillerCells has cages that settle the grid without givens.
+	testUniqueKillerCells = "a\\6 a b\\3 c\\4\na d\\5 d e\\9\nf\\9 g\\1 e e\nf f h\\3 h"
+)
+
+func TestSudokuRulesErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		solution string
+		regions  string
+		cells    string
+		want     string
+	}{
+		{name: "not square", solution: "123\n231", want: "square"},
+		{name: "no standard boxes", solution: "12345\n23451\n34512\n45123\n51234", want: "needs regions"},
+		{name: "too few regions", solution: testSudokuSolution, regions: "aabb\naabb\naabb\naabb", want: "want 4"},
+		{name: "uneven regions", solution: testSudokuSolution, regions: "aaaa\nabbb\nccdd\nccdd", want: "has 5 cells"},
+		{name: "split region", solution: testSudokuSolution, regions: "abab\nabab\ncdcd\ncdcd", want: "joined up"},
+		{name: "cage without a sum", solution: testSudokuSolution, cells: "a a . .\n. . . .\n. . . .\n. . . .", want: "no sum"},
+		{name: "cage with two sums", solution: testSudokuSolution, cells: "a\\3 a\\3 . .\n. . . .\n. . . .\n. . . .", want: "more than one sum"},
+		{name: "sum outside a cage", solution: testSudokuSolution, cells: ".\\3 . . .\n. . . .\n. . . .\n. . . .", want: "no cage"},
+		{name: "split cage", solution: testSudokuSolution, cells: "a\\4 . a .\n. . . .\n. . . .\n. . . .", want: "joined up"},
+		{name: "oversized cage", solution: testSudokuSolution, cells: "a\\15 a a a\na . . .\n. . . .\n. . . .", want: "more cells"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := sudokuRulesFor(Level{Solution: tc.solution, Regions: tc.regions, Cells: tc.cells})
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected an error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestSudokuCheck(t *testing.T) {
+	testCases := []struct {
+		name    string
+		regions string
+		grid    string
+		want    string
+	}{
+		{name: "row", grid: "1..1\n....\n....\n....", want: "1001\n0000\n0000\n0000"},
+		{name: "column", grid: "..2.\n....\n....\n..2.", want: "0010\n0000\n0000\n0010"},
+		{name: "box", grid: "3...\n.3..\n....\n....", want: "1000\n0100\n0000\n0000"},
+		{name: "jigsaw region", regions: testJigsawRegions, grid: "..4.\n.4..\n....\n....", want: "0010\n0100\n0000\n0000"},
+		{name: "not a jigsaw region", regions: testJigsawRegions, grid: ".4..\n4...\n....\n....", want: "0000\n0000\n0000\n0000"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := sudokuRulesFor(Level{Solution: testSudokuSolution, Regions: tc.regions})
+			if err != nil {
+				t.Fatalf("invalid rules: %v", err)
+			}
+			var values [][]rune
+			for _, row := range strings.Split(tc.grid, "\n") {
+				values = append(values, []rune(row))
+			}
+			conflicts, _ := r.check(values)
+			var got []string
+			for _, row := range conflicts {
+				var b strings.Builder
+				for _, c := range row {
+					if c {
+						b.WriteByte('1')
+					} else {
+						b.WriteByte('0')
+					}
+				}
+				got = append(got, b.String())
+			}
+			if strings.Join(got, "\n") != tc.want {
+				t.Errorf("expected conflicts\n%s\ngot\n%s", tc.want, strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestSudokuPlay(t *testing.T) {
+	e := newTestEngine(t, Level{Engine: "sudoku", Initial: "12..\n34..\n21..\n43..", Solution: testSudokuSolution}).(*SudokuEngine)
+	fill := map[[2]int]rune{{2, 0}: '3', {3, 0}: '4', {2, 1}: '1', {3, 1}: '2', {2, 2}: '4', {3, 2}: '3', {2, 3}: '2'}
+	for c, v := range fill {
+		if err := e.EnterValue(c[0], c[1], v); err != nil {
+			t.Fatalf("failed to enter %c: %v", v, err)
+		}
+	}
+	if e.Save.Solved {
+		t.Fatal("expected an unfinished grid to be unsolved")
+	}
+
+	// Stepping down from blank wraps round to the biggest digit.
+	e.SecondaryAction(3, 3)
+	if v := e.Grid[3][3].value; v != '4' {
+		t.Errorf("expected stepping down from blank to enter 4, got %q", v)
+	}
+	if e.Grid[3][3].state != invalid {
+		t.Error("expected a repeated 4 to be invalid")
+	}
+	e.PrimaryAction(3, 3)
+	e.PrimaryAction(3, 3)
+	if v := e.Grid[3][3].value; v != '1' || !e.Save.Solved {
+		t.Errorf("expected stepping up through blank to 1 to solve the grid, got %q", e.Save.State)
+	}
+	if err := e.EnterValue(3, 3, '5'); err == nil {
+		t.Error("expected a digit bigger than the grid to be refused")
+	}
+	if err := e.EnterValue(0, 0, '2'); err != nil || e.Grid[0][0].value != '1' {
+		t.Errorf("expected a given to stay as it is, got %q", e.Grid[0][0].value)
+	}
+}
+
+func TestSudokuKiller(t *testing.T) {
+	e := newTestEngine(t, Level{Engine: "sudoku", Initial: testSudokuBlankGrid, Solution: testSudokuSolution, Cells: testKillerCells}).(*SudokuEngine)
+	if len(e.rules.cages) != 8 {
+		t.Fatalf("expected 8 cages, got %d", len(e.rules.cages))
+	}
+
+	// Going over a cage's sum breaks it before it is full.
+	e.EnterValue(0, 0, '4')
+	if e.cageStatus[0] != lineBroken {
+		t.Errorf("expected a digit over the sum to break the cage, got %d", e.cageStatus[0])
+	}
+	e.EnterValue(0, 0, '1')
+	if e.cageStatus[0] != lineOpen {
+		t.Errorf("expected the cage to be open, got %d", e.cageStatus[0])
+	}
+	e.EnterValue(1, 0, '2')
+	if e.cageStatus[0] != lineSatisfied {
+		t.Errorf("expected the cage to be satisfied, got %d", e.cageStatus[0])
+	}
+
+	for y, row := range strings.Split(testSudokuSolution, "\n") {
+		for x, v := range row {
+			e.EnterValue(x, y, v)
+		}
+	}
+	if !e.Save.Solved {
+		t.Errorf("expected the grid to be solved, got %q", e.Save.State)
+	}
+}
+
+func TestCountSudokuSolutions(t *testing.T) {
+	testCases := []struct {
+		name    string
+		initial string
+		regions string
+		cells   string
+		want    int
+	}{
+		{name: "unique", initial: "1..4\n....\n....\n.32.", want: 1},
+		{name: "blank", initial: testSudokuBlankGrid, want: 2},
+		{name: "clashing givens", initial: "11..\n....\n....\n....", want: 0},
+		{name: "jigsaw", initial: "12..\n...3\n....\n....", regions: testJigsawRegions, want: 1},
+		{name: "jigsaw without enough givens", initial: "1...\n.4..\n..4.\n...1", regions: testJigsawRegions, want: 2},
+		{name: "killer", initial: testSudokuBlankGrid, cells: testUniqueKillerCells, want: 1},
+		{name: "killer without enough cages", initial: testSudokuBlankGrid, cells: testKillerCells, want: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			solution := testSudokuSolution
+			if tc.regions != "" {
+				solution = testJigsawSolution
+			}
+			l := Level{Initial: tc.initial, Solution: solution, Regions: tc.regions, Cells: tc.cells}
+			got, ok := countSudokuSolutions(l, 2)
+			if !ok || got != tc.want {
+				t.Errorf("expected %d solutions, got %d (ok %v)", tc.want, got, ok)
+			}
+		})
+	}
+}
+
+func TestSudokuBorders(t *testing.T) {
+	// The grid is a border line, then a line of cells, for each row.
+	e := newTestEngine(t, Level{Engine: "sudoku", Initial: testSudokuBlankGrid, Solution: testSudokuSolution, Cells: testKillerCells}).(*SudokuEngine)
+	lines := strings.Split(e.gridView(model{}), "\n")
+	if len(lines) != 9 {
+		t.Fatalf("expected 9 lines, got %d", len(lines))
+	}
+	if x, y, ok := e.CellAt(6, 3); !ok || x != 1 || y != 1 {
+		t.Errorf("expected column 6 row 3 to be cell 1,1, got %d,%d %v", x, y, ok)
+	}
+	if _, _, ok := e.CellAt(4, 3); ok {
+		t.Error("expected a border not to be a cell")
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// saveExportVersion is the version of the save export format.
+const saveExportVersion = 1
+
+// SaveExport is the file progress is moved between machines in. Saves are
+// keyed by level identity rather than database ids, which differ from one
+// library to the next. Pack and level names are kept for exports made
+// before levels had identities, and for the preview.
+type SaveExport struct {
+	Version    int          `json:"version"`
+	ExportedAt time.Time    `json:"exported_at"`
+	Saves      []SyncedSave `json:"saves"`
+}
+
+// SyncedSave is the progress on one level in a save export.
+type SyncedSave struct {
+	Identity  string    `json:"identity,omitempty"`
+	Pack      string    `json:"pack"`
+	Level     string    `json:"level"`
+	Engine    string    `json:"engine"`
+	State     string    `json:"state"`
+	Solved    bool      `json:"solved"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// The ways importing a save can change the local one.
+const (
+	syncAdd    = "add"
+	syncUpdate = "update"
+	syncKeep   = "keep"
+	syncSkip   = "skip"
+)
+
+// SaveChange is what importing one save will do, worked out before anything
+// is written so it can be previewed.
+type SaveChange struct {
+	Action   string
+	Reason   string
+	LevelID  int
+	Identity string
+	Save     SyncedSave
+}
+
+// ExportSaves collects the progress on every level in the library.
+func (s *Store) ExportSaves() (*SaveExport, error) {
+	rows, err := s.q().Query(`
+		SELECT saves.level_identity, level_packs.name, levels.name, levels.engine, saves.state, saves.solved, saves.updated_at
+		FROM saves
+		JOIN levels ON levels.id = (SELECT MIN(id) FROM levels WHERE identity = saves.level_identity)
+		JOIN level_packs ON level_packs.id = levels.level_pack_id
+		ORDER BY level_packs.name, levels.name;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	export := &SaveExport{Version: saveExportVersion, ExportedAt: time.Now().UTC()}
+	for rows.Next() {
+		var save SyncedSave
+		if err := rows.Scan(&save.Identity, &save.Pack, &save.Level, &save.Engine, &save.State, &save.Solved, &save.UpdatedAt); err != nil {
+			return nil, err
+		}
+		export.Saves = append(export.Saves, save)
+	}
+	return export, rows.Err()
+}
+
+// DecodeSaveExport parses a save export file.
+func DecodeSaveExport(data []byte) (*SaveExport, error) {
+	var export SaveExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, err
+	}
+	if export.Version != saveExportVersion {
+		return nil, fmt.Errorf("unsupported save export version %d", export.Version)
+	}
+	return &export, nil
+}
+
+// findLevelID looks a level up by its pack and level name.
+func (s *Store) findLevelID(pack, level string) (int, error) {
+	var id int
+	err := s.q().QueryRow(`
+		SELECT levels.id
+		FROM levels
+		JOIN level_packs ON level_packs.id = levels.level_pack_id
+		WHERE level_packs.name = ? AND levels.name = ?;
+	`, pack, level).Scan(&id)
+	return id, err
+}
+
+// findSyncedLevel looks up the level a synced save belongs to by identity.
+// Only saves exported before levels had identities are looked up by pack
+// and level name, as a level with another identity under the same names has
+// different grids.
+func (s *Store) findSyncedLevel(save SyncedSave) (int, error) {
+	if save.Identity == "" {
+		return s.findLevelID(save.Pack, save.Level)
+	}
+	var id int
+	err := s.q().QueryRow(`
+		SELECT MIN(id)
+		FROM levels
+		WHERE identity = ?
+		HAVING COUNT(*) > 0;
+	`, save.Identity).Scan(&id)
+	return id, err
+}
+
+// mergeSave decides how an imported save combines with the local one. A
+// solved save always beats an unsolved one, so solving a level on either
+// machine is never lost. Otherwise the most recently updated save wins.
+func mergeSave(local *Save, incoming SyncedSave) (string, string) {
+	switch {
+	case local == nil:
+		return syncAdd, "no local save"
+	case local.Solved && !incoming.Solved:
+		return syncKeep, "solved locally"
+	case incoming.Solved && !local.Solved:
+		return syncUpdate, "solved on the other machine"
+	case local.State == incoming.State:
+		return syncKeep, "already up to date"
+	case incoming.UpdatedAt.After(local.UpdatedAt):
+		return syncUpdate, "newer"
+	}
+	return syncKeep, "local save is newer"
+}
+
+// PlanSaveImport works out what importing a save export would change,
+// without changing anything.
+func (s *Store) PlanSaveImport(export *SaveExport) ([]SaveChange, error) {
+	var changes []SaveChange
+	for _, incoming := range export.Saves {
+		change := SaveChange{Save: incoming}
+		levelID, err := s.findSyncedLevel(incoming)
+		if errors.Is(err, sql.ErrNoRows) {
+			change.Action, change.Reason = syncSkip, "level not installed"
+			changes = append(changes, change)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		level, err := s.GetLevel(levelID)
+		if err != nil {
+			return nil, err
+		}
+		change.LevelID, change.Identity = levelID, level.Identity
+		if incoming.Engine != level.Engine || !stateFits(*level, incoming.State) {
+			change.Action, change.Reason = syncSkip, "save doesn't fit the level"
+			changes = append(changes, change)
+			continue
+		}
+
+		local, err := s.GetSave(levelID)
+		if errors.Is(err, sql.ErrNoRows) {
+			local = nil
+		} else if err != nil {
+			return nil, err
+		}
+		change.Action, change.Reason = mergeSave(local, incoming)
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// ApplySaveImport writes the saves a plan adds or updates, keeping their
+// original update times, in a single transaction.
+func (s *Store) ApplySaveImport(changes []SaveChange) error {
+	return s.InTx(func(tx *Store) error {
+		for _, change := range changes {
+			if change.Action != syncAdd && change.Action != syncUpdate {
+				continue
+			}
+			_, err := tx.q().Exec(`
+				INSERT INTO saves (level_identity, state, solved, updated_at)
+				VALUES (?, ?, ?, ?)
+				ON CONFLICT(level_identity) DO UPDATE SET
+					state = excluded.state,
+					solved = excluded.solved,
+					updated_at = excluded.updated_at;
+			`, change.Identity, change.Save.State, change.Save.Solved, change.Save.UpdatedAt.UTC().Format(time.DateTime))
+			if err != nil {
+				return err
+			}
+			log.Printf("event=\"synced_save\" level_id=%d action=%s", change.LevelID, change.Action)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestMergeSave(t *testing.T) {
+	earlier := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	later := earlier.Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		local    *Save
+		incoming SyncedSave
+		want     string
+	}{
+		{"no local save", nil, SyncedSave{State: "1."}, syncAdd},
+		{"newer incoming", &Save{State: "1.", UpdatedAt: earlier}, SyncedSave{State: "11", UpdatedAt: later}, syncUpdate},
+		{"older incoming", &Save{State: "1.", UpdatedAt: later}, SyncedSave{State: "11", UpdatedAt: earlier}, syncKeep},
+		{"same state", &Save{State: "11", UpdatedAt: earlier}, SyncedSave{State: "11", UpdatedAt: later}, syncKeep},
+		{"solved locally", &Save{State: "11", Solved: true, UpdatedAt: earlier}, SyncedSave{State: "1.", UpdatedAt: later}, syncKeep},
+		{"solved elsewhere", &Save{State: "1.", UpdatedAt: later}, SyncedSave{State: "11", Solved: true, UpdatedAt: earlier}, syncUpdate},
+		{"both solved, newer", &Save{State: "11", Solved: true, UpdatedAt: earlier}, SyncedSave{State: "1x", Solved: true, UpdatedAt: later}, syncUpdate},
+	}
+	for _, tt := range tests {
+		if got, _ := mergeSave(tt.local, tt.incoming); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+// newSyncStore returns a store holding Pack A, which has one level, and the
+// id of that level.
+func newSyncStore(t *testing.T) (*Store, int) {
+	t.Helper()
+	store := newTestStore(t)
+	pack, err := DecodeLevelPack([]byte(bundlePackA), formatYAML)
+	if err != nil {
+		t.Fatalf("failed to decode pack: %v", err)
+	}
+	if _, err := store.importPack(pack); err != nil {
+		t.Fatalf("failed to import pack: %v", err)
+	}
+	id, err := store.findLevelID("Pack A", "A1")
+	if err != nil {
+		t.Fatalf("failed to find level: %v", err)
+	}
+	return store, id
+}
+
+func TestSaveSyncRoundTrip(t *testing.T) {
+	src, srcID := newSyncStore(t)
+	if err := src.UpsertSave(&Save{LevelID: srcID, State: "1 \n 1", Solved: true}); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	export, err := src.ExportSaves()
+	if err != nil {
+		t.Fatalf("failed to export saves: %v", err)
+	}
+	data, err := json.Marshal(export)
+	if err != nil {
+		t.Fatalf("failed to marshal saves: %v", err)
+	}
+	export, err = DecodeSaveExport(data)
+	if err != nil {
+		t.Fatalf("failed to decode saves: %v", err)
+	}
+	export.Saves = append(export.Saves, SyncedSave{Pack: "Pack Z", Level: "Z1", State: "1"})
+	// A level whose grids changed under the same names is not the same level.
+	export.Saves = append(export.Saves, SyncedSave{Identity: "changed", Pack: "Pack A", Level: "A1", State: "1 \n 1"})
+	// Nor does a save made for another engine or another size of grid fit.
+	wrongEngine, wrongSize := export.Saves[0], export.Saves[0]
+	wrongEngine.Engine = "sudoku"
+	wrongSize.State = "1"
+	export.Saves = append(export.Saves, wrongEngine, wrongSize)
+
+	// The same level has a different id in the second library.
+	dst := newTestStore(t)
+	if err := dst.UpsertLevelPack(&LevelPack{Name: "Padding"}); err != nil {
+		t.Fatalf("failed to add pack: %v", err)
+	}
+	pack, _ := DecodeLevelPack([]byte(bundlePackA), formatYAML)
+	if _, err := dst.importPack(pack); err != nil {
+		t.Fatalf("failed to import pack: %v", err)
+	}
+	dstID, _ := dst.findLevelID("Pack A", "A1")
+	if err := dst.UpsertSave(&Save{LevelID: dstID, State: "1 \n  "}); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+
+	changes, err := dst.PlanSaveImport(export)
+	if err != nil {
+		t.Fatalf("failed to plan import: %v", err)
+	}
+	if len(changes) != 5 || changes[0].Action != syncUpdate {
+		t.Fatalf("unexpected plan %+v", changes)
+	}
+	for _, change := range changes[1:] {
+		if change.Action != syncSkip {
+			t.Errorf("expected %+v to be skipped", change)
+		}
+	}
+	if changes[3].Reason != "save doesn't fit the level" || changes[4].Reason != changes[3].Reason {
+		t.Errorf("expected saves that don't fit to say so, got %q and %q", changes[3].Reason, changes[4].Reason)
+	}
+	if save, _ := dst.GetSave(dstID); save.Solved {
+		t.Fatal("planning an import must not change saves")
+	}
+
+	if err := dst.ApplySaveImport(changes); err != nil {
+		t.Fatalf("failed to apply import: %v", err)
+	}
+	save, err := dst.GetSave(dstID)
+	if err != nil {
+		t.Fatalf("failed to get save: %v", err)
+	}
+	if !save.Solved || save.State != "1 \n 1" {
+		t.Errorf("expected the solved save to be imported, got %+v", save)
+	}
+	if !save.UpdatedAt.Equal(export.Saves[0].UpdatedAt.Truncate(time.Second)) {
+		t.Errorf("expected update time %v to be kept, got %v", export.Saves[0].UpdatedAt, save.UpdatedAt)
+	}
+
+	changes, err = dst.PlanSaveImport(export)
+	if err != nil {
+		t.Fatalf("failed to plan import: %v", err)
+	}
+	if changes[0].Action != syncKeep {
+		t.Errorf("expected a second import to change nothing, got %+v", changes[0])
+	}
+}
@@ -0,0 +1,368 @@
+// This file implements the Takuzu game logic, also known as Binairo.
+//
+// The grid has an even number of rows and columns, and every cell holds one
+// of two symbols, TakuzuOneTile or TakuzuZeroTile. No three cells in a row or
+// column hold the same symbol, every row and column holds as many of one as
+// of the other, and no two rows or two columns are the same. The initial
+// state gives some of the symbols.
+//
+// The puzzle is solved by the rules rather than by matching the solution,
+// so a level with more than one way to fill it in accepts any of them.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	TakuzuOneTile  = rune('1')
+	TakuzuZeroTile = rune('0')
+)
+
+var takuzuGlyphs = map[string]GlyphSet{
+	unicodeGlyphs: {
+		TakuzuOneTile:  "●",
+		TakuzuZeroTile: "○",
+		EmptyTile:      "·",
+	},
+	asciiGlyphs: {
+		TakuzuOneTile:  "1",
+		TakuzuZeroTile: "0",
+		EmptyTile:      ".",
+	},
+	emojiGlyphs: {
+		TakuzuOneTile:  "⚫",
+		TakuzuZeroTile: "⚪",
+		EmptyTile:      "·",
+	},
+}
+
+// takuzuLineGlyphs mark how each row and column stands at the edge of the
+// grid.
+var takuzuLineGlyphs = map[string]map[lineStatus]string{
+	unicodeGlyphs: {lineOpen: " ", lineSatisfied: "✓", lineBroken: "✗"},
+	asciiGlyphs:   {lineOpen: " ", lineSatisfied: "=", lineBroken: "!"},
+	emojiGlyphs:   {lineOpen: " ", lineSatisfied: "✓", lineBroken: "✗"},
+}
+
+// takuzuLines returns the cells of every row and then every column of a
+// grid.
+func takuzuLines(w, h int) [][][2]int {
+	lines := make([][][2]int, h+w)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			lines[y] = append(lines[y], [2]int{x, y})
+			lines[h+x] = append(lines[h+x], [2]int{x, y})
+		}
+	}
+	return lines
+}
+
+// takuzuCheck checks a grid against the rules. It returns the cells that
+// break one, by being three of a kind or by being more than half of their
+// line, and how each row and then each column stands. A full line that
+// repeats another is broken too.
+func takuzuCheck(values [][]rune) ([][]bool, []lineStatus) {
+	h, w := len(values), len(values[0])
+	broken := make([][]bool, h)
+	for y := range broken {
+		broken[y] = make([]bool, w)
+	}
+	lines := takuzuLines(w, h)
+	status := make([]lineStatus, len(lines))
+	full := make(map[string]int)
+	for i, line := range lines {
+		var b strings.Builder
+		counts := make(map[rune]int)
+		filled := true
+		for j, c := range line {
+			v := values[c[1]][c[0]]
+			b.WriteRune(v)
+			if v != TakuzuOneTile && v != TakuzuZeroTile {
+				filled = false
+				continue
+			}
+			counts[v]++
+			if j >= 2 && values[line[j-1][1]][line[j-1][0]] == v && values[line[j-2][1]][line[j-2][0]] == v {
+				for _, p := range line[j-2 : j+1] {
+					broken[p[1]][p[0]] = true
+				}
+				status[i] = lineBroken
+			}
+		}
+		for v, n := range counts {
+			if n > len(line)/2 {
+				for _, c := range line {
+					if values[c[1]][c[0]] == v {
+						broken[c[1]][c[0]] = true
+					}
+				}
+				status[i] = lineBroken
+			}
+		}
+		if !filled {
+			continue
+		}
+
+		// Rows are only compared with rows and columns with columns.
+		key := fmt.Sprintf("%t:%s", i < h, b.String())
+		if j, ok := full[key]; ok {
+			status[i], status[j] = lineBroken, lineBroken
+		} else {
+			full[key] = i
+		}
+		if status[i] == lineOpen {
+			status[i] = lineSatisfied
+		}
+	}
+	return broken, status
+}
+
+// takuzuSolved reports whether a grid is full and breaks no rule.
+func takuzuSolved(values [][]rune) bool {
+	_, status := takuzuCheck(values)
+	for _, s := range status {
+		if s != lineSatisfied {
+			return false
+		}
+	}
+	return true
+}
+
+// takuzuGrid reads a grid of symbols, one line per row.
+func takuzuGrid(s string) [][]rune {
+	var values [][]rune
+	for _, row := range strings.Split(strings.Trim(s, "\n"), "\n") {
+		values = append(values, []rune(row))
+	}
+	return values
+}
+
+// validateTakuzu refuses levels with an odd number of rows or columns, whose
+// solution breaks the rules or whose givens don't match it.
+func validateTakuzu(l Level) (string, error) {
+	solution := takuzuGrid(l.Solution)
+	h, w := len(solution), len(solution[0])
+	if h%2 != 0 || w%2 != 0 {
+		return "", fmt.Errorf("a %dx%d grid can't be split evenly, want an even number of rows and columns", w, h)
+	}
+	for y, row := range solution {
+		if len(row) != w {
+			return "", fmt.Errorf("solution row %d has %d cells, want %d", y+1, len(row), w)
+		}
+	}
+	if !takuzuSolved(solution) {
+		return "", errors.New("solution breaks the rules")
+	}
+	for y, row := range takuzuGrid(l.Initial) {
+		for x, v := range row {
+			if (v == TakuzuOneTile || v == TakuzuZeroTile) && (y >= h || x >= w || v != solution[y][x]) {
+				return "", fmt.Errorf("given at row %d column %d doesn't match the solution", y+1, x+1)
+			}
+		}
+	}
+	return "", nil
+}
+
+type TakuzuEngine struct {
+	Engine
+	lineStatus []lineStatus
+	viewport   gridViewport
+}
+
+func (e *TakuzuEngine) New(l Level, s *Save) (GameEngine, error) {
+	if _, err := e.Engine.New(l, s); err != nil {
+		return nil, err
+	}
+	if e.GetWidth()%2 != 0 || e.GetHeight()%2 != 0 {
+		return nil, errors.New("takuzu grids need an even number of rows and columns")
+	}
+	e.updateStatus()
+	e.viewport.fit(0, 0, e.cellWidth(), e.GetWidth(), e.GetHeight(), 0, 0)
+	return e, nil
+}
+
+// PrimaryAction puts a one in a cell.
+func (e *TakuzuEngine) PrimaryAction(x, y int) error {
+	return e.place(x, y, TakuzuOneTile)
+}
+
+// SecondaryAction puts a zero in a cell.
+func (e *TakuzuEngine) SecondaryAction(x, y int) error {
+	return e.place(x, y, TakuzuZeroTile)
+}
+
+// dragPaints lets a drag paint ones and zeros across the grid.
+func (e *TakuzuEngine) dragPaints() {}
+
+// EnterValue puts a typed symbol in a cell.
+func (e *TakuzuEngine) EnterValue(x, y int, v rune) error {
+	if v != TakuzuOneTile && v != TakuzuZeroTile {
+		return fmt.Errorf("%q is not %c or %c", v, TakuzuZeroTile, TakuzuOneTile)
+	}
+	return e.place(x, y, v)
+}
+
+func (e *TakuzuEngine) ClearCell(x, y int) error {
+	if err := e.Engine.ClearCell(x, y); err != nil {
+		return err
+	}
+	e.updateStatus()
+	return nil
+}
+
+// Evaluate reports whether the grid is full and breaks no rule, whether or
+// not it matches the level's solution.
+func (e *TakuzuEngine) Evaluate() (bool, error) {
+	return takuzuSolved(e.values()), nil
+}
+
+func (e *TakuzuEngine) View(m model) string {
+	h := e.helpView(m)
+	// The line marks take two columns after the rows and a line below.
+	e.viewport.fitTo(m, 2, lipgloss.Height(h)+1, e.cellWidth(), e.GetWidth(), e.GetHeight())
+	return lipgloss.JoinVertical(lipgloss.Left, e.gridView(m), h)
+}
+
+// CellAt maps a position within the rendered view to grid coordinates.
+func (e *TakuzuEngine) CellAt(col, row int) (int, int, bool) {
+	return e.viewport.cellAt(col, row, e.cellWidth())
+}
+
+// GlyphSets returns the glyphs the two symbols can be drawn with.
+func (e *TakuzuEngine) GlyphSets() map[string]GlyphSet {
+	return takuzuGlyphs
+}
+
+// --- Private Functions ---
+
+// place puts a symbol in a cell and checks the grid again.
+func (e *TakuzuEngine) place(x, y int, v rune) error {
+	if err := e.setCellValue(x, y, v); err != nil {
+		return err
+	}
+	e.updateStatus()
+	return nil
+}
+
+// updateStatus flags the cells that break a rule and refreshes the solved
+// flag on the save.
+func (e *TakuzuEngine) updateStatus() {
+	values := e.values()
+	broken, status := takuzuCheck(values)
+	for y, row := range e.Grid {
+		for x := range row {
+			e.Grid[y][x].RunValidation(!broken[y][x])
+		}
+	}
+	e.lineStatus = status
+	solved := true
+	for _, s := range status {
+		solved = solved && s == lineSatisfied
+	}
+	e.Save.Solved = solved
+}
+
+// cellWidth is wide enough for the glyphs with a space either side.
+func (e *TakuzuEngine) cellWidth() int {
+	return widestGlyph(glyphSetFor(takuzuGlyphs)) + 2
+}
+
+// lineMark draws how a row or column stands.
+func (e *TakuzuEngine) lineMark(i int, active bool) string {
+	marks, ok := takuzuLineGlyphs[activeGlyphSet]
+	if !ok {
+		marks = takuzuLineGlyphs[asciiGlyphs]
+	}
+	s := hintLineStyle(e.lineStatus[i])
+	if active {
+		s = s.Inherit(crosshairStyle)
+	}
+	return s.Render(marks[e.lineStatus[i]])
+}
+
+// gridView draws the symbols with a mark after each row and below each
+// column once it is full or broken.
+func (e *TakuzuEngine) gridView(m model) string {
+	glyphs := glyphSetFor(takuzuGlyphs)
+	w := e.cellWidth()
+	h := e.GetHeight()
+
+	v := e.viewport
+	var rows []string
+	for y := v.offsetY; y < v.offsetY+v.rows; y++ {
+		var cells []string
+		for x := v.offsetX; x < v.offsetX+v.cols; x++ {
+			cell := e.Grid[y][x]
+			s := digitStyle
+			switch cell.state {
+			case given:
+				s = digitGivenStyle
+			case invalid:
+				s = digitInvalidStyle
+			}
+			if x == m.cursorX || y == m.cursorY {
+				s = s.Inherit(crosshairStyle)
+			}
+			if x == m.cursorX && y == m.cursorY {
+				s = highlightStyle
+			}
+			g, ok := glyphs[cell.value]
+			if !ok {
+				g = glyphs[EmptyTile]
+			}
+			cells = append(cells, s.Width(w).AlignHorizontal(lipgloss.Center).Render(g))
+		}
+		cells = append(cells, " "+e.lineMark(y, y == m.cursorY))
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, cells...))
+	}
+
+	var marks []string
+	for x := v.offsetX; x < v.offsetX+v.cols; x++ {
+		marks = append(marks, lipgloss.NewStyle().Width(w).AlignHorizontal(lipgloss.Center).Render(e.lineMark(h+x, x == m.cursorX)))
+	}
+	rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, marks...))
+	return lipgloss.JoinVertical(lipgloss.Left, rows...)
+}
+
+func (e *TakuzuEngine) helpView(m model) string {
+	help := "\nz: One\tx: Zero\tbackspace: clear\n"
+	help += e.statusView(m)
+	help += "arrow keys or hjkl to move\n"
+	help += "Press 'esc' to return to the menu.\n"
+	if e.viewport.clipped(e.GetWidth(), e.GetHeight()) {
+		v := e.viewport
+		help += fmt.Sprintf("Showing columns %d-%d of %d, rows %d-%d of %d\n",
+			v.offsetX+1, v.offsetX+v.cols, e.GetWidth(),
+			v.offsetY+1, v.offsetY+v.rows, e.GetHeight())
+	}
+	if e.Save.Solved {
+		help += "Congrats!\n"
+	}
+	return help
+}
+
+// statusView counts the symbols in the row and column under the cursor.
+func (e *TakuzuEngine) statusView(m model) string {
+	glyphs := glyphSetFor(takuzuGlyphs)
+	count := func(cells [][2]int) string {
+		ones, zeros := 0, 0
+		for _, c := range cells {
+			switch e.Grid[c[1]][c[0]].value {
+			case TakuzuOneTile:
+				ones++
+			case TakuzuZeroTile:
+				zeros++
+			}
+		}
+		half := len(cells) / 2
+		return fmt.Sprintf("%s %d/%d  %s %d/%d", glyphs[TakuzuOneTile], ones, half, glyphs[TakuzuZeroTile], zeros, half)
+	}
+	lines := takuzuLines(e.GetWidth(), e.GetHeight())
+	return fmt.Sprintf("Row: %s\tColumn: %s\n", count(lines[m.cursorY]), count(lines[e.GetHeight()+m.cursorX]))
+}
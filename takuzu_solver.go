@@ -0,0 +1,127 @@
+package main
+
+// takuzuSearchBudget caps how many symbols countTakuzuSolutions tries before
+// giving up on a level.
+const takuzuSearchBudget = 2000000
+
+// takuzuSolver fills in a takuzu grid cell by cell in reading order, backing
+// off as soon as a row or column breaks a rule.
+type takuzuSolver struct {
+	values [][]rune
+	w, h   int
+	budget int
+}
+
+// countTakuzuSolutions counts the ways to fill in a level's grid, up to
+// limit, keeping the symbols its initial state gives.
+func countTakuzuSolutions(l Level, limit int) (int, bool) {
+	solution := takuzuGrid(l.Solution)
+	h, w := len(solution), len(solution[0])
+	if h%2 != 0 || w%2 != 0 {
+		return 0, true
+	}
+	s := &takuzuSolver{w: w, h: h, budget: takuzuSearchBudget}
+	s.values = make([][]rune, h)
+	for y := range s.values {
+		s.values[y] = make([]rune, w)
+		for x := range s.values[y] {
+			s.values[y][x] = EmptyTile
+		}
+	}
+	givens := make(map[[2]int]bool)
+	for y, row := range takuzuGrid(l.Initial) {
+		for x, v := range row {
+			if y < h && x < w && (v == TakuzuOneTile || v == TakuzuZeroTile) {
+				s.values[y][x] = v
+				givens[[2]int{x, y}] = true
+			}
+		}
+	}
+	if broken, _ := takuzuCheck(s.values); anyBroken(broken) {
+		return 0, true
+	}
+	count := s.search(0, givens, limit)
+	return count, s.budget > 0
+}
+
+// search counts the completions of the grid from cell i onwards, up to
+// limit.
+func (s *takuzuSolver) search(i int, givens map[[2]int]bool, limit int) int {
+	if i == s.w*s.h {
+		if takuzuSolved(s.values) {
+			return 1
+		}
+		return 0
+	}
+	x, y := i%s.w, i/s.w
+	if givens[[2]int{x, y}] {
+		if !s.fits(x, y) {
+			return 0
+		}
+		return s.search(i+1, givens, limit)
+	}
+
+	count := 0
+	for _, v := range []rune{TakuzuZeroTile, TakuzuOneTile} {
+		if s.budget--; s.budget <= 0 {
+			break
+		}
+		s.values[y][x] = v
+		if s.fits(x, y) {
+			count += s.search(i+1, givens, limit-count)
+		}
+		if count >= limit {
+			break
+		}
+	}
+	s.values[y][x] = EmptyTile
+	return count
+}
+
+// fits reports whether the symbol in a cell keeps its row and column within
+// the rules, given everything before it in reading order is filled in.
+func (s *takuzuSolver) fits(x, y int) bool {
+	v := s.values[y][x]
+	if x >= 2 && s.values[y][x-1] == v && s.values[y][x-2] == v {
+		return false
+	}
+	if y >= 2 && s.values[y-1][x] == v && s.values[y-2][x] == v {
+		return false
+	}
+	inRow, inCol := 0, 0
+	for i := 0; i <= x; i++ {
+		if s.values[y][i] == v {
+			inRow++
+		}
+	}
+	for i := 0; i <= y; i++ {
+		if s.values[i][x] == v {
+			inCol++
+		}
+	}
+	if inRow > s.w/2 || inCol > s.h/2 {
+		return false
+	}
+
+	// A finished row mustn't repeat one above it.
+	if x == s.w-1 {
+		for i := 0; i < y; i++ {
+			if string(s.values[i]) == string(s.values[y]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// anyBroken reports whether any cell is flagged.
+func anyBroken(broken [][]bool) bool {
+	for _, row := range broken {
+		for _, b := range row {
+			if b {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	testTakuzuSolution = "0011\n0101\n1010\n1100"
+	testTakuzuBlank    = "....\n....\n....\n...."
+
+	// testTakuzuOpenGivens leave the last two rows either way round.
+	testTakuzuOpenGivens = "00..\n0.0.\n....\n...."
+)
+
+func TestTakuzuCheck(t *testing.T) {
+	testCases := []struct {
+		name   string
+		grid   string
+		want   string
+		status []lineStatus
+	}{
+		{
+			name:   "three in a row",
+			grid:   "111.\n....\n....\n....",
+			want:   "1110\n0000\n0000\n0000",
+			status: []lineStatus{lineBroken, lineOpen, lineOpen, lineOpen, lineOpen, lineOpen, lineOpen, lineOpen},
+		},
+		{
+			name:   "three in a column",
+			grid:   "0...\n0...\n0...\n....",
+			want:   "1000\n1000\n1000\n0000",
+			status: []lineStatus{lineOpen, lineOpen, lineOpen, lineOpen, lineBroken, lineOpen, lineOpen, lineOpen},
+		},
+		{
+			name:   "too many of one",
+			grid:   "1101\n....\n....\n....",
+			want:   "1101\n0000\n0000\n0000",
+			status: []lineStatus{lineBroken, lineOpen, lineOpen, lineOpen, lineOpen, lineOpen, lineOpen, lineOpen},
+		},
+		{
+			name:   "repeated rows",
+			grid:   "0011\n0011\n....\n....",
+			want:   "0000\n0000\n0000\n0000",
+			status: []lineStatus{lineBroken, lineBroken, lineOpen, lineOpen, lineOpen, lineOpen, lineOpen, lineOpen},
+		},
+		{
+			name:   "solved",
+			grid:   testTakuzuSolution,
+			want:   "0000\n0000\n0000\n0000",
+			status: []lineStatus{lineSatisfied, lineSatisfied, lineSatisfied, lineSatisfied, lineSatisfied, lineSatisfied, lineSatisfied, lineSatisfied},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			broken, status := takuzuCheck(takuzuGrid(tc.grid))
+			var got []string
+			for _, row := range broken {
+				var b strings.Builder
+				for _, c := range row {
+					if c {
+						b.WriteByte('1')
+					} else {
+						b.WriteByte('0')
+					}
+				}
+				got = append(got, b.String())
+			}
+			if strings.Join(got, "\n") != tc.want {
+				t.Errorf("expected broken cells\n%s\ngot\n%s", tc.want, strings.Join(got, "\n"))
+			}
+			for i, s := range status {
+				if s != tc.status[i] {
+					t.Errorf("line %d: expected status %d, got %d", i, tc.status[i], s)
+				}
+			}
+		})
+	}
+}
+
+func TestTakuzuPlay(t *testing.T) {
+	e := newTestEngine(t, Level{Engine: "takuzu", Initial: testTakuzuOpenGivens, Solution: testTakuzuSolution}).(*TakuzuEngine)
+	if e.Grid[0][0].state != given {
+		t.Fatalf("expected a pre-placed symbol to be given, got %v", e.Grid[0][0].state)
+	}
+	if err := e.PrimaryAction(0, 0); err != nil || e.Grid[0][0].value != TakuzuZeroTile {
+		t.Errorf("expected a given to stay as it is, got %q", e.Grid[0][0].value)
+	}
+
+	// The last two rows are swapped from the level's solution, which is
+	// just as valid.
+	other := "0011\n0101\n1100\n1010"
+	for y, row := range strings.Split(other, "\n") {
+		for x, v := range row {
+			if e.Grid[y][x].state == given {
+				continue
+			}
+			var err error
+			if v == TakuzuOneTile {
+				err = e.PrimaryAction(x, y)
+			} else {
+				err = e.SecondaryAction(x, y)
+			}
+			if err != nil {
+				t.Fatalf("failed to place %c: %v", v, err)
+			}
+		}
+	}
+	if !e.Save.Solved {
+		t.Fatalf("expected another valid fill to solve the level, got %q", e.Save.State)
+	}
+	if solved, _ := e.Evaluate(); !solved {
+		t.Error("expected Evaluate to accept another valid fill")
+	}
+
+	e.EnterValue(3, 3, TakuzuOneTile)
+	if e.Save.Solved || e.Grid[3][3].state != invalid {
+		t.Error("expected a third 1 in the last row to be invalid")
+	}
+	if err := e.EnterValue(3, 3, '2'); err == nil {
+		t.Error("expected a symbol other than 0 or 1 to be refused")
+	}
+	e.ClearCell(3, 3)
+	if e.Save.Solved || e.Grid[2][3].state == invalid {
+		t.Error("expected clearing the cell to leave the level unsolved without conflicts")
+	}
+}
+
+func TestValidateTakuzu(t *testing.T) {
+	testCases := []struct {
+		name     string
+		initial  string
+		solution string
+		want     string
+	}{
+		{name: "valid", initial: testTakuzuOpenGivens, solution: testTakuzuSolution},
+		{name: "odd size", initial: "...\n...", solution: "010\n101", want: "evenly"},
+		{name: "ragged", initial: testTakuzuBlank, solution: "0011\n010\n1010\n1100", want: "has 3 cells"},
+		{name: "repeated rows", initial: testTakuzuBlank, solution: "0101\n1010\n0101\n1010", want: "breaks the rules"},
+		{name: "wrong given", initial: "1...\n....\n....\n....", solution: testTakuzuSolution, want: "doesn't match"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := validateTakuzu(Level{Initial: tc.initial, Solution: tc.solution})
+			if tc.want == "" {
+				if err != nil {
+					t.Errorf("expected the level to be valid, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected an error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestCountTakuzuSolutions(t *testing.T) {
+	testCases := []struct {
+		name    string
+		initial string
+		want    int
+	}{
+		{name: "unique", initial: "00..\n0...\n..1.\n....", want: 1},
+		{name: "two ways", initial: testTakuzuOpenGivens, want: 2},
+		{name: "blank", initial: testTakuzuBlank, want: 2},
+		{name: "clashing givens", initial: "000.\n....\n....\n....", want: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := countTakuzuSolutions(Level{Initial: tc.initial, Solution: testTakuzuSolution}, 2)
+			if !ok || got != tc.want {
+				t.Errorf("expected %d solutions, got %d (ok %v)", tc.want, got, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/charmbracelet/lipgloss"
+	"gopkg.in/yaml.v3"
+)
+
+// ThemeColor is a colour with explicit fallbacks for each terminal colour
+// profile. In YAML it is either a single colour, used for every profile, or
+// a mapping with truecolor, ansi256 and ansi keys.
+type ThemeColor struct {
+	TrueColor string `yaml:"truecolor"`
+	ANSI256   string `yaml:"ansi256"`
+	ANSI      string `yaml:"ansi"`
+}
+
+func (c *ThemeColor) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*c = ThemeColor{TrueColor: node.Value, ANSI256: node.Value, ANSI: node.Value}
+		return nil
+	}
+	type plain ThemeColor
+	return node.Decode((*plain)(c))
+}
+
+// IsZero reports whether no colour has been set.
+func (c ThemeColor) IsZero() bool {
+	return c.TrueColor == "" && c.ANSI256 == "" && c.ANSI == ""
+}
+
+// Color converts the colour to one lipgloss picks from for the terminal's
+// colour profile.
+func (c ThemeColor) Color() lipgloss.TerminalColor {
+	if c.IsZero() {
+		return lipgloss.NoColor{}
+	}
+	return lipgloss.CompleteColor{TrueColor: c.TrueColor, ANSI256: c.ANSI256, ANSI: c.ANSI}
+}
+
+// Theme is the set of colours every view draws from. User defined themes
+// start from a base theme and only need to set the colours they change.
+type Theme struct {
+	Name string `yaml:"name"`
+	Base string `yaml:"base"`
+
+	Title      ThemeColor `yaml:"title"`
+	TitleText  ThemeColor `yaml:"title_text"`
+	Text       ThemeColor `yaml:"text"`
+	Subtle     ThemeColor `yaml:"subtle"`
+	Heading    ThemeColor `yaml:"heading"`
+	Accent     ThemeColor `yaml:"accent"`
+	AccentText ThemeColor `yaml:"accent_text"`
+	Error      ThemeColor `yaml:"error"`
+	Given      ThemeColor `yaml:"given"`
+	Done       ThemeColor `yaml:"done"`
+	Crosshair  ThemeColor `yaml:"crosshair"`
+	Filled     ThemeColor `yaml:"filled"`
+	KnownEmpty ThemeColor `yaml:"known_empty"`
+	Empty      ThemeColor `yaml:"empty"`
+
+	// Regions are the background colours regions are told apart by, used
+	// in turn when a grid has more regions than colours.
+	Regions []ThemeColor `yaml:"regions"`
+}
+
+// tc builds a ThemeColor from true colour, 256 colour and 16 colour values.
+func tc(trueColor, ansi256, ansi string) ThemeColor {
+	return ThemeColor{TrueColor: trueColor, ANSI256: ansi256, ANSI: ansi}
+}
+
+// defaultThemeName is used when no theme has been configured.
+const defaultThemeName = "dark"
+
+var builtinThemes = map[string]Theme{
+	"dark": {
+		Name:       "dark",
+		Title:      tc("#af5f00", "130", "3"),
+		TitleText:  tc("#ffffff", "255", "15"),
+		Text:       tc("#eeeeee", "255", "7"),
+		Subtle:     tc("#626262", "241", "8"),
+		Heading:    tc("#afafaf", "145", "7"),
+		Accent:     tc("#ff5faf", "205", "13"),
+		AccentText: tc("#eeeeee", "255", "15"),
+		Error:      tc("#ff0000", "196", "9"),
+		Given:      tc("#6c6c6c", "242", "8"),
+		Done:       tc("#626262", "241", "8"),
+		Crosshair:  tc("#3a3a3a", "237", "0"),
+		Filled:     tc("#eeeeee", "255", "15"),
+		KnownEmpty: tc("#8a8a8a", "245", "7"),
+		Empty:      tc("#bcbcbc", "250", "7"),
+		Regions: []ThemeColor{
+			tc("#5f0000", "52", "1"), tc("#005f00", "22", "2"), tc("#00005f", "17", "4"), tc("#5f5f00", "58", "3"),
+			tc("#5f005f", "53", "5"), tc("#005f5f", "23", "6"), tc("#875f00", "94", "3"), tc("#444444", "238", "8"),
+		},
+	},
+	"light": {
+		Name:       "light",
+		Title:      tc("#d78700", "172", "3"),
+		TitleText:  tc("#000000", "16", "0"),
+		Text:       tc("#262626", "235", "0"),
+		Subtle:     tc("#8a8a8a", "245", "8"),
+		Heading:    tc("#585858", "240", "8"),
+		Accent:     tc("#d70087", "162", "5"),
+		AccentText: tc("#ffffff", "231", "15"),
+		Error:      tc("#d70000", "160", "1"),
+		Given:      tc("#9e9e9e", "247", "8"),
+		Done:       tc("#a8a8a8", "248", "8"),
+		Crosshair:  tc("#e4e4e4", "254", "7"),
+		Filled:     tc("#262626", "235", "0"),
+		KnownEmpty: tc("#808080", "244", "8"),
+		Empty:      tc("#a8a8a8", "248", "8"),
+		Regions: []ThemeColor{
+			tc("#ffd7d7", "224", "9"), tc("#d7ffd7", "194", "10"), tc("#d7d7ff", "189", "12"), tc("#ffffd7", "230", "11"),
+			tc("#ffd7ff", "225", "13"), tc("#d7ffff", "195", "14"), tc("#ffd7af", "223", "3"), tc("#e4e4e4", "254", "7"),
+		},
+	},
+	"high-contrast": {
+		Name:       "high-contrast",
+		Title:      tc("#ffff00", "226", "11"),
+		TitleText:  tc("#000000", "16", "0"),
+		Text:       tc("#ffffff", "231", "15"),
+		Subtle:     tc("#d0d0d0", "252", "7"),
+		Heading:    tc("#ffffff", "231", "15"),
+		Accent:     tc("#ffff00", "226", "11"),
+		AccentText: tc("#000000", "16", "0"),
+		Error:      tc("#ff0000", "196", "9"),
+		Given:      tc("#ffffff", "231", "15"),
+		Done:       tc("#808080", "244", "8"),
+		Crosshair:  tc("#0000ff", "21", "4"),
+		Filled:     tc("#ffffff", "231", "15"),
+		KnownEmpty: tc("#ffffff", "231", "15"),
+		Empty:      tc("#d0d0d0", "252", "7"),
+		Regions: []ThemeColor{
+			tc("#870000", "88", "1"), tc("#008700", "28", "2"), tc("#0000af", "19", "4"), tc("#875f00", "94", "3"),
+			tc("#870087", "90", "5"), tc("#008787", "30", "6"), tc("#5f5f5f", "59", "8"), tc("#af5f00", "130", "3"),
+		},
+	},
+	// colour-blind uses the Okabe-Ito palette, which stays distinct under
+	// the common forms of colour vision deficiency. Errors are orange and the
+	// cursor is blue so they are never told apart by red and green alone.
+	"colour-blind": {
+		Name:       "colour-blind",
+		Title:      tc("#0072b2", "25", "4"),
+		TitleText:  tc("#ffffff", "231", "15"),
+		Text:       tc("#eeeeee", "255", "7"),
+		Subtle:     tc("#808080", "244", "8"),
+		Heading:    tc("#56b4e9", "74", "6"),
+		Accent:     tc("#56b4e9", "74", "14"),
+		AccentText: tc("#000000", "16", "0"),
+		Error:      tc("#e69f00", "214", "11"),
+		Given:      tc("#808080", "244", "8"),
+		Done:       tc("#808080", "244", "8"),
+		Crosshair:  tc("#303030", "236", "0"),
+		Filled:     tc("#f0e442", "227", "11"),
+		KnownEmpty: tc("#cc79a7", "175", "5"),
+		Empty:      tc("#bcbcbc", "250", "7"),
+		Regions: []ThemeColor{
+			tc("#00527f", "24", "4"), tc("#9c6b00", "136", "3"), tc("#00684f", "29", "2"), tc("#8a4f70", "96", "5"),
+			tc("#3d7a99", "67", "6"), tc("#9a3f00", "130", "1"), tc("#5c5c5c", "59", "8"),
+		},
+	},
+}
+
+// themeNames returns the names of the built-in themes in a stable order.
+func themeNames() []string {
+	var names []string
+	for name := range builtinThemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// findTheme looks a theme up by name, checking the user's custom themes
+// before the built-in ones. Custom themes are completed from their base, and
+// one with the name of a built-in theme is based on that theme.
+func findTheme(name string, custom []Theme) (Theme, error) {
+	return resolveTheme(name, custom, map[string]bool{})
+}
+
+func resolveTheme(name string, custom []Theme, seen map[string]bool) (Theme, error) {
+	if seen[name] {
+		return Theme{}, fmt.Errorf("theme %q inherits from itself", name)
+	}
+	seen[name] = true
+
+	for _, t := range custom {
+		if t.Name != name {
+			continue
+		}
+		baseName := t.Base
+		if baseName == "" {
+			baseName = defaultThemeName
+		}
+		// A custom theme named after a built-in one adjusts it.
+		if baseName == name {
+			if base, ok := builtinThemes[name]; ok {
+				return base.merge(t), nil
+			}
+		}
+		base, err := resolveTheme(baseName, custom, seen)
+		if err != nil {
+			return Theme{}, err
+		}
+		return base.merge(t), nil
+	}
+
+	if t, ok := builtinThemes[name]; ok {
+		return t, nil
+	}
+	return Theme{}, fmt.Errorf("unknown theme %q", name)
+}
+
+// merge returns a copy of the theme with every colour set in o overriding
+// its own.
+func (t Theme) merge(o Theme) Theme {
+	out := t
+	out.Name = o.Name
+	out.Base = o.Base
+	dst := reflect.ValueOf(&out).Elem()
+	src := reflect.ValueOf(o)
+	for i := 0; i < src.NumField(); i++ {
+		c, ok := src.Field(i).Interface().(ThemeColor)
+		if ok && !c.IsZero() {
+			dst.Field(i).Set(reflect.ValueOf(c))
+		}
+	}
+	if len(o.Regions) > 0 {
+		out.Regions = o.Regions
+	}
+	return out
+}
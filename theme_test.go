@@ -0,0 +1,139 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestThemeColorUnmarshal(t *testing.T) {
+	testCases := []struct {
+		name string
+		yaml string
+		want ThemeColor
+	}{
+		{
+			name: "scalar",
+			yaml: `"205"`,
+			want: ThemeColor{TrueColor: "205", ANSI256: "205", ANSI: "205"},
+		},
+		{
+			name: "mapping",
+			yaml: "truecolor: \"#ff5faf\"\nansi256: \"205\"\nansi: \"13\"",
+			want: ThemeColor{TrueColor: "#ff5faf", ANSI256: "205", ANSI: "13"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got ThemeColor
+			if err := yaml.Unmarshal([]byte(tc.yaml), &got); err != nil {
+				t.Fatalf("failed to unmarshal: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuiltinThemesComplete(t *testing.T) {
+	for name, theme := range builtinThemes {
+		v := reflect.ValueOf(theme)
+		for i := 0; i < v.NumField(); i++ {
+			c, ok := v.Field(i).Interface().(ThemeColor)
+			if !ok {
+				continue
+			}
+			if c.TrueColor == "" || c.ANSI256 == "" || c.ANSI == "" {
+				t.Errorf("theme %q is missing a fallback for %s: %+v", name, v.Type().Field(i).Name, c)
+			}
+		}
+		if len(theme.Regions) < 4 {
+			t.Errorf("theme %q has %d region colours, want at least 4", name, len(theme.Regions))
+		}
+		for i, c := range theme.Regions {
+			if c.TrueColor == "" || c.ANSI256 == "" || c.ANSI == "" {
+				t.Errorf("theme %q is missing a fallback for region colour %d: %+v", name, i, c)
+			}
+		}
+	}
+}
+
+func TestFindTheme(t *testing.T) {
+	custom := []Theme{
+		{Name: "pink", Base: "light", Accent: ThemeColor{TrueColor: "#ff00ff", ANSI256: "201", ANSI: "13"}},
+		{Name: "pinker", Base: "pink", Error: ThemeColor{TrueColor: "#ff0000", ANSI256: "196", ANSI: "9"}},
+		{Name: "plain", Title: ThemeColor{TrueColor: "1", ANSI256: "1", ANSI: "1"}},
+		{Name: "loop", Base: "loop"},
+	}
+
+	t.Run("builtin", func(t *testing.T) {
+		got, err := findTheme("light", custom)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got, builtinThemes["light"]) {
+			t.Errorf("expected the built-in light theme, got %+v", got)
+		}
+	})
+
+	t.Run("custom adjusts builtin of the same name", func(t *testing.T) {
+		red := ThemeColor{TrueColor: "#ff0000", ANSI256: "196", ANSI: "9"}
+		adjusted := []Theme{
+			{Name: "dark", Error: red},
+			{Name: "light", Base: "light", Error: red},
+		}
+		for _, name := range []string{"dark", "light"} {
+			got, err := findTheme(name, adjusted)
+			if err != nil {
+				t.Fatalf("unexpected error for %s: %v", name, err)
+			}
+			if got.Error != red || got.Text != builtinThemes[name].Text {
+				t.Errorf("expected %s adjusted from the built-in theme, got %+v", name, got)
+			}
+		}
+	})
+
+	t.Run("custom inherits base", func(t *testing.T) {
+		got, err := findTheme("pinker", custom)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.Name != "pinker" {
+			t.Errorf("expected name pinker, got %q", got.Name)
+		}
+		if got.Accent != custom[0].Accent {
+			t.Errorf("expected accent from pink, got %+v", got.Accent)
+		}
+		if got.Error != custom[1].Error {
+			t.Errorf("expected own error colour, got %+v", got.Error)
+		}
+		if got.Text != builtinThemes["light"].Text {
+			t.Errorf("expected text colour from light, got %+v", got.Text)
+		}
+	})
+
+	t.Run("custom defaults to dark base", func(t *testing.T) {
+		got, err := findTheme("plain", custom)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.Accent != builtinThemes[defaultThemeName].Accent {
+			t.Errorf("expected accent from the default theme, got %+v", got.Accent)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		if _, err := findTheme("nope", custom); err == nil {
+			t.Errorf("expected an error for an unknown theme")
+		}
+	})
+
+	t.Run("self inheritance", func(t *testing.T) {
+		if _, err := findTheme("loop", custom); err == nil {
+			t.Errorf("expected an error for a theme that inherits from itself")
+		}
+	})
+}
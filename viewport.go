@@ -0,0 +1,82 @@
+package main
+
+// gridViewport tracks the window of a grid that fits on screen. Engines keep
+// one per game and refit it on every render so the cursor stays visible while
+// their clues stay pinned around it.
+type gridViewport struct {
+	offsetX int
+	offsetY int
+	cols    int
+	rows    int
+}
+
+// fit sizes the viewport to show as many cells as fit in the given space and
+// scrolls it so the cursor is visible. A space of zero or less in either
+// direction means the terminal size is unknown, so the whole grid is shown.
+func (v *gridViewport) fit(space, lines, cellWidth, gridW, gridH, cursorX, cursorY int) {
+	v.cols = gridW
+	if space > 0 && cellWidth > 0 {
+		v.cols = max(1, min(gridW, space/cellWidth))
+	}
+	v.rows = gridH
+	if lines > 0 {
+		v.rows = max(1, min(gridH, lines))
+	}
+
+	v.offsetX = scrollTo(v.offsetX, v.cols, gridW, cursorX)
+	v.offsetY = scrollTo(v.offsetY, v.rows, gridH, cursorY)
+}
+
+// fitTo fits the viewport to the terminal m is drawn in, leaving out the
+// columns and lines an engine draws around its grid, such as pinned clues and
+// the help, as well as the header.
+func (v *gridViewport) fitTo(m model, reservedCols, reservedLines, cellWidth, gridW, gridH int) {
+	space, lines := 0, 0
+	if m.width > 0 && m.height > 0 {
+		space = max(1, m.width-reservedCols)
+		lines = max(1, m.height-viewHeaderHeight-reservedLines)
+	}
+	v.fit(space, lines, cellWidth, gridW, gridH, m.cursorX, m.cursorY)
+}
+
+// cellAt maps a position relative to the top left of the drawn grid to grid
+// coordinates, offset by the current scroll position.
+func (v *gridViewport) cellAt(col, row, cellWidth int) (int, int, bool) {
+	if col < 0 || row < 0 {
+		return 0, 0, false
+	}
+	x := col/cellWidth + v.offsetX
+	y := row + v.offsetY
+	if !v.contains(x, y) {
+		return 0, 0, false
+	}
+	return x, y, true
+}
+
+// contains reports whether a grid coordinate is inside the viewport.
+func (v *gridViewport) contains(x, y int) bool {
+	return x >= v.offsetX && x < v.offsetX+v.cols && y >= v.offsetY && y < v.offsetY+v.rows
+}
+
+// clipped reports whether part of the grid is hidden.
+func (v *gridViewport) clipped(gridW, gridH int) bool {
+	return v.cols < gridW || v.rows < gridH
+}
+
+// scrollTo moves a one dimensional window of the given size the least amount
+// needed to contain pos, keeping it within [0, total).
+func scrollTo(offset, size, total, pos int) int {
+	if pos < offset {
+		offset = pos
+	}
+	if pos >= offset+size {
+		offset = pos - size + 1
+	}
+	return max(0, min(offset, total-size))
+}
+
+// zoomBy steps a zoom level through cellWidths. A positive step makes cells
+// larger and a negative step makes them more compact.
+func zoomBy(zoom, step int) int {
+	return max(0, min(len(cellWidths)-1, zoom-step))
+}
@@ -0,0 +1,97 @@
+package main
+
+import "testing"
+
+func TestGridViewportFit(t *testing.T) {
+	testCases := []struct {
+		name             string
+		start            gridViewport
+		space, lines     int
+		cellWidth        int
+		cursorX, cursorY int
+		want             gridViewport
+	}{
+		{name: "unknown size shows everything", space: 0, lines: 0, cellWidth: 3, want: gridViewport{cols: 30, rows: 30}},
+		{name: "fits columns by cell width", space: 30, lines: 10, cellWidth: 3, want: gridViewport{cols: 10, rows: 10}},
+		{name: "compact cells fit more", space: 30, lines: 10, cellWidth: 1, want: gridViewport{cols: 30, rows: 10}},
+		{name: "scrolls to cursor", space: 30, lines: 10, cellWidth: 3, cursorX: 15, cursorY: 12, want: gridViewport{offsetX: 6, offsetY: 3, cols: 10, rows: 10}},
+		{name: "keeps offset while cursor visible", start: gridViewport{offsetX: 5, offsetY: 5}, space: 30, lines: 10, cellWidth: 3, cursorX: 7, cursorY: 7, want: gridViewport{offsetX: 5, offsetY: 5, cols: 10, rows: 10}},
+		{name: "scrolls back to cursor", start: gridViewport{offsetX: 5, offsetY: 5}, space: 30, lines: 10, cellWidth: 3, cursorX: 2, cursorY: 0, want: gridViewport{offsetX: 2, offsetY: 0, cols: 10, rows: 10}},
+		{name: "clamps to grid edge", start: gridViewport{offsetX: 25, offsetY: 25}, space: 30, lines: 10, cellWidth: 3, cursorX: 29, cursorY: 29, want: gridViewport{offsetX: 20, offsetY: 20, cols: 10, rows: 10}},
+		{name: "tiny terminal shows one cell", space: 1, lines: 1, cellWidth: 3, want: gridViewport{cols: 1, rows: 1}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := tc.start
+			v.fit(tc.space, tc.lines, tc.cellWidth, 30, 30, tc.cursorX, tc.cursorY)
+			if v != tc.want {
+				t.Errorf("fit() = %+v, want %+v", v, tc.want)
+			}
+		})
+	}
+}
+
+func TestGridViewportFitTo(t *testing.T) {
+	testCases := []struct {
+		name        string
+		m           model
+		cols, lines int
+		want        gridViewport
+	}{
+		{name: "unknown size shows everything", want: gridViewport{cols: 30, rows: 30}},
+		{name: "leaves room for the header", m: model{width: 30, height: 12}, want: gridViewport{cols: 10, rows: 10}},
+		{name: "leaves room around the grid", m: model{width: 36, height: 17}, cols: 6, lines: 5, want: gridViewport{cols: 10, rows: 10}},
+		{name: "scrolls to the cursor", m: model{width: 30, height: 12, cursorX: 15, cursorY: 12}, want: gridViewport{offsetX: 6, offsetY: 3, cols: 10, rows: 10}},
+		{name: "tiny terminal shows one cell", m: model{width: 2, height: 1}, cols: 5, lines: 5, want: gridViewport{cols: 1, rows: 1}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var v gridViewport
+			v.fitTo(tc.m, tc.cols, tc.lines, 3, 30, 30)
+			if v != tc.want {
+				t.Errorf("fitTo() = %+v, want %+v", v, tc.want)
+			}
+		})
+	}
+}
+
+func TestZoomBy(t *testing.T) {
+	if got := zoomBy(0, 1); got != 0 {
+		t.Errorf("expected the largest cells to stay largest, got %d", got)
+	}
+	if got := zoomBy(0, -1); got != 1 {
+		t.Errorf("expected zooming out to step to more compact cells, got %d", got)
+	}
+	if got := zoomBy(len(cellWidths)-1, -1); got != len(cellWidths)-1 {
+		t.Errorf("expected the most compact cells to stay most compact, got %d", got)
+	}
+}
+
+func TestGridViewportCellAt(t *testing.T) {
+	v := gridViewport{offsetX: 2, offsetY: 1, cols: 4, rows: 3}
+	testCases := []struct {
+		name     string
+		col, row int
+		wantX    int
+		wantY    int
+		wantOK   bool
+	}{
+		{name: "top left cell", wantX: 2, wantY: 1, wantOK: true},
+		{name: "within a wide cell", col: 5, row: 2, wantX: 3, wantY: 3, wantOK: true},
+		{name: "left of the grid", col: -1},
+		{name: "above the grid", row: -1},
+		{name: "right of the viewport", col: 12},
+		{name: "below the viewport", row: 3},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			x, y, ok := v.cellAt(tc.col, tc.row, 3)
+			if ok != tc.wantOK || (ok && (x != tc.wantX || y != tc.wantY)) {
+				t.Errorf("cellAt(%d, %d) = %d, %d, %v, want %d, %d, %v", tc.col, tc.row, x, y, ok, tc.wantX, tc.wantY, tc.wantOK)
+			}
+		})
+	}
+}